@@ -1,22 +1,48 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/config"
 	"meal_prep/internal/db"
+	"meal_prep/internal/households"
 	"meal_prep/internal/ingredients"
 	mealplan "meal_prep/internal/meal_plan"
+	"meal_prep/internal/nutrition"
+	"meal_prep/internal/prices"
 	"meal_prep/internal/recipes"
+	"meal_prep/internal/sqlstore"
+	"meal_prep/internal/tags"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
 
-const (
-	DBPath = "/tmp/meal_prep.db"
-)
-
 func main() {
-	mealDB, err := db.Open(DBPath)
+	cfg, err := config.Load(os.Args[1:])
+
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	level, _ := cfg.Level()
+	drain, _ := cfg.DrainTimeout()
+	slog.SetLogLoggerLevel(level)
+	gin.SetMode(cfg.GinMode)
+
+	mealDB, err := db.Open(cfg.DBPath)
 
 	if err != nil {
 		log.Fatal(err)
@@ -28,49 +54,185 @@ func main() {
 		log.Fatal(err)
 	}
 
+	version, err := db.Version(mealDB)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("database ready", "dialect", mealDB.Dialect, "schema_version", version, "full_text_search", mealDB.FullText)
+
+	foods, err := nutrition.Open(cfg.FoodsCSV)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("nutrition database ready", "foods", len(foods.Foods()))
+
+	store := sqlstore.New(mealDB)
+
 	r := gin.Default()
 
-	r.StaticFile("/", "./public/index.html")
+	r.StaticFile("/", filepath.Join(cfg.StaticDir, "index.html"))
+
+	r.GET("/healthz", func(c *gin.Context) {
+		version, err := db.Version(mealDB.Reader())
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "schema_version": version})
+	})
+
+	// Accounts and login sessions; everything under /v1 needs one or an API
+	// token
+	authRoutes := r.Group("/auth")
+	{
+		authRoutes.POST("/register", func(c *gin.Context) { auth.RegisterHandler(c, store) })
+		authRoutes.POST("/login", func(c *gin.Context) { auth.LoginHandler(c, store) })
+		authRoutes.POST("/logout", func(c *gin.Context) { auth.LogoutHandler(c, store) })
+	}
+
+	v1 := r.Group("/v1", auth.Required(store))
+	{
+		v1.GET("/me", auth.MeHandler)
+		v1.GET("/foods", func(c *gin.Context) { nutrition.ListFoodsHandler(c, foods) })
+
+		// Personal API tokens, accepted like a session as a bearer token
+		v1.GET("/tokens", func(c *gin.Context) { auth.ListAPITokensHandler(c, store) })
+		v1.POST("/tokens", func(c *gin.Context) { auth.CreateAPITokenHandler(c, store) })
+		v1.PUT("/tokens/:id", func(c *gin.Context) { auth.UpdateAPITokenHandler(c, store) })
+		v1.PATCH("/tokens/:id", func(c *gin.Context) { auth.UpdateAPITokenHandler(c, store) })
+		v1.DELETE("/tokens/:id", func(c *gin.Context) { auth.DeleteAPITokenHandler(c, store) })
+
+		// Households and their members; recipes and meal plans below belong to
+		// the one picked by the X-Household-ID header
+		v1.GET("/households", func(c *gin.Context) { households.ListHouseholdsHandler(c, store) })
+		v1.POST("/households", func(c *gin.Context) { households.CreateHouseholdHandler(c, store) })
+		v1.POST("/households/join", func(c *gin.Context) { households.JoinHouseholdHandler(c, store) })
+		v1.GET("/households/:id", func(c *gin.Context) { households.GetHouseholdHandler(c, store) })
+		v1.PUT("/households/:id", func(c *gin.Context) { households.UpdateHouseholdHandler(c, store) })
+		v1.PATCH("/households/:id", func(c *gin.Context) { households.UpdateHouseholdHandler(c, store) })
+		v1.DELETE("/households/:id", func(c *gin.Context) { households.DeleteHouseholdHandler(c, store) })
+		v1.POST("/households/:id/invites", func(c *gin.Context) { households.CreateInviteHandler(c, store) })
+		v1.PUT("/households/:id/members/:userId", func(c *gin.Context) { households.UpdateMemberHandler(c, store) })
+		v1.PATCH("/households/:id/members/:userId", func(c *gin.Context) { households.UpdateMemberHandler(c, store) })
+		v1.DELETE("/households/:id/members/:userId", func(c *gin.Context) { households.RemoveMemberHandler(c, store) })
+	}
 
-	v1 := r.Group("/v1")
+	scoped := v1.Group("", households.Resolve(store))
 	{
-		v1.GET("/recipes", func(c *gin.Context) { recipes.ListRecipesHandler(c, mealDB) })
-		v1.POST("/recipes", func(c *gin.Context) { recipes.CreateRecipeHandler(c, mealDB) })
-		v1.GET("/recipes/:id", func(c *gin.Context) { recipes.GetRecipeHandler(c, mealDB) })
-		v1.DELETE("/recipes/:id", func(c *gin.Context) { recipes.DeleteRecipeHandler(c, mealDB) })
-		v1.GET("/recipes/:id/ingredients", func(c *gin.Context) { ingredients.ListIngredientsForRecipeHandler(c, mealDB) })
-		v1.POST("/recipes/:id/ingredients", func(c *gin.Context) { ingredients.CreateIngredientForRecipeHandler(c, mealDB) })
-
-		v1.GET("/ingredients/:id", func(c *gin.Context) { ingredients.GetIngredientHandler(c, mealDB) })
-		v1.PUT("/ingredients/:id", func(c *gin.Context) { ingredients.UpdateIngredientHandler(c, mealDB) })
-		v1.DELETE("/ingredients/:id", func(c *gin.Context) { ingredients.DeleteIngredientHandler(c, mealDB) })
-
-		v1.GET("/meal-plans", func(c *gin.Context) { mealplan.ListMealPlansHandler(c, mealDB) })
-		v1.POST("/meal-plans", func(c *gin.Context) { mealplan.CreateMealPlanHandler(c, mealDB) })
-		v1.GET("/meal-plans/:id", func(c *gin.Context) { mealplan.GetMealPlanHandler(c, mealDB) })
-		v1.PUT("/meal-plans/:id", func(c *gin.Context) { mealplan.UpdateMealPlanHandler(c, mealDB) })
-		v1.DELETE("/meal-plans/:id", func(c *gin.Context) { mealplan.DeleteMealPlanHandler(c, mealDB) })
+		scoped.GET("/recipes", func(c *gin.Context) { recipes.ListRecipesHandler(c, store) })
+		scoped.POST("/recipes", func(c *gin.Context) { recipes.CreateRecipeHandler(c, store) })
+		scoped.GET("/recipes/:id", func(c *gin.Context) { recipes.GetRecipeHandler(c, store) })
+		scoped.PUT("/recipes/:id", func(c *gin.Context) { recipes.UpdateRecipeHandler(c, store) })
+		scoped.PATCH("/recipes/:id", func(c *gin.Context) { recipes.UpdateRecipeHandler(c, store) })
+		scoped.DELETE("/recipes/:id", func(c *gin.Context) { recipes.DeleteRecipeHandler(c, store) })
+		scoped.GET("/recipes/:id/scaled", func(c *gin.Context) { recipes.GetScaledRecipeHandler(c, store, store) })
+		scoped.GET("/recipes/:id/nutrition", func(c *gin.Context) { recipes.GetRecipeNutritionHandler(c, store, store, foods) })
+		scoped.GET("/recipes/:id/cost", func(c *gin.Context) { recipes.GetRecipeCostHandler(c, store, store, store, foods) })
+		scoped.GET("/recipes/:id/ingredients", func(c *gin.Context) { ingredients.ListIngredientsForRecipeHandler(c, store) })
+		scoped.POST("/recipes/:id/ingredients", func(c *gin.Context) { ingredients.CreateIngredientForRecipeHandler(c, store) })
+
+		// Ordered method steps of a recipe
+		scoped.GET("/recipes/:id/steps", func(c *gin.Context) { recipes.ListStepsHandler(c, store) })
+		scoped.POST("/recipes/:id/steps", func(c *gin.Context) { recipes.CreateStepHandler(c, store) })
+		scoped.PUT("/recipes/:id/steps", func(c *gin.Context) { recipes.ReorderStepsHandler(c, store) })
+		scoped.GET("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.GetStepHandler(c, store) })
+		scoped.PUT("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.UpdateStepHandler(c, store) })
+		scoped.PATCH("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.UpdateStepHandler(c, store) })
+		scoped.DELETE("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.DeleteStepHandler(c, store) })
+
+		// Tags, and the tags on a recipe
+		scoped.GET("/tags", func(c *gin.Context) { tags.ListTagsHandler(c, store) })
+		scoped.POST("/tags", func(c *gin.Context) { tags.CreateTagHandler(c, store) })
+		scoped.GET("/tags/:id", func(c *gin.Context) { tags.GetTagHandler(c, store) })
+		scoped.PUT("/tags/:id", func(c *gin.Context) { tags.UpdateTagHandler(c, store) })
+		scoped.PATCH("/tags/:id", func(c *gin.Context) { tags.UpdateTagHandler(c, store) })
+		scoped.DELETE("/tags/:id", func(c *gin.Context) { tags.DeleteTagHandler(c, store) })
+		scoped.GET("/recipes/:id/tags", func(c *gin.Context) { tags.ListRecipeTagsHandler(c, store) })
+		scoped.POST("/recipes/:id/tags", func(c *gin.Context) { tags.TagRecipeHandler(c, store) })
+		scoped.DELETE("/recipes/:id/tags/:tagId", func(c *gin.Context) { tags.UntagRecipeHandler(c, store) })
+
+		// The price catalog recipes, meal plans and shopping lists are costed with
+		scoped.GET("/prices", func(c *gin.Context) { prices.ListPricesHandler(c, store) })
+		scoped.POST("/prices", func(c *gin.Context) { prices.CreatePriceHandler(c, store) })
+		scoped.GET("/prices/:id", func(c *gin.Context) { prices.GetPriceHandler(c, store) })
+		scoped.PUT("/prices/:id", func(c *gin.Context) { prices.UpdatePriceHandler(c, store) })
+		scoped.PATCH("/prices/:id", func(c *gin.Context) { prices.UpdatePriceHandler(c, store) })
+		scoped.DELETE("/prices/:id", func(c *gin.Context) { prices.DeletePriceHandler(c, store) })
+
+		scoped.GET("/ingredients/:id", func(c *gin.Context) { ingredients.GetIngredientHandler(c, store) })
+		scoped.PUT("/ingredients/:id", func(c *gin.Context) { ingredients.UpdateIngredientHandler(c, store) })
+		scoped.PATCH("/ingredients/:id", func(c *gin.Context) { ingredients.UpdateIngredientHandler(c, store) })
+		scoped.DELETE("/ingredients/:id", func(c *gin.Context) { ingredients.DeleteIngredientHandler(c, store) })
+
+		scoped.GET("/meal-plans", func(c *gin.Context) { mealplan.ListMealPlansHandler(c, store) })
+		scoped.POST("/meal-plans", func(c *gin.Context) { mealplan.CreateMealPlanHandler(c, store) })
+		scoped.GET("/meal-plans/:id", func(c *gin.Context) { mealplan.GetMealPlanHandler(c, store) })
+		scoped.PUT("/meal-plans/:id", func(c *gin.Context) { mealplan.UpdateMealPlanHandler(c, store) })
+		scoped.PATCH("/meal-plans/:id", func(c *gin.Context) { mealplan.UpdateMealPlanHandler(c, store) })
+		scoped.DELETE("/meal-plans/:id", func(c *gin.Context) { mealplan.DeleteMealPlanHandler(c, store) })
 
 		// Recipes inside a meal plan
-		v1.GET("/meal-plans/:id/recipes", func(c *gin.Context) { mealplan.ListMealPlanRecipesHandler(c, mealDB) })
-		v1.POST("/meal-plans/:id/recipes", func(c *gin.Context) { mealplan.CreateMealPlanRecipeHandler(c, mealDB) })
+		scoped.GET("/meal-plans/:id/recipes", func(c *gin.Context) { mealplan.ListMealPlanRecipesHandler(c, store) })
+		scoped.POST("/meal-plans/:id/recipes", func(c *gin.Context) { mealplan.CreateMealPlanRecipeHandler(c, store, store) })
+		scoped.GET("/meal-plans/:id/shopping-list", func(c *gin.Context) { mealplan.GetShoppingListHandler(c, store, store, foods) })
+		scoped.GET("/meal-plans/:id/nutrition", func(c *gin.Context) { mealplan.GetMealPlanNutritionHandler(c, store, foods) })
+		scoped.GET("/meal-plans/:id/cost", func(c *gin.Context) { mealplan.GetMealPlanCostHandler(c, store, store, foods) })
 
 		// Single meal_plan_recipes entries
-		v1.GET("/plan-recipes/:id", func(c *gin.Context) { mealplan.GetMealPlanRecipeHandler(c, mealDB) })
-		v1.PUT("/plan-recipes/:id", func(c *gin.Context) { mealplan.UpdateMealPlanRecipeHandler(c, mealDB) })
-		v1.DELETE("/plan-recipes/:id", func(c *gin.Context) { mealplan.DeleteMealPlanRecipeHandler(c, mealDB) })
+		scoped.GET("/plan-recipes/:id", func(c *gin.Context) { mealplan.GetMealPlanRecipeHandler(c, store) })
+		scoped.PUT("/plan-recipes/:id", func(c *gin.Context) { mealplan.UpdateMealPlanRecipeHandler(c, store, store) })
+		scoped.PATCH("/plan-recipes/:id", func(c *gin.Context) { mealplan.UpdateMealPlanRecipeHandler(c, store, store) })
+		scoped.DELETE("/plan-recipes/:id", func(c *gin.Context) { mealplan.DeleteMealPlanRecipeHandler(c, store) })
 	}
 
-	r.Static("/app", "./public")
+	// Read-only sharing of recipes marked is_public
+	public := r.Group("/public")
+	{
+		public.GET("/recipes", func(c *gin.Context) { recipes.ListPublicRecipesHandler(c, store) })
+		public.GET("/recipes/:id", func(c *gin.Context) { recipes.GetPublicRecipeHandler(c, store, store) })
+	}
 
-	log.Println("listening on :80")
-	if err := r.Run(":80"); err != nil {
-		log.Fatal(err)
+	r.Static("/app", cfg.StaticDir)
+
+	srv := &http.Server{
+		Addr:    cfg.ListenAddr,
+		Handler: r,
 	}
-	fmt.Println("exiting...")
 
-	if err = mealDB.Close(); err != nil {
-		log.Fatal("failed to gracefully close server, force quiting...")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", cfg.ListenAddr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			// Exit non-zero so supervisors and restart policies see the failure
+			slog.Error("server failed", "error", err)
+			if err := db.Close(mealDB); err != nil {
+				slog.Error("closing database", "error", err)
+			}
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		stop()
+		slog.Info("shutting down, draining in-flight requests", "timeout", drain)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), drain)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("requests still in flight at shutdown deadline", "error", err)
+		}
 	}
 
+	if err := db.Close(mealDB); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("exiting...")
 }
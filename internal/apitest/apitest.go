@@ -0,0 +1,260 @@
+// Package apitest drives the HTTP handlers end to end, routed like
+// cmd/main.go, against both store implementations.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/db"
+	"meal_prep/internal/households"
+	"meal_prep/internal/ingredients"
+	mealplan "meal_prep/internal/meal_plan"
+	"meal_prep/internal/memstore"
+	"meal_prep/internal/nutrition"
+	"meal_prep/internal/prices"
+	"meal_prep/internal/recipes"
+	"meal_prep/internal/sqlstore"
+	"meal_prep/internal/tags"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Store is everything the API needs, which both memstore and sqlstore
+// provide.
+type Store interface {
+	auth.UserStore
+	auth.TokenStore
+	households.HouseholdStore
+	recipes.RecipeStore
+	ingredients.IngredientStore
+	mealplan.MealPlanStore
+	tags.TagStore
+	prices.PriceStore
+}
+
+// ForEachStore runs the test on the in-memory store and, so the two don't
+// drift apart, on a SQLite database.
+func ForEachStore(t *testing.T, test func(t *testing.T, s Store)) {
+	t.Run("memstore", func(t *testing.T) { test(t, memstore.New()) })
+	t.Run("sqlstore", func(t *testing.T) { test(t, OpenSQL(t)) })
+}
+
+// OpenSQL returns a store on a fresh, migrated SQLite database that is
+// closed when the test ends.
+func OpenSQL(t *testing.T) *sqlstore.Store {
+	t.Helper()
+	conn, err := db.Open(filepath.Join(t.TempDir(), "meal_prep.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close(conn) })
+	if err := db.Init(conn); err != nil {
+		t.Fatal(err)
+	}
+	return sqlstore.New(conn)
+}
+
+// Router routes requests to the handlers the way cmd/main.go does, with the
+// bundled food database.
+func Router(store Store) *gin.Engine {
+	foods := nutrition.Bundled()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	authRoutes := r.Group("/auth")
+	{
+		authRoutes.POST("/register", func(c *gin.Context) { auth.RegisterHandler(c, store) })
+		authRoutes.POST("/login", func(c *gin.Context) { auth.LoginHandler(c, store) })
+		authRoutes.POST("/logout", func(c *gin.Context) { auth.LogoutHandler(c, store) })
+	}
+
+	v1 := r.Group("/v1", auth.Required(store))
+	{
+		v1.GET("/me", auth.MeHandler)
+		v1.GET("/foods", func(c *gin.Context) { nutrition.ListFoodsHandler(c, foods) })
+
+		v1.GET("/tokens", func(c *gin.Context) { auth.ListAPITokensHandler(c, store) })
+		v1.POST("/tokens", func(c *gin.Context) { auth.CreateAPITokenHandler(c, store) })
+		v1.PUT("/tokens/:id", func(c *gin.Context) { auth.UpdateAPITokenHandler(c, store) })
+		v1.PATCH("/tokens/:id", func(c *gin.Context) { auth.UpdateAPITokenHandler(c, store) })
+		v1.DELETE("/tokens/:id", func(c *gin.Context) { auth.DeleteAPITokenHandler(c, store) })
+
+		v1.GET("/households", func(c *gin.Context) { households.ListHouseholdsHandler(c, store) })
+		v1.POST("/households", func(c *gin.Context) { households.CreateHouseholdHandler(c, store) })
+		v1.POST("/households/join", func(c *gin.Context) { households.JoinHouseholdHandler(c, store) })
+		v1.GET("/households/:id", func(c *gin.Context) { households.GetHouseholdHandler(c, store) })
+		v1.PUT("/households/:id", func(c *gin.Context) { households.UpdateHouseholdHandler(c, store) })
+		v1.PATCH("/households/:id", func(c *gin.Context) { households.UpdateHouseholdHandler(c, store) })
+		v1.DELETE("/households/:id", func(c *gin.Context) { households.DeleteHouseholdHandler(c, store) })
+		v1.POST("/households/:id/invites", func(c *gin.Context) { households.CreateInviteHandler(c, store) })
+		v1.PUT("/households/:id/members/:userId", func(c *gin.Context) { households.UpdateMemberHandler(c, store) })
+		v1.PATCH("/households/:id/members/:userId", func(c *gin.Context) { households.UpdateMemberHandler(c, store) })
+		v1.DELETE("/households/:id/members/:userId", func(c *gin.Context) { households.RemoveMemberHandler(c, store) })
+	}
+
+	scoped := v1.Group("", households.Resolve(store))
+	{
+		scoped.GET("/recipes", func(c *gin.Context) { recipes.ListRecipesHandler(c, store) })
+		scoped.POST("/recipes", func(c *gin.Context) { recipes.CreateRecipeHandler(c, store) })
+		scoped.GET("/recipes/:id", func(c *gin.Context) { recipes.GetRecipeHandler(c, store) })
+		scoped.PUT("/recipes/:id", func(c *gin.Context) { recipes.UpdateRecipeHandler(c, store) })
+		scoped.PATCH("/recipes/:id", func(c *gin.Context) { recipes.UpdateRecipeHandler(c, store) })
+		scoped.DELETE("/recipes/:id", func(c *gin.Context) { recipes.DeleteRecipeHandler(c, store) })
+		scoped.GET("/recipes/:id/scaled", func(c *gin.Context) { recipes.GetScaledRecipeHandler(c, store, store) })
+		scoped.GET("/recipes/:id/nutrition", func(c *gin.Context) { recipes.GetRecipeNutritionHandler(c, store, store, foods) })
+		scoped.GET("/recipes/:id/cost", func(c *gin.Context) { recipes.GetRecipeCostHandler(c, store, store, store, foods) })
+		scoped.GET("/recipes/:id/ingredients", func(c *gin.Context) { ingredients.ListIngredientsForRecipeHandler(c, store) })
+		scoped.POST("/recipes/:id/ingredients", func(c *gin.Context) { ingredients.CreateIngredientForRecipeHandler(c, store) })
+
+		scoped.GET("/recipes/:id/steps", func(c *gin.Context) { recipes.ListStepsHandler(c, store) })
+		scoped.POST("/recipes/:id/steps", func(c *gin.Context) { recipes.CreateStepHandler(c, store) })
+		scoped.PUT("/recipes/:id/steps", func(c *gin.Context) { recipes.ReorderStepsHandler(c, store) })
+		scoped.GET("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.GetStepHandler(c, store) })
+		scoped.PUT("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.UpdateStepHandler(c, store) })
+		scoped.PATCH("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.UpdateStepHandler(c, store) })
+		scoped.DELETE("/recipes/:id/steps/:stepId", func(c *gin.Context) { recipes.DeleteStepHandler(c, store) })
+
+		scoped.GET("/tags", func(c *gin.Context) { tags.ListTagsHandler(c, store) })
+		scoped.POST("/tags", func(c *gin.Context) { tags.CreateTagHandler(c, store) })
+		scoped.GET("/tags/:id", func(c *gin.Context) { tags.GetTagHandler(c, store) })
+		scoped.PUT("/tags/:id", func(c *gin.Context) { tags.UpdateTagHandler(c, store) })
+		scoped.PATCH("/tags/:id", func(c *gin.Context) { tags.UpdateTagHandler(c, store) })
+		scoped.DELETE("/tags/:id", func(c *gin.Context) { tags.DeleteTagHandler(c, store) })
+		scoped.GET("/recipes/:id/tags", func(c *gin.Context) { tags.ListRecipeTagsHandler(c, store) })
+		scoped.POST("/recipes/:id/tags", func(c *gin.Context) { tags.TagRecipeHandler(c, store) })
+		scoped.DELETE("/recipes/:id/tags/:tagId", func(c *gin.Context) { tags.UntagRecipeHandler(c, store) })
+
+		scoped.GET("/prices", func(c *gin.Context) { prices.ListPricesHandler(c, store) })
+		scoped.POST("/prices", func(c *gin.Context) { prices.CreatePriceHandler(c, store) })
+		scoped.GET("/prices/:id", func(c *gin.Context) { prices.GetPriceHandler(c, store) })
+		scoped.PUT("/prices/:id", func(c *gin.Context) { prices.UpdatePriceHandler(c, store) })
+		scoped.PATCH("/prices/:id", func(c *gin.Context) { prices.UpdatePriceHandler(c, store) })
+		scoped.DELETE("/prices/:id", func(c *gin.Context) { prices.DeletePriceHandler(c, store) })
+
+		scoped.GET("/ingredients/:id", func(c *gin.Context) { ingredients.GetIngredientHandler(c, store) })
+		scoped.PUT("/ingredients/:id", func(c *gin.Context) { ingredients.UpdateIngredientHandler(c, store) })
+		scoped.PATCH("/ingredients/:id", func(c *gin.Context) { ingredients.UpdateIngredientHandler(c, store) })
+		scoped.DELETE("/ingredients/:id", func(c *gin.Context) { ingredients.DeleteIngredientHandler(c, store) })
+
+		scoped.GET("/meal-plans", func(c *gin.Context) { mealplan.ListMealPlansHandler(c, store) })
+		scoped.POST("/meal-plans", func(c *gin.Context) { mealplan.CreateMealPlanHandler(c, store) })
+		scoped.GET("/meal-plans/:id", func(c *gin.Context) { mealplan.GetMealPlanHandler(c, store) })
+		scoped.PUT("/meal-plans/:id", func(c *gin.Context) { mealplan.UpdateMealPlanHandler(c, store) })
+		scoped.PATCH("/meal-plans/:id", func(c *gin.Context) { mealplan.UpdateMealPlanHandler(c, store) })
+		scoped.DELETE("/meal-plans/:id", func(c *gin.Context) { mealplan.DeleteMealPlanHandler(c, store) })
+
+		scoped.GET("/meal-plans/:id/recipes", func(c *gin.Context) { mealplan.ListMealPlanRecipesHandler(c, store) })
+		scoped.POST("/meal-plans/:id/recipes", func(c *gin.Context) { mealplan.CreateMealPlanRecipeHandler(c, store, store) })
+		scoped.GET("/meal-plans/:id/shopping-list", func(c *gin.Context) { mealplan.GetShoppingListHandler(c, store, store, foods) })
+		scoped.GET("/meal-plans/:id/nutrition", func(c *gin.Context) { mealplan.GetMealPlanNutritionHandler(c, store, foods) })
+		scoped.GET("/meal-plans/:id/cost", func(c *gin.Context) { mealplan.GetMealPlanCostHandler(c, store, store, foods) })
+
+		scoped.GET("/plan-recipes/:id", func(c *gin.Context) { mealplan.GetMealPlanRecipeHandler(c, store) })
+		scoped.PUT("/plan-recipes/:id", func(c *gin.Context) { mealplan.UpdateMealPlanRecipeHandler(c, store, store) })
+		scoped.PATCH("/plan-recipes/:id", func(c *gin.Context) { mealplan.UpdateMealPlanRecipeHandler(c, store, store) })
+		scoped.DELETE("/plan-recipes/:id", func(c *gin.Context) { mealplan.DeleteMealPlanRecipeHandler(c, store) })
+	}
+
+	public := r.Group("/public")
+	{
+		public.GET("/recipes", func(c *gin.Context) { recipes.ListPublicRecipesHandler(c, store) })
+		public.GET("/recipes/:id", func(c *gin.Context) { recipes.GetPublicRecipeHandler(c, store, store) })
+	}
+
+	return r
+}
+
+// Client sends requests through the router as one user. The zero Token
+// sends them anonymously.
+type Client struct {
+	t      *testing.T
+	router http.Handler
+
+	User  auth.User
+	Token string
+
+	// Household, when set, is sent as the X-Household-ID header
+	Household int
+}
+
+// NewClient registers a user, who gets a household of their own, and logs
+// them in.
+func NewClient(t *testing.T, s Store, email string) *Client {
+	t.Helper()
+	ctx := context.Background()
+	u, err := s.CreateUser(ctx, auth.User{Email: email, PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := "session-" + email
+	if err := s.CreateSession(ctx, u.ID, auth.HashToken(token), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Client{t: t, router: Router(s), User: u, Token: token}
+}
+
+// Anonymous returns a client that isn't logged in.
+func Anonymous(t *testing.T, s Store) *Client {
+	return &Client{t: t, router: Router(s)}
+}
+
+// As returns a copy of the client sending token instead.
+func (cl *Client) As(token string) *Client {
+	other := *cl
+	other.Token = token
+	return &other
+}
+
+// Request sends body, if any, as JSON.
+func (cl *Client) Request(method, path string, body any) *httptest.ResponseRecorder {
+	cl.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			cl.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if cl.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.Token)
+	}
+	if cl.Household != 0 {
+		req.Header.Set(households.HeaderName, strconv.Itoa(cl.Household))
+	}
+
+	w := httptest.NewRecorder()
+	cl.router.ServeHTTP(w, req)
+	return w
+}
+
+// Do sends body as JSON and decodes the response into out, if given,
+// returning the status.
+func (cl *Client) Do(method, path string, body, out any) int {
+	cl.t.Helper()
+	w := cl.Request(method, path, body)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			cl.t.Fatalf("%s %s: decoding %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// Create POSTs body to path and fails the test unless it's created.
+func (cl *Client) Create(path string, body, out any) {
+	cl.t.Helper()
+	if code := cl.Do("POST", path, body, out); code != http.StatusCreated {
+		cl.t.Fatalf("POST %s: status %d", path, code)
+	}
+}
@@ -0,0 +1,162 @@
+// Package auth owns user accounts, login sessions and personal API tokens. A
+// session is a random token handed to the client once, as a cookie for the
+// browser UI and in the response body for API clients, who send it back as a
+// bearer token. API tokens are sent the same way but don't expire. Only the
+// SHA-256 of either is stored.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CookieName = "meal_prep_session"
+	SessionTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrEmailTaken      = errors.New("email already registered")
+	ErrSessionNotFound = errors.New("session not found or expired")
+)
+
+type User struct {
+	ID           int        `json:"id"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"` // bcrypt
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// UserStore is the persistence behind accounts and sessions.
+type UserStore interface {
+	// CreateUser fails with ErrEmailTaken if the address is registered. The
+	// first user to register takes over the recipes and meal plans created
+	// before there were accounts.
+	CreateUser(ctx context.Context, u User) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+
+	// CreateSession also clears out the user's expired sessions.
+	CreateSession(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	// GetSessionUser returns ErrSessionNotFound for unknown and expired
+	// sessions alike.
+	GetSessionUser(ctx context.Context, tokenHash string) (User, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+}
+
+// newToken returns a session token and the hash to store for it.
+func newToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken is what gets stored in place of a secret handed to a client, so
+// a copy of the database can't be used to sign in.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestToken finds the session or API token in the Authorization header or, for
+// the browser UI, the session cookie.
+func requestToken(c *gin.Context) string {
+	if h := c.GetHeader("Authorization"); h != "" {
+		scheme, token, _ := strings.Cut(h, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+
+	token, _ := c.Cookie(CookieName)
+	return token
+}
+
+const (
+	userKey  = "auth.user"
+	tokenKey = "auth.token"
+)
+
+// Required rejects requests without a valid session or API token and makes
+// the user available to handlers through CurrentUser and UserID. Requests
+// made with a read-only API token are limited to safe methods.
+func Required(store interface {
+	UserStore
+	TokenStore
+}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := requestToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		// A session token can start with the prefix by chance, so an unknown
+		// prefixed token still gets looked up as a session
+		if strings.HasPrefix(token, TokenPrefix) {
+			u, t, err := store.UseAPIToken(c.Request.Context(), HashToken(token))
+			if err == nil {
+				if !t.Scope.Allows(c.Request.Method) {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this API token is read-only"})
+					return
+				}
+
+				c.Set(userKey, u)
+				c.Set(tokenKey, t)
+				c.Next()
+				return
+			}
+			if !errors.Is(err, ErrTokenNotFound) {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check API token"})
+				return
+			}
+		}
+
+		u, err := store.GetSessionUser(c.Request.Context(), HashToken(token))
+		if errors.Is(err, ErrSessionNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired session"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check session"})
+			return
+		}
+
+		c.Set(userKey, u)
+		c.Next()
+	}
+}
+
+// CurrentToken is the API token a request was authenticated with; ok is
+// false for sessions.
+func CurrentToken(c *gin.Context) (t APIToken, ok bool) {
+	v, _ := c.Get(tokenKey)
+	t, ok = v.(APIToken)
+	return t, ok
+}
+
+// CurrentUser is the user Required authenticated, or the zero User on routes
+// it doesn't guard.
+func CurrentUser(c *gin.Context) User {
+	u, _ := c.Get(userKey)
+	user, _ := u.(User)
+	return user
+}
+
+func UserID(c *gin.Context) int {
+	return CurrentUser(c).ID
+}
@@ -0,0 +1,290 @@
+package auth
+
+import (
+	"errors"
+	"meal_prep/internal/patch"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordBytes is as long a password as bcrypt accepts. The binding's
+// max counts characters, so the length in bytes is checked separately.
+const maxPasswordBytes = 72
+
+type CredentialsRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type SessionResponse struct {
+	User      User      `json:"user"`
+	Token     string    `json:"token"` // send as "Authorization: Bearer <token>"
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// dummyHash is compared against when the email is unknown, so a login takes
+// as long whether or not the account exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+func normalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// startSession logs u in: it stores a new session, sets the cookie and
+// returns the token in the body.
+func startSession(c *gin.Context, store UserStore, u User, status int) {
+	token, hash, err := newToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	expires := time.Now().UTC().Add(SessionTTL).Truncate(time.Second)
+	if err := store.CreateSession(c.Request.Context(), u.ID, hash, expires); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     CookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.JSON(status, SessionResponse{User: u, Token: token, ExpiresAt: expires})
+}
+
+func RegisterHandler(c *gin.Context, store UserStore) {
+	var req CredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a valid email and a password of 8 to 72 characters are required"})
+		return
+	}
+	if len(req.Password) > maxPasswordBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password must be at most 72 bytes"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
+	u, err := store.CreateUser(c.Request.Context(), User{Email: normalizeEmail(req.Email), PasswordHash: string(hash)})
+	if errors.Is(err, ErrEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		return
+	}
+
+	startSession(c, store, u, http.StatusCreated)
+}
+
+func LoginHandler(c *gin.Context, store UserStore) {
+	var req CredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	u, err := store.GetUserByEmail(c.Request.Context(), normalizeEmail(req.Email))
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	hash := []byte(u.PasswordHash)
+	if err != nil {
+		hash = dummyHash
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		return
+	}
+
+	startSession(c, store, u, http.StatusOK)
+}
+
+// LogoutHandler ends the session the request carries, if any.
+func LogoutHandler(c *gin.Context, store UserStore) {
+	if token := requestToken(c); token != "" {
+		if err := store.DeleteSession(c.Request.Context(), HashToken(token)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete session"})
+			return
+		}
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     CookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Status(http.StatusNoContent)
+}
+
+func MeHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, CurrentUser(c))
+}
+
+type CreateAPITokenRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Scope Scope  `json:"scope"` // optional, "read" (default) or "write"
+}
+
+type UpdateAPITokenRequest struct {
+	Name patch.Field[string] `json:"name"`
+}
+
+// sessionOnly keeps API tokens from minting or revoking API tokens, so a
+// leaked token can't be used to hide behind new ones.
+func sessionOnly(c *gin.Context) bool {
+	if _, ok := CurrentToken(c); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API tokens are managed from a login session"})
+		return false
+	}
+
+	return true
+}
+
+func parseTokenID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+func ListAPITokensHandler(c *gin.Context, store TokenStore) {
+	list, err := store.ListAPITokens(c.Request.Context(), UserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query API tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateAPITokenHandler answers with the token itself, which is never shown
+// again.
+func CreateAPITokenHandler(c *gin.Context, store TokenStore) {
+	if !sessionOnly(c) {
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = ScopeRead
+	}
+	if !req.Scope.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be read or write"})
+		return
+	}
+
+	token, hash, err := newAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API token"})
+		return
+	}
+
+	t, err := store.CreateAPIToken(c.Request.Context(), APIToken{
+		UserID:    UserID(c),
+		Name:      req.Name,
+		Scope:     req.Scope,
+		TokenHash: hash,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API token"})
+		return
+	}
+
+	t.Token = token
+	c.JSON(http.StatusCreated, t)
+}
+
+func UpdateAPITokenHandler(c *gin.Context, store TokenStore) {
+	if !sessionOnly(c) {
+		return
+	}
+	id, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateAPITokenRequest
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	// The name is all there is to change
+	var name string
+	if err := req.Name.Apply(&name); err != nil || strings.TrimSpace(name) == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1 to 100 characters"})
+		return
+	}
+
+	t, err := store.RenameAPIToken(c.Request.Context(), UserID(c), id, strings.TrimSpace(name))
+	if errors.Is(err, ErrTokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update API token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteAPITokenHandler revokes a token; requests using it fail from then on.
+func DeleteAPITokenHandler(c *gin.Context, store TokenStore) {
+	if !sessionOnly(c) {
+		return
+	}
+	id, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	err := store.DeleteAPIToken(c.Request.Context(), UserID(c), id)
+	if errors.Is(err, ErrTokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke API token"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,150 @@
+package auth_test
+
+import (
+	"context"
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/auth"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func register(t *testing.T, anon *apitest.Client, email, password string) auth.SessionResponse {
+	t.Helper()
+	var sess auth.SessionResponse
+	anon.Create("/auth/register", map[string]any{"email": email, "password": password}, &sess)
+	return sess
+}
+
+func TestRegister(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		anon := apitest.Anonymous(t, s)
+		sess := register(t, anon, "Cook@Example.com", "correct horse")
+		if sess.Token == "" || sess.User.Email != "cook@example.com" || sess.User.PasswordHash != "" {
+			t.Errorf("register = %+v", sess)
+		}
+
+		var me auth.User
+		if code := anon.As(sess.Token).Do("GET", "/v1/me", nil, &me); code != http.StatusOK || me.ID != sess.User.ID {
+			t.Errorf("me: status %d, %+v", code, me)
+		}
+
+		tests := []struct {
+			name     string
+			email    string
+			password string
+			want     int
+		}{
+			{"taken, in any case", "COOK@example.com", "battery staple", http.StatusConflict},
+			{"not an email", "cook", "battery staple", http.StatusBadRequest},
+			{"short password", "baker@example.com", "short", http.StatusBadRequest},
+			{"password over 72 bytes", "baker@example.com", strings.Repeat("é", 40), http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			if code := anon.Do("POST", "/auth/register", map[string]any{"email": tt.email, "password": tt.password}, nil); code != tt.want {
+				t.Errorf("%s: status %d, want %d", tt.name, code, tt.want)
+			}
+		}
+	})
+}
+
+// Sign-ups racing for one email leave exactly one account, however the
+// store settles it.
+func TestRegisterRace(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		var (
+			wg                sync.WaitGroup
+			mu                sync.Mutex
+			created, conflict int
+		)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := s.CreateUser(context.Background(), auth.User{Email: "cook@example.com", PasswordHash: "x"})
+				mu.Lock()
+				defer mu.Unlock()
+				switch err {
+				case nil:
+					created++
+				case auth.ErrEmailTaken:
+					conflict++
+				default:
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if created != 1 || conflict != 7 {
+			t.Errorf("%d created and %d conflicts, want 1 and 7", created, conflict)
+		}
+	})
+}
+
+func TestLogin(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		anon := apitest.Anonymous(t, s)
+		registered := register(t, anon, "cook@example.com", "correct horse")
+
+		var sess auth.SessionResponse
+		if code := anon.Do("POST", "/auth/login", map[string]any{"email": "Cook@example.com", "password": "correct horse"}, &sess); code != http.StatusOK {
+			t.Fatalf("login: status %d", code)
+		}
+		if sess.User.ID != registered.User.ID || sess.Token == "" || sess.Token == registered.Token {
+			t.Errorf("login = %+v, want a new session for the same user", sess)
+		}
+		if time.Until(sess.ExpiresAt) < auth.SessionTTL-time.Minute {
+			t.Errorf("session expires at %v", sess.ExpiresAt)
+		}
+
+		for _, creds := range []map[string]any{
+			{"email": "cook@example.com", "password": "wrong horse"},
+			{"email": "nobody@example.com", "password": "correct horse"},
+		} {
+			if code := anon.Do("POST", "/auth/login", creds, nil); code != http.StatusUnauthorized {
+				t.Errorf("login as %v: status %d, want 401", creds, code)
+			}
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		anon := apitest.Anonymous(t, s)
+		sess := register(t, anon, "cook@example.com", "correct horse")
+		cl := anon.As(sess.Token)
+
+		w := cl.Request("POST", "/auth/logout", nil)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("logout: status %d", w.Code)
+		}
+		if cookie := w.Result().Cookies(); len(cookie) != 1 || cookie[0].Name != auth.CookieName || cookie[0].MaxAge >= 0 {
+			t.Errorf("logout cookies = %v, want %s cleared", cookie, auth.CookieName)
+		}
+		if code := cl.Do("GET", "/v1/me", nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("me after logout: status %d, want 401", code)
+		}
+	})
+}
+
+func TestSessionExpiry(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		if err := s.CreateSession(context.Background(), cl.User.ID, auth.HashToken("stale"), time.Now().Add(-time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+
+		if code := cl.Do("GET", "/v1/me", nil, nil); code != http.StatusOK {
+			t.Errorf("live session: status %d", code)
+		}
+		if code := cl.As("stale").Do("GET", "/v1/me", nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("expired session: status %d, want 401", code)
+		}
+		if code := cl.As("").Do("GET", "/v1/me", nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("no session: status %d, want 401", code)
+		}
+	})
+}
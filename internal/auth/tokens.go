@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// TokenPrefix marks personal API tokens, so they can be told apart from
+// session tokens and spotted when they leak into logs or repositories.
+const TokenPrefix = "mp_"
+
+// LastUsedGranularity is how stale an API token's last_used_at may get
+// before a request refreshes it; tokens polled by scripts would otherwise
+// cost a write on every request.
+const LastUsedGranularity = time.Minute
+
+var ErrTokenNotFound = errors.New("api token not found")
+
+// Scope limits what an API token may do. Read tokens are restricted to safe
+// methods; roles within a household still apply on top of the scope.
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+)
+
+func (s Scope) Valid() bool {
+	return s == ScopeRead || s == ScopeWrite
+}
+
+func (s Scope) Allows(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return s.Valid()
+	}
+
+	return s == ScopeWrite
+}
+
+// APIToken is a long-lived credential a user mints for scripts. Like a
+// session token, only its hash is stored and the token itself is shown once.
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Scope      Scope      `json:"scope"`
+	Token      string     `json:"token,omitempty"` // only in the response that creates it
+	TokenHash  string     `json:"-"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at"` // null until first used
+}
+
+// TokenStore is the persistence behind API tokens. Every method but
+// UseAPIToken is scoped to the user owning the tokens, and another user's
+// token is reported as ErrTokenNotFound.
+type TokenStore interface {
+	CreateAPIToken(ctx context.Context, t APIToken) (APIToken, error)
+	ListAPITokens(ctx context.Context, userID int) ([]APIToken, error)
+	RenameAPIToken(ctx context.Context, userID, id int, name string) (APIToken, error)
+	DeleteAPIToken(ctx context.Context, userID, id int) error
+
+	// UseAPIToken looks a token up by hash for authentication and records the
+	// use, at most once per LastUsedGranularity.
+	UseAPIToken(ctx context.Context, tokenHash string) (User, APIToken, error)
+}
+
+// newAPIToken returns a prefixed API token and the hash to store for it.
+func newAPIToken() (token, hash string, err error) {
+	if token, _, err = newToken(); err != nil {
+		return "", "", err
+	}
+
+	token = TokenPrefix + token
+	return token, HashToken(token), nil
+}
@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/recipes"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func createToken(cl *apitest.Client, name string, scope auth.Scope) auth.APIToken {
+	var tok auth.APIToken
+	cl.Create("/v1/tokens", map[string]any{"name": name, "scope": scope}, &tok)
+	return tok
+}
+
+func TestAPITokens(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		tok := createToken(cl, "Grocery script", auth.ScopeWrite)
+		if !strings.HasPrefix(tok.Token, auth.TokenPrefix) || tok.LastUsedAt != nil {
+			t.Errorf("create = %+v, want an unused %s token", tok, auth.TokenPrefix)
+		}
+
+		script := cl.As(tok.Token)
+		var me auth.User
+		if code := script.Do("GET", "/v1/me", nil, &me); code != http.StatusOK || me.ID != cl.User.ID {
+			t.Fatalf("me with a token: status %d, %+v", code, me)
+		}
+		if code := script.Do("POST", "/v1/recipes", map[string]any{"title": "Soup"}, nil); code != http.StatusCreated {
+			t.Errorf("write token creating: status %d, want 201", code)
+		}
+
+		// The token itself is never listed, and its use is recorded
+		var list []auth.APIToken
+		if code := cl.Do("GET", "/v1/tokens", nil, &list); code != http.StatusOK || len(list) != 1 {
+			t.Fatalf("list: status %d, %+v", code, list)
+		}
+		if list[0].Token != "" || list[0].LastUsedAt == nil {
+			t.Errorf("listed = %+v, want no token and a last use", list[0])
+		}
+
+		if code := cl.Do("POST", "/v1/tokens", map[string]any{"name": "Admin", "scope": "admin"}, nil); code != http.StatusBadRequest {
+			t.Errorf("unknown scope: status %d, want 400", code)
+		}
+		if code := script.Do("POST", "/v1/tokens", map[string]any{"name": "Another"}, nil); code != http.StatusForbidden {
+			t.Errorf("token minting a token: status %d, want 403", code)
+		}
+
+		var renamed auth.APIToken
+		path := "/v1/tokens/" + strconv.Itoa(tok.ID)
+		if code := cl.Do("PATCH", path, map[string]any{"name": "Pantry script"}, &renamed); code != http.StatusOK || renamed.Name != "Pantry script" {
+			t.Errorf("rename: status %d, %+v", code, renamed)
+		}
+
+		// Revoking takes effect on the next request
+		if code := script.Do("DELETE", path, nil, nil); code != http.StatusForbidden {
+			t.Errorf("token revoking itself: status %d, want 403", code)
+		}
+		if code := cl.Do("DELETE", path, nil, nil); code != http.StatusNoContent {
+			t.Fatalf("revoke: status %d", code)
+		}
+		if code := script.Do("GET", "/v1/me", nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("revoked token: status %d, want 401", code)
+		}
+		if code := cl.Do("DELETE", path, nil, nil); code != http.StatusNotFound {
+			t.Errorf("revoking twice: status %d, want 404", code)
+		}
+	})
+}
+
+func TestReadScope(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		var soup recipes.Recipe
+		cl.Create("/v1/recipes", map[string]any{"title": "Soup"}, &soup)
+		path := "/v1/recipes/" + strconv.Itoa(soup.ID)
+
+		// Read is the default scope
+		tok := createToken(cl, "Dashboard", "")
+		if tok.Scope != auth.ScopeRead {
+			t.Errorf("scope = %q, want read", tok.Scope)
+		}
+
+		reader := cl.As(tok.Token)
+		tests := []struct {
+			method string
+			path   string
+			body   any
+			want   int
+		}{
+			{"GET", path, nil, http.StatusOK},
+			{"GET", "/v1/recipes", nil, http.StatusOK},
+			{"POST", "/v1/recipes", map[string]any{"title": "Stew"}, http.StatusForbidden},
+			{"PATCH", path, map[string]any{"title": "Broth"}, http.StatusForbidden},
+			{"DELETE", path, nil, http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			if code := reader.Do(tt.method, tt.path, tt.body, nil); code != tt.want {
+				t.Errorf("%s %s with a read token: status %d, want %d", tt.method, tt.path, code, tt.want)
+			}
+		}
+
+		// Another user's token isn't theirs to see or revoke
+		other := apitest.NewClient(t, s, "baker@example.com")
+		if code := other.Do("DELETE", "/v1/tokens/"+strconv.Itoa(tok.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("someone else's token: status %d, want 404", code)
+		}
+	})
+}
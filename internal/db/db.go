@@ -2,66 +2,82 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"runtime"
+	"strings"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/lib/pq"
 )
 
+type Dialect string
+
 const (
-	schema = `
-PRAGMA foreign_keys = ON;
-
-CREATE TABLE IF NOT EXISTS recipes (
-    id          INTEGER PRIMARY KEY AUTOINCREMENT,
-    title       TEXT NOT NULL,
-    description TEXT,
-    servings    INTEGER,
-    prep_time   INTEGER, -- minutes
-    cook_time   INTEGER, -- minutes
-    is_public   INTEGER NOT NULL DEFAULT 0,
-    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE IF NOT EXISTS recipe_ingredients (
-    id         INTEGER PRIMARY KEY AUTOINCREMENT,
-    recipe_id  INTEGER NOT NULL,
-    name       TEXT NOT NULL,
-    quantity   TEXT,
-    unit       TEXT,
-    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
-);
-
-CREATE TABLE IF NOT EXISTS recipe_steps (
-    id          INTEGER PRIMARY KEY AUTOINCREMENT,
-    recipe_id   INTEGER NOT NULL,
-    step_no     INTEGER NOT NULL,
-    instruction TEXT NOT NULL,
-    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
-);
-
-CREATE TABLE IF NOT EXISTS meal_plans (
-    id         INTEGER PRIMARY KEY AUTOINCREMENT,
-    name       TEXT NOT NULL,
-    start_date DATE NOT NULL,
-    end_date   DATE NOT NULL,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE IF NOT EXISTS meal_plan_recipes (
-    id            INTEGER PRIMARY KEY AUTOINCREMENT,
-    meal_plan_id  INTEGER NOT NULL,
-    recipe_id     INTEGER,
-    meal_type     TEXT,
-    planned_date  DATE,
-    FOREIGN KEY (meal_plan_id) REFERENCES meal_plans(id) ON DELETE CASCADE,
-    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE SET NULL
-);
-`
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
 )
 
-func Open(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_foreign_keys=on", path))
+// busyTimeout is how long a SQLite connection waits on a lock held by another
+// connection, or another process, before giving up with SQLITE_BUSY.
+const busyTimeout = 5 * time.Second
+
+// DB is a connection pool that knows which database it talks to. Queries are
+// written once, with ? placeholders, and rebound for the dialect on their way
+// through; see Rebind.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+	// FullText is whether recipes can be searched with a full-text index;
+	// without one, stores fall back to plain substring matching.
+	FullText bool
+
+	read *DB // SQLite only, see Reader
+}
+
+// Open connects to Postgres when dsn is a postgres:// or postgresql:// URL and
+// otherwise treats it as the path of a SQLite database file.
+func Open(dsn string) (*DB, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return open(Postgres, "postgres", dsn)
+	}
+
+	// SQLite allows a single writer, so every write goes through one
+	// connection. In WAL mode readers don't wait on it, so they get a pool of
+	// their own. The writer is opened first to switch the file to WAL.
+	db, err := open(SQLite, SQLiteDriver, sqliteDSN(dsn, false))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	read, err := open(SQLite, SQLiteDriver, sqliteDSN(dsn, true))
+	if err != nil {
+		db.DB.Close()
+		return nil, err
+	}
+	n := max(4, runtime.NumCPU())
+	read.SetMaxOpenConns(n)
+	read.SetMaxIdleConns(n)
+	db.read = read
+
+	return db, nil
+}
+
+// Reader returns the pool to use for queries that don't write. On SQLite
+// that's the read-only pool, whose connections can't write even by mistake;
+// on Postgres it's db itself. Reads that must see a write made in the same
+// transaction belong on that transaction instead.
+func (db *DB) Reader() *DB {
+	if db.read == nil {
+		return db
+	}
+
+	return db.read
+}
+
+func open(dialect Dialect, driver, dsn string) (*DB, error) {
+	db, err := sql.Open(driver, dsn)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -72,14 +88,51 @@ func Open(path string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	db.SetMaxOpenConns(1)
-	return db, nil
+	return &DB{DB: db, Dialect: dialect}, nil
 }
 
-func Init(db *sql.DB) error {
-	if _, err := db.Exec(schema); err != nil {
+// Init applies any pending schema migrations and sets up recipe search.
+func Init(db *DB) error {
+	if err := Migrate(db); err != nil {
 		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
+	if err := setupSearch(db); err != nil {
+		return fmt.Errorf("failed to set up recipe search: %w", err)
+	}
+
+	return nil
+}
+
+// Close checkpoints the SQLite write-ahead log so the database file is
+// complete on its own, then closes the pools. Readers go first so none of
+// them holds the log open.
+func Close(db *DB) error {
+	if db.read != nil {
+		db.read.DB.Close()
+	}
+
+	if db.Dialect == SQLite {
+		if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+			db.DB.Close()
+			return fmt.Errorf("failed to checkpoint database: %w", err)
+		}
+	}
+
+	if err := db.DB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
 
 	return nil
 }
+
+// IsUniqueViolation reports whether err is a UNIQUE or PRIMARY KEY constraint
+// failing, for stores that let the database settle a race instead of
+// checking first.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Name() == "unique_violation"
+	}
+
+	return isSQLiteUniqueViolation(err)
+}
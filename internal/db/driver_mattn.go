@@ -0,0 +1,35 @@
+//go:build cgo && !purego
+
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDriver names the SQLite driver compiled in: mattn/go-sqlite3 by
+// default, modernc.org/sqlite when built with -tags purego or without cgo.
+const SQLiteDriver = "sqlite3"
+
+// sqliteDSN sets the same pragmas as the modernc variant. WAL mode sticks to
+// the file, so only the writer needs to ask for it.
+func sqliteDSN(path string, readOnly bool) string {
+	if readOnly {
+		return fmt.Sprintf("%s?_foreign_keys=on&_busy_timeout=%d&_query_only=true",
+			path, busyTimeout.Milliseconds())
+	}
+
+	return fmt.Sprintf("%s?_foreign_keys=on&_busy_timeout=%d&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate",
+		path, busyTimeout.Milliseconds())
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
@@ -0,0 +1,37 @@
+//go:build !cgo || purego
+
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteDriver names the SQLite driver compiled in: mattn/go-sqlite3 by
+// default, modernc.org/sqlite when built with -tags purego or without cgo.
+const SQLiteDriver = "sqlite"
+
+// sqliteDSN sets the same pragmas as the mattn variant. WAL mode sticks to
+// the file, so only the writer needs to ask for it. Times are written in the
+// format mattn uses, so either driver can read a database the other wrote.
+func sqliteDSN(path string, readOnly bool) string {
+	if readOnly {
+		return fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)&_pragma=query_only(1)&_time_format=sqlite",
+			path, busyTimeout.Milliseconds())
+	}
+
+	return fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate&_time_format=sqlite",
+		path, busyTimeout.Milliseconds())
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
@@ -0,0 +1,535 @@
+package db
+
+import (
+	"fmt"
+	"meal_prep/internal/quantity"
+	"strings"
+)
+
+// migration is one numbered, forward-only schema change. Each runs in its own
+// transaction together with the schema_version row that records it, so a
+// failed migration leaves the database at the previous version.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *Tx) error
+}
+
+// migrations must stay in version order, and a released migration must never
+// be edited; add a new one instead.
+var migrations = []migration{
+	{1, "initial schema", execSQL(map[Dialect]string{SQLite: `
+CREATE TABLE IF NOT EXISTS recipes (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    title       TEXT NOT NULL,
+    description TEXT,
+    servings    INTEGER,
+    prep_time   INTEGER, -- minutes
+    cook_time   INTEGER, -- minutes
+    is_public   INTEGER NOT NULL DEFAULT 0,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recipe_ingredients (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    recipe_id  INTEGER NOT NULL,
+    name       TEXT NOT NULL,
+    quantity   TEXT,
+    unit       TEXT,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recipe_steps (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    recipe_id   INTEGER NOT NULL,
+    step_no     INTEGER NOT NULL,
+    instruction TEXT NOT NULL,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS meal_plans (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date   DATE NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS meal_plan_recipes (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    meal_plan_id  INTEGER NOT NULL,
+    recipe_id     INTEGER,
+    meal_type     TEXT,
+    planned_date  DATE,
+    FOREIGN KEY (meal_plan_id) REFERENCES meal_plans(id) ON DELETE CASCADE,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE SET NULL
+);
+`, Postgres: `
+CREATE TABLE IF NOT EXISTS recipes (
+    id          SERIAL PRIMARY KEY,
+    title       TEXT NOT NULL,
+    description TEXT,
+    servings    INTEGER,
+    prep_time   INTEGER, -- minutes
+    cook_time   INTEGER, -- minutes
+    is_public   BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recipe_ingredients (
+    id         SERIAL PRIMARY KEY,
+    recipe_id  INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    quantity   TEXT,
+    unit       TEXT
+);
+
+CREATE TABLE IF NOT EXISTS recipe_steps (
+    id          SERIAL PRIMARY KEY,
+    recipe_id   INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    step_no     INTEGER NOT NULL,
+    instruction TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS meal_plans (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date   DATE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS meal_plan_recipes (
+    id            SERIAL PRIMARY KEY,
+    meal_plan_id  INTEGER NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+    recipe_id     INTEGER REFERENCES recipes(id) ON DELETE SET NULL,
+    meal_type     TEXT,
+    planned_date  DATE
+);
+`})},
+	{2, "parsed ingredient quantities", all(
+		addColumns("recipe_ingredients",
+			"quantity_value REAL", // parsed from quantity; lower bound for ranges
+			"quantity_max REAL",   // parsed from quantity; upper bound for ranges
+		),
+		parseQuantities,
+	)},
+	{3, "meal plan recipe servings", addColumns("meal_plan_recipes",
+		"servings INTEGER", // overrides recipes.servings for this entry
+	)},
+	{4, "users and ownership", all(
+		execSQL(map[Dialect]string{SQLite: `
+CREATE TABLE IF NOT EXISTS users (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    email         TEXT NOT NULL UNIQUE, -- lower-cased
+    password_hash TEXT NOT NULL,        -- bcrypt
+    created_at    DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE, -- sha256 of the token, which is never stored
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+`, Postgres: `
+CREATE TABLE IF NOT EXISTS users (
+    id            SERIAL PRIMARY KEY,
+    email         TEXT NOT NULL UNIQUE, -- lower-cased
+    password_hash TEXT NOT NULL,        -- bcrypt
+    created_at    TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE, -- sha256 of the token, which is never stored
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+`}),
+		// Rows from before accounts existed keep a NULL owner until the first
+		// user registers and claims them
+		addColumns("recipes", "owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL"),
+		addColumns("meal_plans", "owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL"),
+		execSQL(map[Dialect]string{SQLite: ownerIndexes, Postgres: ownerIndexes}),
+	)},
+	{5, "households", all(
+		execSQL(map[Dialect]string{SQLite: `
+CREATE TABLE IF NOT EXISTS households (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS household_members (
+    household_id INTEGER NOT NULL,
+    user_id      INTEGER NOT NULL,
+    role         TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    joined_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (household_id, user_id),
+    FOREIGN KEY (household_id) REFERENCES households(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS household_invites (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    household_id INTEGER NOT NULL,
+    code_hash    TEXT NOT NULL UNIQUE, -- sha256 of the code, which is never stored
+    role         TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_by   INTEGER,
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at   DATETIME NOT NULL,
+    used_by      INTEGER,
+    used_at      DATETIME,
+    FOREIGN KEY (household_id) REFERENCES households(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (used_by) REFERENCES users(id) ON DELETE SET NULL
+);
+`, Postgres: `
+CREATE TABLE IF NOT EXISTS households (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS household_members (
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role         TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    joined_at    TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (household_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS household_invites (
+    id           SERIAL PRIMARY KEY,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    code_hash    TEXT NOT NULL UNIQUE, -- sha256 of the code, which is never stored
+    role         TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_by   INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    used_by      INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    used_at      TIMESTAMPTZ
+);
+`}),
+		addColumns("recipes", "household_id INTEGER REFERENCES households(id) ON DELETE CASCADE"),
+		addColumns("meal_plans", "household_id INTEGER REFERENCES households(id) ON DELETE CASCADE"),
+		execSQL(map[Dialect]string{SQLite: personalHouseholds, Postgres: personalHouseholds}),
+	)},
+	{6, "api tokens", execSQL(map[Dialect]string{SQLite: `
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL,
+    name         TEXT NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE, -- sha256 of the token, which is never stored
+    scope        TEXT NOT NULL CHECK (scope IN ('read', 'write')),
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id ON api_tokens(user_id);
+`, Postgres: `
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE, -- sha256 of the token, which is never stored
+    scope        TEXT NOT NULL CHECK (scope IN ('read', 'write')),
+    created_at   TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id ON api_tokens(user_id);
+`})},
+	{7, "recipe tags", execSQL(map[Dialect]string{SQLite: `
+CREATE TABLE IF NOT EXISTS tags (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    household_id INTEGER NOT NULL,
+    name         TEXT NOT NULL, -- lower-cased, single spaced
+    category     TEXT,
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (household_id, name),
+    FOREIGN KEY (household_id) REFERENCES households(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recipe_tags (
+    recipe_id INTEGER NOT NULL,
+    tag_id    INTEGER NOT NULL,
+    PRIMARY KEY (recipe_id, tag_id),
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS recipe_tags_tag_id ON recipe_tags(tag_id);
+`, Postgres: `
+CREATE TABLE IF NOT EXISTS tags (
+    id           SERIAL PRIMARY KEY,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    name         TEXT NOT NULL, -- lower-cased, single spaced
+    category     TEXT,
+    created_at   TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (household_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS recipe_tags (
+    recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    tag_id    INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (recipe_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS recipe_tags_tag_id ON recipe_tags(tag_id);
+`})},
+	{8, "ingredient foods", addColumns("recipe_ingredients",
+		"food TEXT", // nutrition database food; matched by name when NULL
+	)},
+	{9, "meal plan restrictions", addColumns("meal_plans",
+		"restrictions TEXT NOT NULL DEFAULT ''", // comma-separated allergens and diets
+	)},
+	{10, "prices", all(
+		execSQL(map[Dialect]string{SQLite: `
+CREATE TABLE IF NOT EXISTS prices (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    household_id   INTEGER NOT NULL,
+    item           TEXT NOT NULL, -- lower-cased, single spaced
+    store          TEXT NOT NULL, -- lower-cased, single spaced
+    price          REAL NOT NULL, -- for quantity of unit
+    quantity       REAL NOT NULL,
+    unit           TEXT,          -- canonical; NULL when priced per item
+    effective_from TEXT NOT NULL, -- YYYY-MM-DD
+    effective_to   TEXT,          -- YYYY-MM-DD, inclusive; NULL when open-ended
+    created_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (household_id) REFERENCES households(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS prices_household_id ON prices(household_id);
+`, Postgres: `
+CREATE TABLE IF NOT EXISTS prices (
+    id             SERIAL PRIMARY KEY,
+    household_id   INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    item           TEXT NOT NULL, -- lower-cased, single spaced
+    store          TEXT NOT NULL, -- lower-cased, single spaced
+    price          DOUBLE PRECISION NOT NULL, -- for quantity of unit
+    quantity       DOUBLE PRECISION NOT NULL,
+    unit           TEXT,          -- canonical; NULL when priced per item
+    effective_from TEXT NOT NULL, -- YYYY-MM-DD
+    effective_to   TEXT,          -- YYYY-MM-DD, inclusive; NULL when open-ended
+    created_at     TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS prices_household_id ON prices(household_id);
+`}),
+		addColumns("meal_plans",
+			"budget REAL", // spending cap for the plan; NULL when uncapped
+		),
+	)},
+}
+
+// personalHouseholds gives every existing user a household of their own,
+// named after them, holding what they owned so far.
+const personalHouseholds = `
+INSERT INTO households (name, created_by) SELECT email, id FROM users ORDER BY id;
+INSERT INTO household_members (household_id, user_id, role) SELECT id, created_by, 'owner' FROM households;
+UPDATE recipes SET household_id = (SELECT h.id FROM households h WHERE h.created_by = recipes.owner_id);
+UPDATE meal_plans SET household_id = (SELECT h.id FROM households h WHERE h.created_by = meal_plans.owner_id);
+
+CREATE INDEX IF NOT EXISTS household_members_user_id ON household_members(user_id);
+CREATE INDEX IF NOT EXISTS recipes_household_id ON recipes(household_id);
+CREATE INDEX IF NOT EXISTS meal_plans_household_id ON meal_plans(household_id);
+`
+
+const ownerIndexes = `
+CREATE INDEX IF NOT EXISTS recipes_owner_id ON recipes(owner_id);
+CREATE INDEX IF NOT EXISTS meal_plans_owner_id ON meal_plans(owner_id);
+`
+
+// parseQuantities fills the parsed quantity columns of ingredients written
+// before they existed, the way the handlers do on write. Blank, qualitative
+// and unparseable quantities are left NULL.
+func parseQuantities(tx *Tx) error {
+	rows, err := tx.Query(`SELECT id, quantity FROM recipe_ingredients WHERE quantity IS NOT NULL AND quantity_value IS NULL`)
+	if err != nil {
+		return err
+	}
+	parsed := map[int]quantity.Quantity{}
+	for rows.Next() {
+		var (
+			id   int
+			text string
+		)
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		if q, err := quantity.Parse(text); err == nil && !q.Qualitative {
+			parsed[id] = q
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Written once the rows are closed; a SQLite transaction has one connection
+	for id, q := range parsed {
+		if _, err := tx.Exec(`UPDATE recipe_ingredients SET quantity_value = ?, quantity_max = ? WHERE id = ?`, q.Min, q.Max, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// all runs several steps as one migration.
+func all(steps ...func(tx *Tx) error) func(tx *Tx) error {
+	return func(tx *Tx) error {
+		for _, step := range steps {
+			if err := step(tx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// execSQL runs the statements written for the database's dialect.
+func execSQL(stmts map[Dialect]string) func(tx *Tx) error {
+	return func(tx *Tx) error {
+		_, err := tx.Exec(stmts[tx.Dialect])
+		return err
+	}
+}
+
+// postgresTypes maps the SQLite type names used in column definitions shared
+// by both dialects; SQLite's REAL is a double, Postgres' is not.
+var postgresTypes = strings.NewReplacer(
+	" REAL", " DOUBLE PRECISION",
+	" DATETIME", " TIMESTAMPTZ",
+)
+
+// columnDef adapts column definitions written for SQLite, on their own or in
+// a CREATE TABLE, to the dialect.
+func (d Dialect) columnDef(def string) string {
+	if d == Postgres {
+		return postgresTypes.Replace(def)
+	}
+
+	return def
+}
+
+// addColumns adds columns that are not there yet. Databases created before
+// migrations existed may already have some of them from the old schema
+// constant, so a plain ALTER TABLE would fail on them.
+func addColumns(table string, defs ...string) func(tx *Tx) error {
+	return func(tx *Tx) error {
+		if tx.Dialect == Postgres {
+			for _, def := range defs {
+				if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s`, table, tx.Dialect.columnDef(def))); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		rows, err := tx.Query(fmt.Sprintf(`SELECT name FROM pragma_table_info('%s')`, table))
+		if err != nil {
+			return err
+		}
+		existing := map[string]bool{}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			existing[name] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, def := range defs {
+			if column, _, _ := strings.Cut(def, " "); existing[column] {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s`, table, def)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// Migrate brings the schema up to the latest version.
+func Migrate(db *DB) error {
+	if _, err := db.Exec(db.Dialect.columnDef(`
+CREATE TABLE IF NOT EXISTS schema_version (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`)); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	current, err := Version(db)
+	if err != nil {
+		return err
+	}
+
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := apply(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func apply(db *DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO schema_version (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Version reports the schema version the database is at, 0 for a database
+// that has never been migrated.
+func Version(db *DB) (int, error) {
+	var v int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return v, nil
+}
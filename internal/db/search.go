@@ -0,0 +1,132 @@
+package db
+
+import "fmt"
+
+// The SQLite recipe search index is an FTS5 table with one row per recipe,
+// rowid = recipes.id, kept in sync by triggers. It isn't a migration because
+// whether it can exist depends on the build, not the schema version:
+// mattn/go-sqlite3 only has FTS5 with -tags sqlite_fts5, modernc always
+// does. A build without it drops the triggers, which it couldn't run, and the
+// next build with it rebuilds the index from scratch.
+const searchTable = `
+CREATE VIRTUAL TABLE IF NOT EXISTS recipe_search USING fts5(
+    title, description, ingredients, steps,
+    tokenize = 'porter unicode61 remove_diacritics 2'
+)`
+
+// ingredientsText and stepsText compute the ingredients and steps columns
+// for the recipe id they are formatted with.
+const (
+	ingredientsText = `(SELECT group_concat(name, ' ') FROM recipe_ingredients WHERE recipe_id = %[1]s)`
+	stepsText       = `(SELECT group_concat(instruction, ' ') FROM (SELECT instruction FROM recipe_steps WHERE recipe_id = %[1]s ORDER BY step_no))`
+)
+
+var searchTriggers = []struct{ name, def string }{
+	{"recipe_search_recipe_insert", `AFTER INSERT ON recipes BEGIN
+    INSERT INTO recipe_search (rowid, title, description, ingredients, steps)
+    VALUES (new.id, new.title, new.description, ` + fmt.Sprintf(ingredientsText, "new.id") + `, ` + fmt.Sprintf(stepsText, "new.id") + `);
+END`},
+	{"recipe_search_recipe_update", `AFTER UPDATE OF title, description ON recipes BEGIN
+    UPDATE recipe_search SET title = new.title, description = new.description WHERE rowid = new.id;
+END`},
+	{"recipe_search_recipe_delete", `AFTER DELETE ON recipes BEGIN
+    DELETE FROM recipe_search WHERE rowid = old.id;
+END`},
+	{"recipe_search_ingredient_insert", `AFTER INSERT ON recipe_ingredients BEGIN
+    ` + refreshSearch("ingredients", ingredientsText, "new") + `
+END`},
+	{"recipe_search_ingredient_update", `AFTER UPDATE OF recipe_id, name ON recipe_ingredients BEGIN
+    ` + refreshSearch("ingredients", ingredientsText, "old") + `
+    ` + refreshSearch("ingredients", ingredientsText, "new") + `
+END`},
+	{"recipe_search_ingredient_delete", `AFTER DELETE ON recipe_ingredients BEGIN
+    ` + refreshSearch("ingredients", ingredientsText, "old") + `
+END`},
+	{"recipe_search_step_insert", `AFTER INSERT ON recipe_steps BEGIN
+    ` + refreshSearch("steps", stepsText, "new") + `
+END`},
+	{"recipe_search_step_update", `AFTER UPDATE OF recipe_id, step_no, instruction ON recipe_steps BEGIN
+    ` + refreshSearch("steps", stepsText, "old") + `
+    ` + refreshSearch("steps", stepsText, "new") + `
+END`},
+	{"recipe_search_step_delete", `AFTER DELETE ON recipe_steps BEGIN
+    ` + refreshSearch("steps", stepsText, "old") + `
+END`},
+}
+
+// refreshSearch recomputes one column of the index row for the recipe of
+// the trigger's old or new row.
+func refreshSearch(column, text, row string) string {
+	return fmt.Sprintf(`UPDATE recipe_search SET %s = %s WHERE rowid = %s.recipe_id;`,
+		column, fmt.Sprintf(text, row+".recipe_id"), row)
+}
+
+const rebuildSearch = `
+DELETE FROM recipe_search;
+INSERT INTO recipe_search (rowid, title, description, ingredients, steps)
+SELECT r.id, r.title, r.description, %s, %s
+FROM recipes r;
+`
+
+// setupSearch brings the SQLite search index in line with what the build
+// supports and records the outcome in FullText. Postgres searches with
+// tsvectors computed at query time and needs nothing set up.
+func setupSearch(db *DB) error {
+	if db.Dialect == Postgres {
+		db.FullText = true
+		return nil
+	}
+
+	var fts5 bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !fts5 {
+		for _, t := range searchTriggers {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + t.name); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	}
+
+	var present int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'trigger' AND name LIKE 'recipe_search_%'
+	`).Scan(&present); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(searchTable); err != nil {
+		return err
+	}
+	for _, t := range searchTriggers {
+		if _, err := tx.Exec(`CREATE TRIGGER IF NOT EXISTS ` + t.name + ` ` + t.def); err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+
+	// Missing triggers mean the index missed writes, or never existed
+	if present != len(searchTriggers) {
+		if _, err := tx.Exec(fmt.Sprintf(rebuildSearch,
+			fmt.Sprintf(ingredientsText, "r.id"), fmt.Sprintf(stepsText, "r.id"))); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	db.FullText = true
+	db.read.FullText = true
+	return nil
+}
@@ -0,0 +1,174 @@
+// Package diet works out which allergens a recipe contains and which diets it
+// suits from the names of its ingredients, and checks recipes against the
+// restrictions a meal plan declares.
+//
+// Classification goes by keywords: "flour" has gluten, "peanut butter" has
+// peanuts but no dairy, and qualifiers such as "gluten-free" or "vegan" clear
+// what the rest of the name would flag. Names it doesn't know flag nothing,
+// so a recipe is only as well classified as its ingredients are named.
+package diet
+
+import (
+	"fmt"
+	"meal_prep/internal/words"
+	"slices"
+	"strings"
+)
+
+type Allergen string
+
+const (
+	Gluten    Allergen = "gluten"
+	Dairy     Allergen = "dairy"
+	Egg       Allergen = "egg"
+	Nuts      Allergen = "nuts" // tree nuts
+	Peanuts   Allergen = "peanuts"
+	Fish      Allergen = "fish"
+	Shellfish Allergen = "shellfish"
+	Soy       Allergen = "soy"
+	Sesame    Allergen = "sesame"
+)
+
+// Allergens lists every allergen, in the order profiles report them.
+var Allergens = []Allergen{Gluten, Dairy, Egg, Nuts, Peanuts, Fish, Shellfish, Soy, Sesame}
+
+type Diet string
+
+const (
+	Vegetarian  Diet = "vegetarian"
+	Vegan       Diet = "vegan"
+	Pescatarian Diet = "pescatarian"
+)
+
+// Diets lists every diet, in the order profiles report them.
+var Diets = []Diet{Vegetarian, Vegan, Pescatarian}
+
+// Profile is what a recipe's ingredients add up to.
+type Profile struct {
+	Allergens []Allergen `json:"allergens"` // the ones it contains
+	Diets     []Diet     `json:"diets"`     // the ones it suits
+
+	// causes names, for each allergen it contains and each diet it doesn't
+	// suit, the ingredients responsible
+	causes map[string][]string
+}
+
+// Classify profiles a recipe with the ingredients named. A recipe without
+// ingredients suits no diet, as nothing is known of what goes in it.
+func Classify(ingredientNames []string) Profile {
+	p := Profile{Allergens: []Allergen{}, Diets: []Diet{}, causes: map[string][]string{}}
+	if len(ingredientNames) == 0 {
+		return p
+	}
+
+	var all traits
+	for _, name := range ingredientNames {
+		t := classify(name)
+		for _, a := range Allergens {
+			if t&allergenTraits[a] != 0 {
+				p.causes[string(a)] = append(p.causes[string(a)], name)
+			}
+		}
+		for _, d := range Diets {
+			if t&ruledOut[d] != 0 {
+				p.causes[string(d)] = append(p.causes[string(d)], name)
+			}
+		}
+		all |= t
+	}
+
+	for _, a := range Allergens {
+		if all&allergenTraits[a] != 0 {
+			p.Allergens = append(p.Allergens, a)
+		}
+	}
+	for _, d := range Diets {
+		if all&ruledOut[d] == 0 {
+			p.Diets = append(p.Diets, d)
+		}
+	}
+
+	return p
+}
+
+// Violation is a restriction a recipe breaks and the ingredients that break
+// it, none for a diet the recipe can't be shown to suit.
+type Violation struct {
+	Restriction string   `json:"restriction"`
+	Ingredients []string `json:"ingredients"`
+}
+
+// Violations checks the profile against restrictions as ParseRestrictions
+// returns them.
+func (p Profile) Violations(restrictions []string) []Violation {
+	var list []Violation
+	for _, r := range restrictions {
+		switch causes := p.causes[r]; {
+		case len(causes) > 0:
+			list = append(list, Violation{Restriction: r, Ingredients: causes})
+		case slices.Contains(Diets, Diet(r)) && !slices.Contains(p.Diets, Diet(r)):
+			list = append(list, Violation{Restriction: r, Ingredients: []string{}})
+		}
+	}
+
+	return list
+}
+
+// ParseRestrictions checks that each restriction is an allergen, meaning the
+// plan must be free of it, or a diet the plan must suit. It returns them
+// lower-cased, without duplicates and in the order Allergens and Diets list
+// them.
+func ParseRestrictions(restrictions []string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, r := range restrictions {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if !slices.Contains(Allergens, Allergen(r)) && !slices.Contains(Diets, Diet(r)) {
+			return nil, fmt.Errorf("unknown restriction %q, want one of %s", r, strings.Join(names(), ", "))
+		}
+		seen[r] = true
+	}
+
+	list := []string{}
+	for _, name := range names() {
+		if seen[name] {
+			list = append(list, name)
+		}
+	}
+
+	return list, nil
+}
+
+// names lists every restriction.
+func names() []string {
+	var list []string
+	for _, a := range Allergens {
+		list = append(list, string(a))
+	}
+	for _, d := range Diets {
+		list = append(list, string(d))
+	}
+
+	return list
+}
+
+// classify flags one ingredient. It reads the name's words left to right,
+// taking the longest keyword phrase at each word, so "peanut butter" is read
+// as one phrase rather than as peanut and butter.
+func classify(name string) traits {
+	split := words.Split(name)
+
+	var has, clears traits
+	for i := 0; i < len(split); {
+		n := min(maxPhraseWords, len(split)-i)
+		for ; n > 0; n-- {
+			if k, ok := keywords[strings.Join(split[i:i+n], " ")]; ok {
+				has |= k.has
+				clears |= k.clears
+				break
+			}
+		}
+		i += max(n, 1)
+	}
+
+	return has &^ clears
+}
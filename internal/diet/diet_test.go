@@ -0,0 +1,101 @@
+package diet
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	all := []Diet{Vegetarian, Vegan, Pescatarian}
+
+	tests := []struct {
+		name          string
+		ingredients   []string
+		wantAllergens []Allergen
+		wantDiets     []Diet
+	}{
+		{"no ingredients", nil, []Allergen{}, []Diet{}},
+		{"unknown ingredient", []string{"carrots"}, []Allergen{}, all},
+		{"peanut butter is one phrase", []string{"peanut butter"}, []Allergen{Peanuts}, all},
+		{"butternut squash is not butter", []string{"butternut squash"}, []Allergen{}, all},
+		{"vegetable broth", []string{"vegetable broth"}, []Allergen{}, all},
+		{"chicken broth", []string{"chicken broth"}, []Allergen{}, []Diet{}},
+		{"coconut milk is not dairy", []string{"coconut milk"}, []Allergen{}, all},
+		{"vegan clears butter", []string{"vegan butter"}, []Allergen{}, all},
+		{"gluten-free clears flour", []string{"gluten-free flour"}, []Allergen{}, all},
+		{"soy sauce", []string{"soy sauce"}, []Allergen{Gluten, Soy}, all},
+		{"plurals match", []string{"Eggs", "Salmon fillets"}, []Allergen{Egg, Fish}, []Diet{Pescatarian}},
+		{"honey rules out vegan only", []string{"honey"}, []Allergen{}, []Diet{Vegetarian, Pescatarian}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Classify(tt.ingredients)
+			if !slices.Equal(p.Allergens, tt.wantAllergens) {
+				t.Errorf("Allergens = %v, want %v", p.Allergens, tt.wantAllergens)
+			}
+			if !slices.Equal(p.Diets, tt.wantDiets) {
+				t.Errorf("Diets = %v, want %v", p.Diets, tt.wantDiets)
+			}
+		})
+	}
+}
+
+func TestViolations(t *testing.T) {
+	tests := []struct {
+		name         string
+		ingredients  []string
+		restrictions []string
+		want         []Violation
+	}{
+		{"suits", []string{"tofu"}, []string{"vegan"}, nil},
+		{"contains allergen", []string{"flour", "butter"}, []string{"gluten", "dairy"}, []Violation{
+			{Restriction: "gluten", Ingredients: []string{"flour"}},
+			{Restriction: "dairy", Ingredients: []string{"butter"}},
+		}},
+		{"breaks diet", []string{"bacon", "eggs"}, []string{"vegetarian"}, []Violation{
+			{Restriction: "vegetarian", Ingredients: []string{"bacon"}},
+		}},
+		{"no ingredients suit no diet", nil, []string{"vegan", "nuts"}, []Violation{
+			{Restriction: "vegan", Ingredients: []string{}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.ingredients).Violations(tt.restrictions)
+			if !slices.EqualFunc(got, tt.want, func(a, b Violation) bool {
+				return a.Restriction == b.Restriction && slices.Equal(a.Ingredients, b.Ingredients)
+			}) {
+				t.Errorf("Violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRestrictions(t *testing.T) {
+	tests := []struct {
+		name         string
+		restrictions []string
+		want         []string
+	}{
+		{"none", nil, []string{}},
+		{"normalized and deduplicated", []string{"Vegan", " NUTS ", "nuts"}, []string{"nuts", "vegan"}},
+		{"allergens before diets", []string{"pescatarian", "sesame", "gluten"}, []string{"gluten", "sesame", "pescatarian"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRestrictions(tt.restrictions)
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("ParseRestrictions = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range [][]string{{"keto"}, {"vegan", ""}} {
+		if got, err := ParseRestrictions(bad); err == nil {
+			t.Errorf("ParseRestrictions(%q) = %q, want an error", bad, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package diet
+
+import (
+	"meal_prep/internal/words"
+	"strings"
+)
+
+// traits are what an ingredient can be flagged with: the allergens, plus
+// meat and other animal products for the diets.
+type traits uint16
+
+const (
+	gluten traits = 1 << iota
+	dairy
+	egg
+	nuts
+	peanuts
+	fish
+	shellfish
+	soy
+	sesame
+	meat   // meat and poultry, and what's made from them such as gelatin
+	animal // other animal products, such as honey
+
+	// animalProducts is everything a vegan diet rules out
+	animalProducts = dairy | egg | fish | shellfish | meat | animal
+)
+
+var allergenTraits = map[Allergen]traits{
+	Gluten:    gluten,
+	Dairy:     dairy,
+	Egg:       egg,
+	Nuts:      nuts,
+	Peanuts:   peanuts,
+	Fish:      fish,
+	Shellfish: shellfish,
+	Soy:       soy,
+	Sesame:    sesame,
+}
+
+// ruledOut is what keeps a recipe from suiting each diet.
+var ruledOut = map[Diet]traits{
+	Vegetarian:  meat | fish | shellfish,
+	Vegan:       animalProducts,
+	Pescatarian: meat,
+}
+
+// keyword is what a phrase in an ingredient name flags, and what it clears
+// from the whole name.
+type keyword struct {
+	has    traits
+	clears traits
+}
+
+// keywordList is keyed by phrases as written; keywords re-keys it by
+// words.Key. Phrases that contain a keyword but mean something else, such as
+// "coconut milk", are listed with nothing to flag.
+var keywordList = map[string]keyword{
+	// Gluten
+	"flour": {has: gluten}, "wheat": {has: gluten}, "bread": {has: gluten}, "breadcrumbs": {has: gluten},
+	"panko": {has: gluten}, "pasta": {has: gluten}, "spaghetti": {has: gluten}, "penne": {has: gluten},
+	"macaroni": {has: gluten}, "fusilli": {has: gluten}, "linguine": {has: gluten}, "fettuccine": {has: gluten},
+	"lasagna": {has: gluten}, "orzo": {has: gluten}, "noodles": {has: gluten}, "ramen": {has: gluten},
+	"udon": {has: gluten}, "couscous": {has: gluten}, "bulgur": {has: gluten}, "barley": {has: gluten},
+	"rye": {has: gluten}, "spelt": {has: gluten}, "farro": {has: gluten}, "semolina": {has: gluten},
+	"seitan": {has: gluten}, "tortilla": {has: gluten}, "pita": {has: gluten}, "bagel": {has: gluten},
+	"crackers": {has: gluten}, "croutons": {has: gluten}, "pastry": {has: gluten}, "pie crust": {has: gluten},
+	"beer": {has: gluten}, "malt": {has: gluten},
+	"rice flour": {}, "corn flour": {}, "cornflour": {}, "coconut flour": {}, "chickpea flour": {},
+	"buckwheat flour": {}, "potato flour": {}, "rice noodles": {}, "corn tortilla": {},
+	"almond flour": {has: nuts}, "egg noodles": {has: gluten | egg},
+
+	// Dairy
+	"milk": {has: dairy}, "butter": {has: dairy}, "cream": {has: dairy}, "cheese": {has: dairy},
+	"yogurt": {has: dairy}, "yoghurt": {has: dairy}, "ghee": {has: dairy}, "buttermilk": {has: dairy},
+	"whey": {has: dairy}, "parmesan": {has: dairy}, "mozzarella": {has: dairy}, "cheddar": {has: dairy},
+	"feta": {has: dairy}, "ricotta": {has: dairy}, "mascarpone": {has: dairy}, "brie": {has: dairy},
+	"gouda": {has: dairy}, "halloumi": {has: dairy}, "paneer": {has: dairy}, "creme fraiche": {has: dairy},
+	"sour cream": {has: dairy}, "half-and-half": {has: dairy},
+	"coconut milk": {}, "coconut cream": {}, "oat milk": {}, "rice milk": {}, "cocoa butter": {},
+	"apple butter": {}, "cream of tartar": {},
+	"almond milk": {has: nuts}, "almond butter": {has: nuts}, "cashew butter": {has: nuts},
+	"soy milk": {has: soy}, "peanut butter": {has: peanuts},
+
+	// Egg
+	"egg": {has: egg}, "mayonnaise": {has: egg}, "mayo": {has: egg}, "meringue": {has: egg},
+	"aioli": {has: egg},
+
+	// Tree nuts
+	"nuts": {has: nuts}, "almonds": {has: nuts}, "walnuts": {has: nuts}, "cashews": {has: nuts},
+	"pecans": {has: nuts}, "pistachios": {has: nuts}, "hazelnuts": {has: nuts}, "macadamia": {has: nuts},
+	"brazil nuts": {has: nuts}, "pine nuts": {has: nuts}, "chestnuts": {has: nuts}, "praline": {has: nuts},
+	"marzipan": {has: nuts}, "pesto": {has: nuts | dairy},
+	"water chestnuts": {},
+
+	// Peanuts
+	"peanuts": {has: peanuts}, "groundnuts": {has: peanuts}, "satay": {has: peanuts},
+
+	// Fish
+	"fish": {has: fish}, "salmon": {has: fish}, "tuna": {has: fish}, "cod": {has: fish},
+	"haddock": {has: fish}, "anchovies": {has: fish}, "sardines": {has: fish}, "trout": {has: fish},
+	"mackerel": {has: fish}, "tilapia": {has: fish}, "halibut": {has: fish}, "bonito": {has: fish},
+	"fish sauce": {has: fish}, "worcestershire sauce": {has: fish},
+
+	// Shellfish
+	"shrimp": {has: shellfish}, "prawns": {has: shellfish}, "crab": {has: shellfish},
+	"lobster": {has: shellfish}, "mussels": {has: shellfish}, "clams": {has: shellfish},
+	"oysters": {has: shellfish}, "scallops": {has: shellfish}, "squid": {has: shellfish},
+	"calamari": {has: shellfish}, "crayfish": {has: shellfish}, "oyster sauce": {has: shellfish},
+	"oyster mushrooms": {},
+
+	// Soy
+	"soy": {has: soy}, "soya": {has: soy}, "soybeans": {has: soy}, "tofu": {has: soy},
+	"tempeh": {has: soy}, "edamame": {has: soy}, "miso": {has: soy}, "tamari": {has: soy},
+	"soy sauce": {has: soy | gluten}, "soya sauce": {has: soy | gluten},
+
+	// Sesame
+	"sesame": {has: sesame}, "tahini": {has: sesame}, "hummus": {has: sesame},
+
+	// Meat
+	"meat": {has: meat}, "beef": {has: meat}, "pork": {has: meat}, "chicken": {has: meat},
+	"turkey": {has: meat}, "lamb": {has: meat}, "mutton": {has: meat}, "veal": {has: meat},
+	"bacon": {has: meat}, "ham": {has: meat}, "sausages": {has: meat}, "chorizo": {has: meat},
+	"salami": {has: meat}, "prosciutto": {has: meat}, "pancetta": {has: meat}, "pepperoni": {has: meat},
+	"duck": {has: meat}, "venison": {has: meat}, "mince": {has: meat}, "steak": {has: meat},
+	"gelatin": {has: meat}, "gelatine": {has: meat}, "lard": {has: meat}, "suet": {has: meat},
+
+	// Other animal products
+	"honey": {has: animal},
+
+	// Qualifiers
+	"gluten-free": {clears: gluten}, "gluten free": {clears: gluten},
+	"dairy-free": {clears: dairy}, "dairy free": {clears: dairy},
+	"egg-free": {clears: egg}, "nut-free": {clears: nuts | peanuts},
+	"vegetarian": {clears: meat | fish | shellfish}, "meat-free": {clears: meat}, "meatless": {clears: meat},
+	"vegan": {clears: animalProducts}, "plant-based": {clears: animalProducts},
+}
+
+var keywords = func() map[string]keyword {
+	m := make(map[string]keyword, len(keywordList))
+	for phrase, k := range keywordList {
+		m[words.Key(phrase)] = k
+	}
+
+	return m
+}()
+
+// maxPhraseWords is the most words a keyword phrase has.
+var maxPhraseWords = func() int {
+	n := 0
+	for phrase := range keywords {
+		n = max(n, strings.Count(phrase, " ")+1)
+	}
+
+	return n
+}()
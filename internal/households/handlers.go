@@ -0,0 +1,291 @@
+package households
+
+import (
+	"errors"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/patch"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateHouseholdRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type UpdateHouseholdRequest struct {
+	Name patch.Field[string] `json:"name"`
+}
+
+type CreateInviteRequest struct {
+	Role Role `json:"role"` // defaults to viewer
+}
+
+type JoinRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role Role `json:"role" binding:"required"`
+}
+
+// HouseholdDetails is a household together with who is in it.
+type HouseholdDetails struct {
+	Household
+	Members []Member `json:"members"`
+}
+
+// loadHousehold resolves the :id household of the management routes and
+// checks the caller's role in it. Non-members get the same 404 as a missing
+// household.
+func loadHousehold(c *gin.Context, store HouseholdStore, need Role) (Household, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid household id"})
+		return Household{}, false
+	}
+
+	h, err := store.GetHousehold(c.Request.Context(), auth.UserID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "household not found"})
+		return h, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return h, false
+	}
+	if !h.Role.Allows(need) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires the " + string(need) + " role in this household"})
+		return h, false
+	}
+
+	return h, true
+}
+
+func ListHouseholdsHandler(c *gin.Context, store HouseholdStore) {
+	list, err := store.ListHouseholds(c.Request.Context(), auth.UserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query households"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func CreateHouseholdHandler(c *gin.Context, store HouseholdStore) {
+	var req CreateHouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	h, err := store.CreateHousehold(c.Request.Context(), auth.UserID(c), strings.TrimSpace(req.Name))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create household"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, h)
+}
+
+func GetHouseholdHandler(c *gin.Context, store HouseholdStore) {
+	h, ok := loadHousehold(c, store, Viewer)
+	if !ok {
+		return
+	}
+
+	members, err := store.ListMembers(c.Request.Context(), h.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, HouseholdDetails{Household: h, Members: members})
+}
+
+func UpdateHouseholdHandler(c *gin.Context, store HouseholdStore) {
+	h, ok := loadHousehold(c, store, Owner)
+	if !ok {
+		return
+	}
+
+	var req UpdateHouseholdRequest
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if err := req.Name.Apply(&h.Name); err != nil || strings.TrimSpace(h.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+
+	h, err := store.RenameHousehold(c.Request.Context(), auth.UserID(c), h.ID, strings.TrimSpace(h.Name))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h)
+}
+
+func DeleteHouseholdHandler(c *gin.Context, store HouseholdStore) {
+	h, ok := loadHousehold(c, store, Owner)
+	if !ok {
+		return
+	}
+
+	if err := store.DeleteHousehold(c.Request.Context(), h.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func CreateInviteHandler(c *gin.Context, store HouseholdStore) {
+	h, ok := loadHousehold(c, store, Owner)
+	if !ok {
+		return
+	}
+
+	var req CreateInviteRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	if req.Role == "" {
+		req.Role = Viewer
+	}
+	if !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, editor or viewer"})
+		return
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invite"})
+		return
+	}
+
+	inv, err := store.CreateInvite(c.Request.Context(), Invite{
+		HouseholdID: h.ID,
+		CodeHash:    auth.HashToken(code),
+		Role:        req.Role,
+		CreatedBy:   auth.UserID(c),
+		ExpiresAt:   time.Now().UTC().Add(InviteTTL).Truncate(time.Second),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invite"})
+		return
+	}
+
+	// The code is only ever shown here
+	inv.Code = code
+	c.JSON(http.StatusCreated, inv)
+}
+
+func JoinHouseholdHandler(c *gin.Context, store HouseholdStore) {
+	var req JoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	h, err := store.AcceptInvite(c.Request.Context(), auth.HashToken(normalizeCode(req.Code)), auth.UserID(c))
+	switch {
+	case errors.Is(err, ErrInviteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to join household"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h)
+}
+
+func parseMemberID(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func UpdateMemberHandler(c *gin.Context, store HouseholdStore) {
+	h, ok := loadHousehold(c, store, Owner)
+	if !ok {
+		return
+	}
+	userID, ok := parseMemberID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, editor or viewer"})
+		return
+	}
+
+	m, err := store.SetMemberRole(c.Request.Context(), h.ID, userID, req.Role)
+	switch {
+	case errors.Is(err, ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	case errors.Is(err, ErrLastOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, m)
+}
+
+// RemoveMemberHandler lets owners remove anyone, and anyone leave.
+func RemoveMemberHandler(c *gin.Context, store HouseholdStore) {
+	userID, ok := parseMemberID(c)
+	if !ok {
+		return
+	}
+
+	need := Owner
+	if userID == auth.UserID(c) {
+		need = Viewer
+	}
+	h, ok := loadHousehold(c, store, need)
+	if !ok {
+		return
+	}
+
+	err := store.RemoveMember(c.Request.Context(), h.ID, userID)
+	switch {
+	case errors.Is(err, ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	case errors.Is(err, ErrLastOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove member"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,255 @@
+package households_test
+
+import (
+	"context"
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/households"
+	"meal_prep/internal/recipes"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func createHousehold(cl *apitest.Client, name string) households.Household {
+	var h households.Household
+	cl.Create("/v1/households", map[string]any{"name": name}, &h)
+	return h
+}
+
+func invite(cl *apitest.Client, householdID int, role households.Role) string {
+	var inv households.Invite
+	cl.Create("/v1/households/"+strconv.Itoa(householdID)+"/invites", map[string]any{"role": role}, &inv)
+	return inv.Code
+}
+
+func join(cl *apitest.Client, code string) int {
+	return cl.Do("POST", "/v1/households/join", map[string]any{"code": code}, nil)
+}
+
+func memberPath(householdID, userID int) string {
+	return "/v1/households/" + strconv.Itoa(householdID) + "/members/" + strconv.Itoa(userID)
+}
+
+func TestHouseholdRoles(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		alice := apitest.NewClient(t, s, "alice@example.com")
+		bob := apitest.NewClient(t, s, "bob@example.com")
+		carol := apitest.NewClient(t, s, "carol@example.com")
+		shared := createHousehold(alice, "Shared")
+		if shared.Role != households.Owner {
+			t.Errorf("creator's role = %q, want owner", shared.Role)
+		}
+
+		// Codes are accepted however they're typed back
+		code := invite(alice, shared.ID, households.Viewer)
+		if code := join(bob, strings.ToLower(strings.ReplaceAll(code, "-", " "))); code != http.StatusOK {
+			t.Fatalf("viewer join: status %d", code)
+		}
+		if code := join(carol, invite(alice, shared.ID, households.Editor)); code != http.StatusOK {
+			t.Fatalf("editor join: status %d", code)
+		}
+		alice.Household, bob.Household, carol.Household = shared.ID, shared.ID, shared.ID
+
+		var soup recipes.Recipe
+		alice.Create("/v1/recipes", map[string]any{"title": "Soup"}, &soup)
+		path := "/v1/recipes/" + strconv.Itoa(soup.ID)
+
+		tests := []struct {
+			name   string
+			cl     *apitest.Client
+			method string
+			path   string
+			body   any
+			want   int
+		}{
+			{"viewer reads", bob, "GET", path, nil, http.StatusOK},
+			{"viewer can't write", bob, "PATCH", path, map[string]any{"title": "Broth"}, http.StatusForbidden},
+			{"viewer can't create", bob, "POST", "/v1/recipes", map[string]any{"title": "Stew"}, http.StatusForbidden},
+			{"viewer can't delete", bob, "DELETE", path, nil, http.StatusForbidden},
+			{"editor writes", carol, "PATCH", path, map[string]any{"title": "Broth"}, http.StatusOK},
+			{"editor creates", carol, "POST", "/v1/recipes", map[string]any{"title": "Stew"}, http.StatusCreated},
+			{"editor can't invite", carol, "POST", "/v1/households/" + strconv.Itoa(shared.ID) + "/invites", nil, http.StatusForbidden},
+			{"editor can't rename", carol, "PATCH", "/v1/households/" + strconv.Itoa(shared.ID), map[string]any{"name": "Mine"}, http.StatusForbidden},
+			{"editor can't change roles", carol, "PATCH", memberPath(shared.ID, bob.User.ID), map[string]any{"role": "owner"}, http.StatusForbidden},
+			{"owner changes roles", alice, "PATCH", memberPath(shared.ID, bob.User.ID), map[string]any{"role": "editor"}, http.StatusOK},
+			{"promoted viewer writes", bob, "PATCH", path, map[string]any{"title": "Soup"}, http.StatusOK},
+		}
+		for _, tt := range tests {
+			if code := tt.cl.Do(tt.method, tt.path, tt.body, nil); code != tt.want {
+				t.Errorf("%s: status %d, want %d", tt.name, code, tt.want)
+			}
+		}
+
+		var details households.HouseholdDetails
+		if code := bob.Do("GET", "/v1/households/"+strconv.Itoa(shared.ID), nil, &details); code != http.StatusOK || len(details.Members) != 3 {
+			t.Errorf("get household: status %d, members %+v", code, details.Members)
+		}
+	})
+}
+
+func TestHouseholdHeader(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		alice := apitest.NewClient(t, s, "alice@example.com")
+		bob := apitest.NewClient(t, s, "bob@example.com")
+		var own []households.Household
+		alice.Do("GET", "/v1/households", nil, &own)
+		shared := createHousehold(alice, "Shared")
+		join(bob, invite(alice, shared.ID, households.Editor))
+
+		titles := func(cl *apitest.Client) []string {
+			t.Helper()
+			var list []recipes.Recipe
+			if code := cl.Do("GET", "/v1/recipes", nil, &list); code != http.StatusOK {
+				t.Fatalf("list: status %d", code)
+			}
+			out := []string{}
+			for _, r := range list {
+				out = append(out, r.Title)
+			}
+			return out
+		}
+
+		// Without the header, requests work in the first household joined
+		bob.Create("/v1/recipes", map[string]any{"title": "Bob's own"}, nil)
+		if got := titles(bob); len(got) != 1 || got[0] != "Bob's own" {
+			t.Errorf("without the header: %q", got)
+		}
+
+		bob.Household = shared.ID
+		bob.Create("/v1/recipes", map[string]any{"title": "For everyone"}, nil)
+		if got := titles(bob); len(got) != 1 || got[0] != "For everyone" {
+			t.Errorf("in the shared household: %q", got)
+		}
+		alice.Household = shared.ID
+		if got := titles(alice); len(got) != 1 || got[0] != "For everyone" {
+			t.Errorf("alice in the shared household: %q", got)
+		}
+
+		// Alice's own household isn't one bob can pick
+		bob.Household = own[0].ID
+		if code := bob.Do("GET", "/v1/recipes", nil, nil); code != http.StatusNotFound {
+			t.Errorf("someone else's household: status %d, want 404", code)
+		}
+		if code := bob.Do("GET", "/v1/households/"+strconv.Itoa(own[0].ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("get someone else's household: status %d, want 404", code)
+		}
+	})
+}
+
+func TestInvites(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		alice := apitest.NewClient(t, s, "alice@example.com")
+		bob := apitest.NewClient(t, s, "bob@example.com")
+		carol := apitest.NewClient(t, s, "carol@example.com")
+		shared := createHousehold(alice, "Shared")
+
+		var inv households.Invite
+		alice.Create("/v1/households/"+strconv.Itoa(shared.ID)+"/invites", nil, &inv)
+		if inv.Role != households.Viewer || inv.Code == "" || time.Until(inv.ExpiresAt) < households.InviteTTL-time.Minute {
+			t.Errorf("invite = %+v, want a viewer code for a week", inv)
+		}
+		if code := alice.Do("POST", "/v1/households/"+strconv.Itoa(shared.ID)+"/invites", map[string]any{"role": "chef"}, nil); code != http.StatusBadRequest {
+			t.Errorf("unknown role: status %d, want 400", code)
+		}
+
+		if code := join(alice, inv.Code); code != http.StatusConflict {
+			t.Errorf("joining your own household: status %d, want 409", code)
+		}
+		if code := join(bob, inv.Code); code != http.StatusOK {
+			t.Fatalf("join: status %d", code)
+		}
+		if code := join(carol, inv.Code); code != http.StatusNotFound {
+			t.Errorf("reusing a code: status %d, want 404", code)
+		}
+		if code := join(carol, "AAAA-BBBB-CCCC-DDDD"); code != http.StatusNotFound {
+			t.Errorf("unknown code: status %d, want 404", code)
+		}
+
+		const expired = "EEEE-EEEE-EEEE-EEEE"
+		if _, err := s.CreateInvite(context.Background(), households.Invite{
+			HouseholdID: shared.ID,
+			CodeHash:    auth.HashToken(expired),
+			Role:        households.Editor,
+			CreatedBy:   alice.User.ID,
+			ExpiresAt:   time.Now().UTC().Add(-time.Minute),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if code := join(carol, expired); code != http.StatusNotFound {
+			t.Errorf("expired code: status %d, want 404", code)
+		}
+	})
+}
+
+// However many redeem one code at once, it lets one person in.
+func TestInviteRace(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		alice := apitest.NewClient(t, s, "alice@example.com")
+		shared := createHousehold(alice, "Shared")
+		code := invite(alice, shared.ID, households.Editor)
+
+		var guests []*apitest.Client
+		for i := range 8 {
+			guests = append(guests, apitest.NewClient(t, s, "guest"+strconv.Itoa(i)+"@example.com"))
+		}
+
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			status = map[int]int{}
+		)
+		for _, g := range guests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := g.Request("POST", "/v1/households/join", map[string]any{"code": code})
+				mu.Lock()
+				status[w.Code]++
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		if status[http.StatusOK] != 1 || status[http.StatusNotFound] != len(guests)-1 {
+			t.Errorf("statuses = %v, want one 200 and the rest 404", status)
+		}
+	})
+}
+
+func TestLastOwner(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		alice := apitest.NewClient(t, s, "alice@example.com")
+		bob := apitest.NewClient(t, s, "bob@example.com")
+		shared := createHousehold(alice, "Shared")
+		join(bob, invite(alice, shared.ID, households.Editor))
+		aliceMember, bobMember := memberPath(shared.ID, alice.User.ID), memberPath(shared.ID, bob.User.ID)
+
+		if code := alice.Do("PATCH", aliceMember, map[string]any{"role": "editor"}, nil); code != http.StatusConflict {
+			t.Errorf("last owner stepping down: status %d, want 409", code)
+		}
+		if code := alice.Do("DELETE", aliceMember, nil, nil); code != http.StatusConflict {
+			t.Errorf("last owner leaving: status %d, want 409", code)
+		}
+		if code := bob.Do("DELETE", aliceMember, nil, nil); code != http.StatusForbidden {
+			t.Errorf("editor removing the owner: status %d, want 403", code)
+		}
+
+		var promoted households.Member
+		if code := alice.Do("PATCH", bobMember, map[string]any{"role": "owner"}, &promoted); code != http.StatusOK || promoted.Role != households.Owner {
+			t.Fatalf("promote: status %d, %+v", code, promoted)
+		}
+		if code := alice.Do("DELETE", aliceMember, nil, nil); code != http.StatusNoContent {
+			t.Fatalf("leaving with another owner: status %d", code)
+		}
+		if code := alice.Do("GET", "/v1/households/"+strconv.Itoa(shared.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("household after leaving: status %d, want 404", code)
+		}
+		if code := bob.Do("PATCH", bobMember, map[string]any{"role": "viewer"}, nil); code != http.StatusConflict {
+			t.Errorf("new last owner stepping down: status %d, want 409", code)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+// Package households groups users who cook together. Recipes and meal plans
+// belong to a household rather than a person, and what a member may do with
+// them depends on their role. Every user starts out with a household of
+// their own and can join others with a one-time invite code.
+package households
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"meal_prep/internal/auth"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Role string
+
+const (
+	Owner  Role = "owner"  // manages members and invites, and can delete the household
+	Editor Role = "editor" // changes recipes and meal plans
+	Viewer Role = "viewer" // read-only
+)
+
+var roleRank = map[Role]int{Viewer: 1, Editor: 2, Owner: 3}
+
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows reports whether r includes everything need can do.
+func (r Role) Allows(need Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[need]
+}
+
+const InviteTTL = 7 * 24 * time.Hour
+
+var (
+	ErrNotFound       = errors.New("household not found")
+	ErrMemberNotFound = errors.New("member not found")
+	ErrLastOwner      = errors.New("a household must keep at least one owner")
+	ErrInviteNotFound = errors.New("invite not found, used or expired")
+	ErrAlreadyMember  = errors.New("already a member of this household")
+)
+
+type Household struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role"` // the caller's
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type Member struct {
+	UserID   int        `json:"user_id"`
+	Email    string     `json:"email"`
+	Role     Role       `json:"role"`
+	JoinedAt *time.Time `json:"joined_at,omitempty"`
+}
+
+type Invite struct {
+	ID          int       `json:"id"`
+	HouseholdID int       `json:"household_id"`
+	Code        string    `json:"code,omitempty"` // only when the invite is created
+	CodeHash    string    `json:"-"`
+	Role        Role      `json:"role"`
+	CreatedBy   int       `json:"created_by"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// HouseholdStore is the persistence behind households, their members and
+// invites. Households a user doesn't belong to are reported as ErrNotFound.
+type HouseholdStore interface {
+	// ListHouseholds returns the user's households, oldest membership first,
+	// each with the user's role.
+	ListHouseholds(ctx context.Context, userID int) ([]Household, error)
+	GetHousehold(ctx context.Context, userID, id int) (Household, error)
+	// CreateHousehold makes the user its owner.
+	CreateHousehold(ctx context.Context, userID int, name string) (Household, error)
+	RenameHousehold(ctx context.Context, userID, id int, name string) (Household, error)
+	// DeleteHousehold removes the household with its recipes, meal plans,
+	// members and invites.
+	DeleteHousehold(ctx context.Context, id int) error
+
+	ListMembers(ctx context.Context, householdID int) ([]Member, error)
+	// SetMemberRole and RemoveMember fail with ErrLastOwner rather than leave
+	// the household without an owner.
+	SetMemberRole(ctx context.Context, householdID, userID int, role Role) (Member, error)
+	RemoveMember(ctx context.Context, householdID, userID int) error
+
+	CreateInvite(ctx context.Context, inv Invite) (Invite, error)
+	// AcceptInvite adds the user with the invite's role and uses the invite
+	// up. Unknown, used and expired codes are all ErrInviteNotFound.
+	AcceptInvite(ctx context.Context, codeHash string, userID int) (Household, error)
+}
+
+// codeEncoding avoids padding so codes are plain letters and digits.
+var codeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newInviteCode returns a code short enough to read out or type, in groups
+// of four: 80 random bits.
+func newInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	s := codeEncoding.EncodeToString(b)
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16], nil
+}
+
+// normalizeCode accepts a code however it was typed back.
+func normalizeCode(s string) string {
+	s = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(s))
+	if len(s) != 16 {
+		return s
+	}
+
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16]
+}
+
+const (
+	HeaderName   = "X-Household-ID"
+	householdKey = "households.household"
+)
+
+// Resolve picks the household a request works in: the one named by the
+// X-Household-ID header, or else the first the user joined. It runs after
+// auth.Required.
+func Resolve(store HouseholdStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		userID := auth.UserID(c)
+
+		var (
+			h   Household
+			err error
+		)
+		if v := c.GetHeader(HeaderName); v != "" {
+			id, convErr := strconv.Atoi(v)
+			if convErr != nil || id <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + HeaderName})
+				return
+			}
+			h, err = store.GetHousehold(ctx, userID, id)
+		} else {
+			var list []Household
+			list, err = store.ListHouseholds(ctx, userID)
+			if err == nil && len(list) == 0 {
+				err = ErrNotFound
+			}
+			if err == nil {
+				h = list[0]
+			}
+		}
+
+		if errors.Is(err, ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "household not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load household"})
+			return
+		}
+
+		c.Set(householdKey, h)
+		c.Next()
+	}
+}
+
+// Current is the household Resolve picked, or the zero Household on routes it
+// doesn't cover.
+func Current(c *gin.Context) Household {
+	h, _ := c.Get(householdKey)
+	household, _ := h.(Household)
+	return household
+}
+
+func ID(c *gin.Context) int {
+	return Current(c).ID
+}
+
+// Allow reports whether the caller's role in the current household covers
+// need, answering 403 when it doesn't. Outside Resolve nobody has a role, so
+// a handler mounted without it refuses everything.
+func Allow(c *gin.Context, need Role) bool {
+	if !Current(c).Role.Allows(need) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires the " + string(need) + " role in this household"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,211 @@
+package ingredients_test
+
+import (
+	"encoding/json"
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/ingredients"
+	"meal_prep/internal/listing"
+	"meal_prep/internal/recipes"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func createRecipe(cl *apitest.Client) int {
+	var r recipes.Recipe
+	cl.Create("/v1/recipes", map[string]any{"title": "Pancakes"}, &r)
+	return r.ID
+}
+
+func createIngredient(cl *apitest.Client, recipeID int, body map[string]any) ingredients.Ingredient {
+	var ing ingredients.Ingredient
+	cl.Create("/v1/recipes/"+strconv.Itoa(recipeID)+"/ingredients", body, &ing)
+	return ing
+}
+
+func floatOrNil(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
+
+func TestCreateIngredientParsesQuantity(t *testing.T) {
+	tests := []struct {
+		quantity string
+		min, max any
+	}{
+		{"2", 2.0, 2.0},
+		{"1 1/2", 1.5, 1.5},
+		{"½", 0.5, 0.5},
+		{"2-3", 2.0, 3.0},
+		{"to taste", nil, nil},
+	}
+
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := createRecipe(cl)
+
+		for _, tt := range tests {
+			ing := createIngredient(cl, recipeID, map[string]any{"name": "flour", "quantity": tt.quantity})
+			if floatOrNil(ing.QuantityValue) != tt.min || floatOrNil(ing.QuantityMax) != tt.max {
+				t.Errorf("%q: quantity_value %v, quantity_max %v, want %v and %v",
+					tt.quantity, floatOrNil(ing.QuantityValue), floatOrNil(ing.QuantityMax), tt.min, tt.max)
+			}
+
+			var got ingredients.Ingredient
+			cl.Do("GET", "/v1/ingredients/"+strconv.Itoa(ing.ID), nil, &got)
+			if floatOrNil(got.QuantityValue) != tt.min || floatOrNil(got.QuantityMax) != tt.max {
+				t.Errorf("%q stored as quantity_value %v, quantity_max %v", tt.quantity, floatOrNil(got.QuantityValue), floatOrNil(got.QuantityMax))
+			}
+		}
+
+		if code := cl.Do("POST", "/v1/recipes/"+strconv.Itoa(recipeID)+"/ingredients", map[string]any{"name": "eggs", "quantity": "3-2"}, nil); code != http.StatusBadRequest {
+			t.Errorf("backwards range: status %d, want 400", code)
+		}
+		if code := cl.Do("POST", "/v1/recipes/999/ingredients", map[string]any{"name": "eggs"}, nil); code != http.StatusNotFound {
+			t.Errorf("missing recipe: status %d, want 404", code)
+		}
+	})
+}
+
+func TestGetIngredientInUnit(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := createRecipe(cl)
+		flour := createIngredient(cl, recipeID, map[string]any{"name": "flour", "quantity": "1.5", "unit": "kg"})
+		salt := createIngredient(cl, recipeID, map[string]any{"name": "salt", "quantity": "a pinch"})
+		path := "/v1/ingredients/" + strconv.Itoa(flour.ID)
+
+		var got ingredients.Ingredient
+		if code := cl.Do("GET", path+"?unit=grams", nil, &got); code != http.StatusOK {
+			t.Fatalf("?unit=grams: status %d", code)
+		}
+		if got.Unit == nil || *got.Unit != "g" || floatOrNil(got.QuantityValue) != 1500.0 || got.Quantity == nil || *got.Quantity != "1500" {
+			t.Errorf("?unit=grams = %v %v", floatOrNil(got.QuantityValue), got.Unit)
+		}
+
+		if code := cl.Do("GET", path+"?unit=cup", nil, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("mass to volume: status %d, want 422", code)
+		}
+		if code := cl.Do("GET", path+"?unit=furlong", nil, nil); code != http.StatusBadRequest {
+			t.Errorf("unknown unit: status %d, want 400", code)
+		}
+		if code := cl.Do("GET", "/v1/ingredients/"+strconv.Itoa(salt.ID)+"?unit=g", nil, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("qualitative amount: status %d, want 422", code)
+		}
+	})
+}
+
+func TestUpdateIngredient(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		ing := createIngredient(cl, createRecipe(cl), map[string]any{"name": "milk", "quantity": "250", "unit": "ml"})
+		path := "/v1/ingredients/" + strconv.Itoa(ing.ID)
+
+		var got ingredients.Ingredient
+		if code := cl.Do("PATCH", path, map[string]any{"quantity": "1/2"}, &got); code != http.StatusOK {
+			t.Fatalf("patch: status %d", code)
+		}
+		if floatOrNil(got.QuantityValue) != 0.5 || got.Unit == nil || *got.Unit != "ml" || got.Name != "milk" {
+			t.Errorf("patch quantity = %+v", got)
+		}
+
+		// Clearing the quantity clears its parsed value too
+		var cleared ingredients.Ingredient
+		if code := cl.Do("PATCH", path, map[string]any{"quantity": nil, "unit": nil}, &cleared); code != http.StatusOK {
+			t.Fatalf("patch: status %d", code)
+		}
+		if cleared.Quantity != nil || cleared.QuantityValue != nil || cleared.QuantityMax != nil || cleared.Unit != nil {
+			t.Errorf("patch null = %+v", cleared)
+		}
+
+		if code := cl.Do("PATCH", path, map[string]any{"name": nil}, nil); code != http.StatusBadRequest {
+			t.Errorf("null name: status %d, want 400", code)
+		}
+	})
+}
+
+func TestDeleteIngredient(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := createRecipe(cl)
+		eggs := createIngredient(cl, recipeID, map[string]any{"name": "eggs"})
+		milk := createIngredient(cl, recipeID, map[string]any{"name": "milk"})
+
+		if code := cl.Do("DELETE", "/v1/ingredients/"+strconv.Itoa(eggs.ID), nil, nil); code != http.StatusNoContent {
+			t.Fatalf("delete: status %d", code)
+		}
+		if code := cl.Do("GET", "/v1/ingredients/"+strconv.Itoa(eggs.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("get after delete: status %d, want 404", code)
+		}
+
+		// Deleting the recipe takes its ingredients with it
+		cl.Do("DELETE", "/v1/recipes/"+strconv.Itoa(recipeID), nil, nil)
+		if code := cl.Do("GET", "/v1/ingredients/"+strconv.Itoa(milk.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("get after recipe delete: status %d, want 404", code)
+		}
+	})
+}
+
+func TestListIngredients(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := createRecipe(cl)
+		for _, name := range []string{"milk", "eggs", "flour", "buttermilk", "50%_sugar"} {
+			createIngredient(cl, recipeID, map[string]any{"name": name})
+		}
+		base := "/v1/recipes/" + strconv.Itoa(recipeID) + "/ingredients"
+
+		names := func(query string) ([]string, string) {
+			t.Helper()
+			w := cl.Request("GET", base+query, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("list%s: status %d", query, w.Code)
+			}
+			var list []ingredients.Ingredient
+			json.Unmarshal(w.Body.Bytes(), &list)
+			out := []string{}
+			for _, ing := range list {
+				out = append(out, ing.Name)
+			}
+			return out, w.Header().Get(listing.NextCursorHeader)
+		}
+
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"", []string{"milk", "eggs", "flour", "buttermilk", "50%_sugar"}},
+			{"?sort=name", []string{"50%_sugar", "buttermilk", "eggs", "flour", "milk"}},
+			{"?sort=-name&limit=2", []string{"milk", "flour"}},
+			{"?name=MILK", []string{"milk", "buttermilk"}},
+			// LIKE wildcards in ?name= match only themselves
+			{"?name=%25", []string{"50%_sugar"}},
+			{"?name=_", []string{"50%_sugar"}},
+			{"?name=sugar", []string{"50%_sugar"}},
+			{"?name=pepper", []string{}},
+		}
+		for _, tt := range tests {
+			if got, _ := names(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("list%s = %q, want %q", tt.query, got, tt.want)
+			}
+		}
+
+		got, cursor := names("?sort=name&limit=2")
+		for cursor != "" && len(got) < 10 {
+			var page []string
+			page, cursor = names("?limit=2&cursor=" + cursor)
+			got = append(got, page...)
+		}
+		if want := []string{"50%_sugar", "buttermilk", "eggs", "flour", "milk"}; !slices.Equal(got, want) {
+			t.Errorf("paging by name = %q, want %q", got, want)
+		}
+
+		_, cursor = names("?limit=2")
+		if code := cl.Do("GET", base+"?sort=name&cursor="+cursor, nil, nil); code != http.StatusBadRequest {
+			t.Errorf("cursor for another sort: status %d, want 400", code)
+		}
+	})
+}
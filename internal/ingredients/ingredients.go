@@ -1,34 +1,65 @@
 package ingredients
 
 import (
-	"database/sql"
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/listing"
+	"meal_prep/internal/patch"
+	"meal_prep/internal/quantity"
+	"meal_prep/internal/units"
+	"meal_prep/internal/words"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Ingredient struct {
-	ID       int     `json:"id"`
-	RecipeID int     `json:"recipe_id"`
-	Name     string  `json:"name"`
-	Quantity *string `json:"quantity,omitempty"`
-	Unit     *string `json:"unit,omitempty"`
+	ID            int      `json:"id"`
+	RecipeID      int      `json:"recipe_id"`
+	Name          string   `json:"name"`
+	Quantity      *string  `json:"quantity,omitempty"`
+	QuantityValue *float64 `json:"quantity_value,omitempty"` // absent for qualitative amounts
+	QuantityMax   *float64 `json:"quantity_max,omitempty"`   // differs from quantity_value for ranges
+	Unit          *string  `json:"unit,omitempty"`
+	Food          *string  `json:"food,omitempty"` // nutrition database food, when matching by name gets it wrong
 }
 
 type CreateIngredientRequest struct {
 	Name     string  `json:"name" binding:"required"`
 	Quantity *string `json:"quantity"`
 	Unit     *string `json:"unit"`
+	Food     *string `json:"food"`
 }
 
 type UpdateIngredientRequest struct {
-	Name     *string `json:"name"`     // optional
-	Quantity *string `json:"quantity"` // optional
-	Unit     *string `json:"unit"`     // optional
+	Name     patch.Field[string] `json:"name"`     // optional
+	Quantity patch.Field[string] `json:"quantity"` // optional, null clears
+	Unit     patch.Field[string] `json:"unit"`     // optional, null clears
+	Food     patch.Field[string] `json:"food"`     // optional, null goes back to matching by name
 }
 
-func ListIngredientsForRecipeHandler(c *gin.Context, db *sql.DB) {
+// parseQuantityColumns derives the numeric quantity columns from the text the
+// client sent. Blank and qualitative quantities have no numeric form.
+func parseQuantityColumns(text *string) (value, max *float64, err error) {
+	if text == nil || strings.TrimSpace(*text) == "" {
+		return nil, nil, nil
+	}
+
+	q, err := quantity.Parse(*text)
+	if err != nil || q.Qualitative {
+		return nil, nil, err
+	}
+
+	return &q.Min, &q.Max, nil
+}
+
+func ListIngredientsForRecipeHandler(c *gin.Context, store IngredientStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
 	recipeIDStr := c.Param("id")
 	recipeID, err := strconv.Atoi(recipeIDStr)
 
@@ -37,35 +68,26 @@ func ListIngredientsForRecipeHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	rows, err := db.Query(`
-		SELECT id, recipe_id, name, quantity, unit
-		FROM recipe_ingredients
-		WHERE recipe_id = ?
-		ORDER BY id ASC
-	`, recipeID)
+	q, err := parseIngredientQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	list, err := store.ListIngredients(c.Request.Context(), households.ID(c), recipeID, q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
 		return
 	}
 
-	defer rows.Close()
-
-	var list []Ingredient
-	for rows.Next() {
-		var ing Ingredient
-		if err := rows.Scan(&ing.ID, &ing.RecipeID, &ing.Name, &ing.Quantity, &ing.Unit); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "scan error"})
-			return
-		}
+	c.JSON(http.StatusOK, listing.Page(c, q.Query, list, IngredientSorts, ingredientID))
+}
 
-		list = append(list, ing)
+func CreateIngredientForRecipeHandler(c *gin.Context, store IngredientStore) {
+	if !households.Allow(c, households.Editor) {
+		return
 	}
 
-	c.JSON(http.StatusOK, list)
-}
-
-func CreateIngredientForRecipeHandler(c *gin.Context, db *sql.DB) {
 	recipeIDStr := c.Param("id")
 	recipeID, err := strconv.Atoi(recipeIDStr)
 	if err != nil || recipeID <= 0 {
@@ -73,54 +95,41 @@ func CreateIngredientForRecipeHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	// Ensure recipe exists (otherwise FK will fail with a vague error)
-	var exists int
-	if err := db.QueryRow(`SELECT 1 FROM recipes WHERE id = ?`, recipeID).Scan(&exists); err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate recipe"})
-		return
-	}
-
 	var req CreateIngredientRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
-
-	res, err := db.Exec(`
-		INSERT INTO recipe_ingredients (recipe_id, name, quantity, unit)
-		VALUES (?, ?, ?, ?)
-	`, recipeID, req.Name, req.Quantity, req.Unit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert ingredient"})
+	if !words.Plain(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot contain control characters"})
 		return
 	}
 
-	id64, err := res.LastInsertId()
+	ing := Ingredient{RecipeID: recipeID, Name: req.Name, Quantity: req.Quantity, Unit: req.Unit, Food: req.Food}
+	ing.QuantityValue, ing.QuantityMax, err = parseQuantityColumns(req.Quantity)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get new ingredient id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	id := int(id64)
 
-	var ing Ingredient
-	err = db.QueryRow(`
-		SELECT id, recipe_id, name, quantity, unit
-		FROM recipe_ingredients
-		WHERE id = ?
-	`, id).Scan(&ing.ID, &ing.RecipeID, &ing.Name, &ing.Quantity, &ing.Unit)
+	ing, err = store.CreateIngredient(c.Request.Context(), households.ID(c), ing)
+	if errors.Is(err, ErrRecipeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "created but failed to reload"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert ingredient"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, ing)
 }
 
-func GetIngredientHandler(c *gin.Context, db *sql.DB) {
+func GetIngredientHandler(c *gin.Context, store IngredientStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -128,13 +137,8 @@ func GetIngredientHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	var ing Ingredient
-	err = db.QueryRow(`
-		SELECT id, recipe_id, name, quantity, unit
-		FROM recipe_ingredients
-		WHERE id = ?
-	`, id).Scan(&ing.ID, &ing.RecipeID, &ing.Name, &ing.Quantity, &ing.Unit)
-	if err == sql.ErrNoRows {
+	ing, err := store.GetIngredient(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -143,10 +147,29 @@ func GetIngredientHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
+	// Optional conversion, e.g. ?unit=g
+	if unit, ok := c.GetQuery("unit"); ok {
+		if _, known := units.Lookup(unit); !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown unit"})
+			return
+		}
+
+		converted, err := ing.ConvertTo(unit)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		ing = converted
+	}
+
 	c.JSON(http.StatusOK, ing)
 }
 
-func UpdateIngredientHandler(c *gin.Context, db *sql.DB) {
+func UpdateIngredientHandler(c *gin.Context, store IngredientStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -155,19 +178,18 @@ func UpdateIngredientHandler(c *gin.Context, db *sql.DB) {
 	}
 
 	var req UpdateIngredientRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
 
 	// Load existing
-	var current Ingredient
-	err = db.QueryRow(`
-		SELECT id, recipe_id, name, quantity, unit
-		FROM recipe_ingredients
-		WHERE id = ?
-	`, id).Scan(&current.ID, &current.RecipeID, &current.Name, &current.Quantity, &current.Unit)
-	if err == sql.ErrNoRows {
+	current, err := store.GetIngredient(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -177,30 +199,44 @@ func UpdateIngredientHandler(c *gin.Context, db *sql.DB) {
 	}
 
 	// Apply patch
-	if req.Name != nil {
-		current.Name = *req.Name
+	if err := req.Name.Apply(&current.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be null"})
+		return
 	}
-	if req.Quantity != nil {
-		current.Quantity = req.Quantity
+	if !words.Plain(current.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot contain control characters"})
+		return
 	}
-	if req.Unit != nil {
-		current.Unit = req.Unit
+	req.Quantity.ApplyPtr(&current.Quantity)
+	req.Unit.ApplyPtr(&current.Unit)
+	req.Food.ApplyPtr(&current.Food)
+
+	if req.Quantity.Set {
+		current.QuantityValue, current.QuantityMax, err = parseQuantityColumns(current.Quantity)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	_, err = db.Exec(`
-		UPDATE recipe_ingredients
-		SET name = ?, quantity = ?, unit = ?
-		WHERE id = ?
-	`, current.Name, current.Quantity, current.Unit, id)
+	ing, err := store.UpdateIngredient(c.Request.Context(), households.ID(c), current)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
 		return
 	}
 
-	c.JSON(http.StatusOK, current)
+	c.JSON(http.StatusOK, ing)
 }
 
-func DeleteIngredientHandler(c *gin.Context, db *sql.DB) {
+func DeleteIngredientHandler(c *gin.Context, store IngredientStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -208,15 +244,13 @@ func DeleteIngredientHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	res, err := db.Exec(`DELETE FROM recipe_ingredients WHERE id = ?`, id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+	err = store.DeleteIngredient(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
 		return
 	}
 
@@ -0,0 +1,37 @@
+package ingredients
+
+import (
+	"meal_prep/internal/listing"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IngredientQuery is a page of GET /v1/recipes/:id/ingredients. Name keeps
+// the ingredients whose name contains it, ignoring case.
+type IngredientQuery struct {
+	listing.Query
+	Name string
+}
+
+// IngredientSorts are the orders of a recipe's ingredients; "position", the
+// order they were added in, is the default.
+var IngredientSorts = listing.Sorts[Ingredient]{
+	"position": func(ing Ingredient) any { return ing.ID },
+	"name":     func(ing Ingredient) any { return ing.Name },
+}
+
+// AllIngredients lists every ingredient of a recipe in the order they were
+// added, for callers that work on the whole recipe.
+var AllIngredients = IngredientQuery{Query: listing.Query{Sort: "position"}}
+
+func ingredientID(ing Ingredient) int { return ing.ID }
+
+func parseIngredientQuery(c *gin.Context) (IngredientQuery, error) {
+	q := IngredientQuery{Name: strings.TrimSpace(c.Query("name"))}
+
+	var err error
+	q.Query, err = listing.Parse(c, IngredientSorts, "position")
+
+	return q, err
+}
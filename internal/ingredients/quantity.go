@@ -0,0 +1,55 @@
+package ingredients
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"meal_prep/internal/units"
+	"strconv"
+)
+
+var ErrNoQuantity = errors.New("ingredient has no numeric quantity")
+
+// ConvertTo expresses the ingredient's amount in another unit. The original
+// text is replaced by the converted amount.
+func (ing Ingredient) ConvertTo(unit string) (Ingredient, error) {
+	if ing.QuantityValue == nil {
+		return ing, ErrNoQuantity
+	}
+
+	target, ok := units.Lookup(unit)
+	if !ok {
+		return ing, fmt.Errorf("%w: %q", units.ErrUnknownUnit, unit)
+	}
+
+	from := ""
+	if ing.Unit != nil {
+		from = *ing.Unit
+	}
+
+	low, err := units.Convert(*ing.QuantityValue, from, target.Name)
+	if err != nil {
+		return ing, err
+	}
+	high := low
+	if ing.QuantityMax != nil {
+		if high, err = units.Convert(*ing.QuantityMax, from, target.Name); err != nil {
+			return ing, err
+		}
+	}
+
+	text := formatAmount(low)
+	if high != low {
+		text += "-" + formatAmount(high)
+	}
+
+	ing.Quantity = &text
+	ing.QuantityValue = &low
+	ing.QuantityMax = &high
+	ing.Unit = &target.Name
+	return ing, nil
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,107 @@
+package ingredients
+
+import (
+	"math"
+	"meal_prep/internal/units"
+	"strconv"
+	"strings"
+)
+
+type ScaledIngredient struct {
+	Ingredient
+	Scaled bool   `json:"scaled"`
+	Note   string `json:"note,omitempty"` // why the quantity was left as written
+}
+
+// kitchenFractions are the fractions measuring cups and spoons come in.
+var kitchenFractions = []struct {
+	value float64
+	text  string
+}{
+	{0, ""}, {1.0 / 8, "1/8"}, {1.0 / 4, "1/4"}, {1.0 / 3, "1/3"}, {3.0 / 8, "3/8"},
+	{1.0 / 2, "1/2"}, {5.0 / 8, "5/8"}, {2.0 / 3, "2/3"}, {3.0 / 4, "3/4"}, {7.0 / 8, "7/8"}, {1, ""},
+}
+
+// FormatKitchen renders an amount the way a recipe would print it: "1 1/2"
+// rather than 1.5, whole numbers once there's ten or more of something, and
+// plain decimals for metric units where fractions read oddly. The output
+// always round-trips through quantity.Parse.
+func FormatKitchen(v float64, unit string) string {
+	if u, ok := units.Lookup(unit); ok && u.System == units.Metric {
+		if v >= 10 {
+			return strconv.FormatFloat(math.Round(v), 'f', -1, 64)
+		}
+		return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+	}
+
+	if v >= 10 {
+		return strconv.FormatFloat(math.Round(v), 'f', -1, 64)
+	}
+
+	whole, frac := math.Modf(v)
+	best := kitchenFractions[0]
+	for _, f := range kitchenFractions[1:] {
+		if math.Abs(frac-f.value) < math.Abs(frac-best.value) {
+			best = f
+		}
+	}
+	if best.value == 1 {
+		whole++
+	}
+
+	switch {
+	case whole == 0 && best.text == "":
+		// Too small for any measuring spoon; keep the precision instead
+		return formatAmount(v)
+	case whole == 0:
+		return best.text
+	case best.text == "":
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	default:
+		return strconv.FormatFloat(whole, 'f', -1, 64) + " " + best.text
+	}
+}
+
+// Scale multiplies the ingredient's amount by factor, rounds it to kitchen
+// fractions and promotes it to a more readable unit. Amounts without a
+// numeric form, such as "a pinch", are returned as written and flagged.
+func (ing Ingredient) Scale(factor float64) ScaledIngredient {
+	if ing.Quantity == nil || strings.TrimSpace(*ing.Quantity) == "" {
+		return ScaledIngredient{Ingredient: ing, Note: "no quantity"}
+	}
+	if ing.QuantityValue == nil {
+		return ScaledIngredient{Ingredient: ing, Note: "no numeric amount"}
+	}
+
+	lo, hi := *ing.QuantityValue, *ing.QuantityValue
+	if ing.QuantityMax != nil {
+		hi = *ing.QuantityMax
+	}
+	isRange := lo != hi
+
+	unit := ""
+	if ing.Unit != nil {
+		unit = *ing.Unit
+	}
+
+	// Promote on the upper end so both ends of a range share one unit
+	high, promoted := units.Promote(hi*factor, unit)
+	low := high
+	if isRange {
+		low, _ = units.Convert(lo*factor, unit, promoted)
+	}
+
+	text := FormatKitchen(low, promoted)
+	if isRange {
+		text += "-" + FormatKitchen(high, promoted)
+	}
+
+	ing.Quantity = &text
+	ing.QuantityValue = &low
+	ing.QuantityMax = &high
+	if ing.Unit != nil || promoted != unit {
+		ing.Unit = &promoted
+	}
+
+	return ScaledIngredient{Ingredient: ing, Scaled: true}
+}
@@ -0,0 +1,55 @@
+package ingredients
+
+import (
+	"math"
+	"meal_prep/internal/quantity"
+	"testing"
+)
+
+func TestFormatKitchen(t *testing.T) {
+	tests := []struct {
+		v    float64
+		unit string
+		want string
+	}{
+		{1.5, "cup", "1 1/2"},
+		{0.33, "cup", "1/3"},
+		{0.7, "tsp", "2/3"},
+		{2.97, "tbsp", "3"},
+		{0.02, "tsp", "0.02"},
+		{12.4, "cup", "12"},
+		{1.25, "", "1 1/4"},
+		{1.26, "g", "1.3"},
+		{437.6, "g", "438"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatKitchen(tt.v, tt.unit); got != tt.want {
+			t.Errorf("FormatKitchen(%v, %q) = %q, want %q", tt.v, tt.unit, got, tt.want)
+		}
+	}
+}
+
+// Whatever FormatKitchen prints has to read back as about the same amount,
+// since scaled quantities are stored as text like any other.
+func TestFormatKitchenRoundTrips(t *testing.T) {
+	for _, unit := range []string{"cup", "tsp", "", "g", "ml"} {
+		for i := 1; i <= 2000; i++ {
+			v := float64(i) / 100
+			text := FormatKitchen(v, unit)
+			q, err := quantity.Parse(text)
+			if err != nil {
+				t.Fatalf("FormatKitchen(%v, %q) = %q, which doesn't parse: %v", v, unit, text, err)
+			}
+
+			// Kitchen fractions are at most a sixteenth off, whole numbers half
+			tolerance := 1.0 / 16
+			if v >= 10 {
+				tolerance = 0.5
+			}
+			if q.Qualitative || q.Min != q.Max || math.Abs(q.Min-v) > tolerance+1e-9 {
+				t.Errorf("FormatKitchen(%v, %q) = %q, which reads back as %+v", v, unit, text, q)
+			}
+		}
+	}
+}
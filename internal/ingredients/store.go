@@ -0,0 +1,26 @@
+package ingredients
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound       = errors.New("ingredient not found")
+	ErrRecipeNotFound = errors.New("recipe not found")
+)
+
+// IngredientStore is the persistence behind the ingredient handlers. An
+// ingredient belongs to the household of its recipe, and every call is scoped
+// to a household. A missing ingredient, or another household's, is reported as
+// ErrNotFound; creating one for a recipe the household doesn't have fails
+// with ErrRecipeNotFound.
+type IngredientStore interface {
+	// ListIngredients returns the page q asks for, fetching q.Fetch() rows.
+	ListIngredients(ctx context.Context, householdID, recipeID int, q IngredientQuery) ([]Ingredient, error)
+	GetIngredient(ctx context.Context, householdID, id int) (Ingredient, error)
+	CreateIngredient(ctx context.Context, householdID int, ing Ingredient) (Ingredient, error)
+	// UpdateIngredient overwrites every column of ing.ID.
+	UpdateIngredient(ctx context.Context, householdID int, ing Ingredient) (Ingredient, error)
+	DeleteIngredient(ctx context.Context, householdID, id int) error
+}
@@ -0,0 +1,285 @@
+// Package listing implements the query parameters shared by the list
+// endpoints under /v1: ?limit= caps the page, ?sort= names the order, with a
+// leading "-" for descending, and ?cursor= continues where the previous page
+// stopped. Pages are keyset based: the cursor carries the sort value and id
+// of the last row served, so rows added or removed meanwhile don't shift the
+// pages after it. A response that has more rows sets X-Next-Cursor.
+package listing
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 500
+
+	NextCursorHeader = "X-Next-Cursor"
+)
+
+var ErrInvalid = errors.New("invalid list query")
+
+// Key extracts the value a list is sorted by from one of its rows. It must
+// return an int or a string.
+type Key[T any] func(T) any
+
+// Sorts are the orders a list offers, by the name ?sort= uses.
+type Sorts[T any] map[string]Key[T]
+
+// Query is the page a client asked for. A zero Limit lists everything, for
+// callers inside the server that need all rows.
+type Query struct {
+	Limit int
+	Sort  string
+	Desc  bool
+	After *Cursor // nil on the first page
+}
+
+// Fetch is how many rows a store should return for q: one past the limit, so
+// Page can tell whether there is a next page. 0 means no limit.
+func (q Query) Fetch() int {
+	if q.Limit == 0 {
+		return 0
+	}
+
+	return q.Limit + 1
+}
+
+// Cursor is the position after the last row of a page.
+type Cursor struct {
+	Value any // int64 or string
+	ID    int
+}
+
+type cursorJSON struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// sortParam is how a sort is spelled in ?sort= and inside cursors.
+func (q Query) sortParam() string {
+	if q.Desc {
+		return "-" + q.Sort
+	}
+
+	return q.Sort
+}
+
+func encodeCursor(q Query, c Cursor) string {
+	b, _ := json.Marshal(cursorJSON{Sort: q.sortParam(), Value: c.Value, ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (sort string, c Cursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", c, err
+	}
+
+	var raw cursorJSON
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return "", c, err
+	}
+	if raw.Sort == "" || raw.ID <= 0 {
+		return "", c, errors.New("incomplete cursor")
+	}
+
+	switch v := raw.Value.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return "", c, err
+		}
+		c.Value = n
+	case string:
+		c.Value = v
+	default:
+		return "", c, fmt.Errorf("unexpected cursor value %T", raw.Value)
+	}
+	c.ID = raw.ID
+
+	return raw.Sort, c, nil
+}
+
+// Parse reads ?limit=, ?sort= and ?cursor=. Without ?sort= the cursor's sort
+// is kept, or else defaultSort is used; a ?sort= other than the cursor's is
+// an error. Errors wrap ErrInvalid and are written for the client.
+func Parse[T any](c *gin.Context, sorts Sorts[T], defaultSort string) (Query, error) {
+	q := Query{Limit: DefaultLimit}
+
+	if v, ok := c.GetQuery("limit"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return q, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalid, MaxLimit)
+		}
+		q.Limit = n
+	}
+
+	sort, sortGiven := c.GetQuery("sort")
+	if v, ok := c.GetQuery("cursor"); ok {
+		cursorSort, cur, err := decodeCursor(v)
+		if err != nil {
+			return q, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+		}
+		if sortGiven && sort != cursorSort {
+			return q, fmt.Errorf("%w: cursor belongs to sort %q", ErrInvalid, cursorSort)
+		}
+		sort, q.After = cursorSort, &cur
+	} else if !sortGiven {
+		sort = defaultSort
+	}
+
+	q.Sort, q.Desc = strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	if _, ok := sorts[q.Sort]; !ok {
+		names := make([]string, 0, len(sorts))
+		for name := range sorts {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return q, fmt.Errorf("%w: sort must be one of %s, optionally prefixed with -", ErrInvalid, strings.Join(names, ", "))
+	}
+
+	// A cursor's value has to be of the kind its sort orders by, or the
+	// stores would compare strings with numbers
+	if q.After != nil {
+		var zero T
+		if _, isInt := normalize(sorts[q.Sort](zero)).(int64); isInt != isInt64(q.After.Value) {
+			return q, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+		}
+	}
+
+	return q, nil
+}
+
+func isInt64(v any) bool {
+	_, ok := v.(int64)
+	return ok
+}
+
+// Page cuts a list fetched with q.Fetch() rows down to the limit, setting
+// X-Next-Cursor if rows were left over.
+func Page[T any](c *gin.Context, q Query, list []T, sorts Sorts[T], id func(T) int) []T {
+	if q.Limit == 0 || len(list) <= q.Limit {
+		return list
+	}
+
+	list = list[:q.Limit]
+	last := list[len(list)-1]
+	c.Header(NextCursorHeader, encodeCursor(q, Cursor{Value: normalize(sorts[q.Sort](last)), ID: id(last)}))
+
+	return list
+}
+
+// normalize widens ints so keys compare with the int64 a cursor decodes to.
+func normalize(v any) any {
+	if n, ok := v.(int); ok {
+		return int64(n)
+	}
+
+	return v
+}
+
+func compareKeys(a, b any) int {
+	a, b = normalize(a), normalize(b)
+	if x, ok := a.(int64); ok {
+		y, _ := b.(int64)
+		return cmp.Compare(x, y)
+	}
+
+	x, _ := a.(string)
+	y, _ := b.(string)
+	return cmp.Compare(x, y)
+}
+
+// Apply orders an in-memory list the way a SQL store pages: by the sort key,
+// ties broken by id in the same direction, starting after the cursor and cut
+// to q.Fetch() rows.
+func Apply[T any](list []T, q Query, sorts Sorts[T], id func(T) int) []T {
+	key := sorts[q.Sort]
+	order := func(a, b T) int {
+		n := cmp.Or(compareKeys(key(a), key(b)), cmp.Compare(id(a), id(b)))
+		if q.Desc {
+			return -n
+		}
+		return n
+	}
+	slices.SortFunc(list, order)
+
+	if q.After != nil {
+		after := q.After
+		list = slices.DeleteFunc(list, func(v T) bool {
+			n := cmp.Or(compareKeys(key(v), after.Value), cmp.Compare(id(v), after.ID))
+			if q.Desc {
+				n = -n
+			}
+			return n <= 0
+		})
+	}
+
+	if n := q.Fetch(); n > 0 && len(list) > n {
+		list = list[:n]
+	}
+
+	return list
+}
+
+// Keyset is the SQL for one page: cond continues after the cursor, or is
+// TRUE on the first page, and order is the ORDER BY list. expr is the SQL for
+// the sort key and idCol the table's id column.
+func (q Query) Keyset(expr, idCol string) (cond string, args []any, order string) {
+	op, dir := ">", "ASC"
+	if q.Desc {
+		op, dir = "<", "DESC"
+	}
+	order = expr + " " + dir + ", " + idCol + " " + dir
+
+	if q.After == nil {
+		return "1 = 1", nil, order
+	}
+
+	cond = "(" + expr + " " + op + " ? OR (" + expr + " = ? AND " + idCol + " " + op + " ?))"
+	return cond, []any{q.After.Value, q.After.Value, q.After.ID}, order
+}
+
+// Int reads an optional integer filter that can't be negative.
+func Int(c *gin.Context, name string) (*int, error) {
+	v, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%w: %s must be a whole number", ErrInvalid, name)
+	}
+
+	return &n, nil
+}
+
+// Date reads an optional YYYY-MM-DD filter.
+func Date(c *gin.Context, name string) (*string, error) {
+	v, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+
+	if _, err := time.Parse(time.DateOnly, v); err != nil {
+		return nil, fmt.Errorf("%w: %s must be a date, YYYY-MM-DD", ErrInvalid, name)
+	}
+
+	return &v, nil
+}
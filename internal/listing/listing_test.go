@@ -0,0 +1,178 @@
+package listing
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type row struct {
+	id   int
+	name string
+}
+
+var rowSorts = Sorts[row]{
+	"created": func(r row) any { return r.id },
+	"name":    func(r row) any { return r.name },
+}
+
+func rowID(r row) int { return r.id }
+
+func testContext(rawQuery string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/?"+rawQuery, nil)
+	return c, w
+}
+
+func parse(rawQuery string) (Query, error) {
+	c, _ := testContext(rawQuery)
+	return Parse(c, rowSorts, "created")
+}
+
+func rawCursor(json string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(json))
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		q Query
+		c Cursor
+	}{
+		{Query{Sort: "created"}, Cursor{Value: int64(42), ID: 42}},
+		{Query{Sort: "name", Desc: true}, Cursor{Value: "Crêpes", ID: 7}},
+		{Query{Sort: "name"}, Cursor{Value: "", ID: 1}},
+	}
+
+	for _, tt := range tests {
+		sort, got, err := decodeCursor(encodeCursor(tt.q, tt.c))
+		if err != nil {
+			t.Errorf("%+v: %v", tt.c, err)
+			continue
+		}
+		if sort != tt.q.sortParam() || !reflect.DeepEqual(got, tt.c) {
+			t.Errorf("decoded %q %+v, want %q %+v", sort, got, tt.q.sortParam(), tt.c)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"not base64!",
+		rawCursor(`not json`),
+		rawCursor(`{"s":"name","v":1.5,"id":3}`),
+		rawCursor(`{"s":"name","v":true,"id":3}`),
+		rawCursor(`{"s":"name","v":null,"id":3}`),
+		rawCursor(`{"s":"name","v":"Soup"}`),
+		rawCursor(`{"v":"Soup","id":3}`),
+	}
+
+	for _, s := range tests {
+		if _, c, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) = %+v, want an error", s, c)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	byName := encodeCursor(Query{Sort: "name"}, Cursor{Value: "Soup", ID: 3})
+
+	q, err := parse("limit=2&sort=-name")
+	if err != nil || q.Limit != 2 || q.Sort != "name" || !q.Desc || q.After != nil {
+		t.Errorf("?limit=2&sort=-name = %+v, %v", q, err)
+	}
+
+	// Without ?sort=, the cursor's sort carries on
+	q, err = parse("cursor=" + byName)
+	if err != nil || q.Sort != "name" || q.Desc || q.After == nil || q.After.Value != "Soup" || q.After.ID != 3 {
+		t.Errorf("?cursor= = %+v, %v", q, err)
+	}
+	if q, err = parse(""); err != nil || q.Sort != "created" || q.Limit != DefaultLimit {
+		t.Errorf("defaults = %+v, %v", q, err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	byName := encodeCursor(Query{Sort: "name"}, Cursor{Value: "Soup", ID: 3})
+
+	tests := []string{
+		"limit=0",
+		"limit=" + strconv.Itoa(MaxLimit+1),
+		"limit=ten",
+		"sort=calories",
+		"sort=--name",
+		"cursor=garbage",
+		// A cursor is only good for the sort that made it
+		"sort=-name&cursor=" + byName,
+		"sort=created&cursor=" + byName,
+		// and its value must be of the kind that sort orders by
+		"cursor=" + rawCursor(`{"s":"created","v":"Soup","id":3}`),
+		"cursor=" + rawCursor(`{"s":"name","v":3,"id":3}`),
+		"cursor=" + rawCursor(`{"s":"calories","v":3,"id":3}`),
+	}
+
+	for _, raw := range tests {
+		if q, err := parse(raw); !errors.Is(err, ErrInvalid) {
+			t.Errorf("?%s = %+v, %v, want ErrInvalid", raw, q, err)
+		}
+	}
+}
+
+// Pages follow each other without gaps or repeats, whichever way they sort,
+// and the last one sets no cursor.
+func TestPaging(t *testing.T) {
+	all := []row{{1, "b"}, {2, "a"}, {3, "b"}, {4, "c"}, {5, "a"}}
+	tests := []struct {
+		sort string
+		want []int
+	}{
+		{"created", []int{1, 2, 3, 4, 5}},
+		{"name", []int{2, 5, 1, 3, 4}},
+		{"-name", []int{4, 3, 1, 5, 2}},
+	}
+
+	for _, tt := range tests {
+		var got []int
+		cursor := ""
+		for pages := 0; ; pages++ {
+			raw := "limit=2&sort=" + tt.sort
+			if cursor != "" {
+				raw += "&cursor=" + cursor
+			}
+			c, w := testContext(raw)
+			q, err := Parse(c, rowSorts, "created")
+			if err != nil {
+				t.Fatalf("%s: %v", raw, err)
+			}
+			for _, r := range Page(c, q, Apply(append([]row(nil), all...), q, rowSorts, rowID), rowSorts, rowID) {
+				got = append(got, r.id)
+			}
+			if cursor = w.Header().Get(NextCursorHeader); cursor == "" || pages > len(all) {
+				break
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("?sort=%s pages = %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	cond, args, order := Query{Sort: "name"}.Keyset("lower(name)", "id")
+	if cond != "1 = 1" || args != nil || order != "lower(name) ASC, id ASC" {
+		t.Errorf("first page = %q %v %q", cond, args, order)
+	}
+
+	q := Query{Sort: "name", Desc: true, After: &Cursor{Value: "soup", ID: 3}}
+	cond, args, order = q.Keyset("lower(name)", "id")
+	if cond != "(lower(name) < ? OR (lower(name) = ? AND id < ?))" || order != "lower(name) DESC, id DESC" ||
+		!reflect.DeepEqual(args, []any{"soup", "soup", 3}) {
+		t.Errorf("next page = %q %v %q", cond, args, order)
+	}
+}
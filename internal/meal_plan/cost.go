@@ -0,0 +1,130 @@
+package mealplan
+
+import (
+	"cmp"
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/nutrition"
+	"meal_prep/internal/prices"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PlanCost struct {
+	MealPlanID  int           `json:"meal_plan_id"`
+	From        string        `json:"from"`            // "YYYY-MM-DD"
+	To          string        `json:"to"`              // "YYYY-MM-DD"
+	Store       string        `json:"store,omitempty"` // only its prices were used
+	Days        []DayCost     `json:"days"`
+	Unscheduled *DayCost      `json:"unscheduled,omitempty"` // entries without a planned date
+	Total       prices.Money  `json:"total"`
+	Complete    bool          `json:"complete"` // false if an ingredient couldn't be costed
+	Missing     []prices.Line `json:"missing"`  // the ingredients that couldn't, once per recipe
+
+	// Budget is left out when the plan has none
+	*prices.Budget
+}
+
+// DayCost totals the entries planned for one day, each scaled to the
+// servings it is cooked for.
+type DayCost struct {
+	Date    string       `json:"date,omitempty"`
+	Total   prices.Money `json:"total"`
+	Entries []EntryCost  `json:"entries"`
+}
+
+type EntryCost struct {
+	EntryID  int          `json:"entry_id"`
+	RecipeID int          `json:"recipe_id"`
+	Factor   float64      `json:"factor"` // of the recipe as written
+	Cost     prices.Money `json:"cost"`
+	Complete bool         `json:"complete"`
+}
+
+// GetMealPlanCostHandler costs the plan's recipes, each with the prices in
+// effect on the day it is planned for, or on the plan's first day if it
+// isn't, at the cheapest store or only at ?store=. The total is checked
+// against the plan's budget.
+func GetMealPlanCostHandler(c *gin.Context, store MealPlanStore, priceStore prices.PriceStore, foods *nutrition.Database) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	mpID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || mpID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal plan id"})
+		return
+	}
+
+	mp, err := store.GetMealPlan(c.Request.Context(), households.ID(c), mpID)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "meal plan not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	planned, err := store.ListPlannedIngredients(c.Request.Context(), households.ID(c), mpID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
+		return
+	}
+
+	priceList, err := priceStore.ListPrices(c.Request.Context(), households.ID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query prices"})
+		return
+	}
+
+	pc := PlanCost{MealPlanID: mpID, Store: prices.Normalize(c.Query("store")), Days: []DayCost{}, Complete: true, Missing: []prices.Line{}}
+	pc.From = mp.StartDate
+	pc.To = mp.EndDate
+	catalog := prices.NewCatalog(priceList, pc.Store, foods)
+
+	missing := map[[2]int]bool{} // recipe and ingredient ids already reported
+	total := func(d planDay) DayCost {
+		// Unscheduled entries are priced as of the plan's first day
+		priceDay := cmp.Or(d.Date, pc.From)
+
+		day := DayCost{Date: d.Date, Entries: []EntryCost{}}
+		for _, rows := range d.Entries {
+			p := rows[0]
+			entry := EntryCost{EntryID: p.EntryID, RecipeID: p.RecipeID, Factor: p.Factor(), Complete: true}
+			for _, row := range rows {
+				line := catalog.Ingredient(row.Ingredient, priceDay)
+				if line.Cost != nil {
+					entry.Cost += *line.Cost * prices.Money(entry.Factor)
+					continue
+				}
+
+				entry.Complete, pc.Complete = false, false
+				if key := [2]int{p.RecipeID, line.IngredientID}; !missing[key] {
+					missing[key] = true
+					pc.Missing = append(pc.Missing, line)
+				}
+			}
+
+			day.Entries = append(day.Entries, entry)
+			day.Total += entry.Cost
+		}
+
+		pc.Total += day.Total
+		return day
+	}
+
+	days, unscheduled := byDay(mp, planned)
+	for _, d := range days {
+		pc.Days = append(pc.Days, total(d))
+	}
+	if unscheduled != nil {
+		day := total(*unscheduled)
+		pc.Unscheduled = &day
+	}
+	pc.Budget = prices.CheckBudget(pc.Total, mp.Budget)
+
+	c.JSON(http.StatusOK, pc)
+}
@@ -0,0 +1,444 @@
+package mealplan_test
+
+import (
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/diet"
+	"meal_prep/internal/listing"
+	mealplan "meal_prep/internal/meal_plan"
+	"meal_prep/internal/recipes"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// shortbread is a recipe for two with 100 g of butter, the only ingredient
+// the tests price and count.
+func shortbread(cl *apitest.Client) int {
+	var r recipes.Recipe
+	cl.Create("/v1/recipes", map[string]any{"title": "Shortbread", "servings": 2}, &r)
+	cl.Create("/v1/recipes/"+strconv.Itoa(r.ID)+"/ingredients", map[string]any{"name": "butter", "quantity": "100", "unit": "g"}, nil)
+	return r.ID
+}
+
+// week creates a plan for the first week of March, with body overriding
+// any of its fields.
+func week(cl *apitest.Client, body map[string]any) int {
+	plan := map[string]any{"name": "Week 10", "start_date": "2026-03-02", "end_date": "2026-03-08"}
+	for k, v := range body {
+		plan[k] = v
+	}
+	var mp mealplan.MealPlan
+	cl.Create("/v1/meal-plans", plan, &mp)
+	return mp.ID
+}
+
+func TestMealPlanCRUD(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		path := "/v1/meal-plans/" + strconv.Itoa(week(cl, nil))
+
+		var mp mealplan.MealPlan
+		if code := cl.Do("GET", path, nil, &mp); code != http.StatusOK {
+			t.Fatalf("get: status %d", code)
+		}
+		if mp.Name != "Week 10" || mp.StartDate != "2026-03-02" || mp.EndDate != "2026-03-08" {
+			t.Errorf("get = %+v", mp)
+		}
+
+		var patched mealplan.MealPlan
+		if code := cl.Do("PATCH", path, map[string]any{"name": "Spring week"}, &patched); code != http.StatusOK {
+			t.Fatalf("patch: status %d", code)
+		}
+		if patched.Name != "Spring week" || patched.StartDate != "2026-03-02" {
+			t.Errorf("patch = %+v", patched)
+		}
+
+		var entry mealplan.MealPlanRecipe
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": shortbread(cl)}, &entry)
+		if code := cl.Do("DELETE", path, nil, nil); code != http.StatusNoContent {
+			t.Fatalf("delete: status %d", code)
+		}
+		if code := cl.Do("GET", path, nil, nil); code != http.StatusNotFound {
+			t.Errorf("get after delete: status %d, want 404", code)
+		}
+		if code := cl.Do("GET", "/v1/plan-recipes/"+strconv.Itoa(entry.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("entry after plan delete: status %d, want 404", code)
+		}
+	})
+}
+
+func TestShoppingList(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := shortbread(cl)
+		cl.Create("/v1/recipes/"+strconv.Itoa(recipeID)+"/ingredients", map[string]any{"name": "Salt", "quantity": "a pinch"}, nil)
+		cl.Create("/v1/recipes/"+strconv.Itoa(recipeID)+"/ingredients", map[string]any{"name": "eggs", "quantity": "2-3"}, nil)
+		path := "/v1/meal-plans/" + strconv.Itoa(week(cl, nil))
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": recipeID, "planned_date": "2026-03-02"}, nil)
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": recipeID, "planned_date": "2026-03-05"}, nil)
+
+		var list mealplan.ShoppingList
+		if code := cl.Do("GET", path+"/shopping-list", nil, &list); code != http.StatusOK {
+			t.Fatalf("shopping list: status %d", code)
+		}
+		if len(list.Items) != 3 || list.Items[0].Name != "butter" || list.Items[1].Name != "eggs" || list.Items[2].Name != "salt" {
+			t.Fatalf("items = %+v", list.Items)
+		}
+		butter, eggs, salt := list.Items[0], list.Items[1], list.Items[2]
+		if len(butter.Amounts) != 1 || butter.Amounts[0].Quantity == nil || *butter.Amounts[0].Quantity != 200 ||
+			butter.Amounts[0].Unit == nil || *butter.Amounts[0].Unit != "g" {
+			t.Errorf("butter = %+v, want 200 g", butter.Amounts)
+		}
+		if !slices.Equal(butter.RecipeIDs, []int{recipeID}) {
+			t.Errorf("recipe_ids = %v", butter.RecipeIDs)
+		}
+		// Ranges count at their upper end so there's always enough
+		if len(eggs.Amounts) != 1 || eggs.Amounts[0].Quantity == nil || *eggs.Amounts[0].Quantity != 6 {
+			t.Errorf("eggs = %+v, want 6", eggs.Amounts)
+		}
+		// Amounts that can't be added up are kept as written, one per use
+		if len(salt.Amounts) != 2 {
+			t.Fatalf("salt = %+v, want a pinch twice", salt.Amounts)
+		}
+		for _, a := range salt.Amounts {
+			if a.Quantity != nil || a.Note == nil || *a.Note != "a pinch" {
+				t.Errorf("salt = %+v, want a pinch as a note", a)
+			}
+		}
+
+		var ranged mealplan.ShoppingList
+		if code := cl.Do("GET", path+"/shopping-list?from=2026-03-04", nil, &ranged); code != http.StatusOK {
+			t.Fatalf("?from: status %d", code)
+		}
+		if len(ranged.Items) != 3 || *ranged.Items[0].Amounts[0].Quantity != 100 {
+			t.Errorf("?from=2026-03-04 items = %+v, want the one shortbread", ranged.Items)
+		}
+		if code := cl.Do("GET", path+"/shopping-list?to=2026-04-01", nil, nil); code != http.StatusBadRequest {
+			t.Errorf("range outside the plan: status %d, want 400", code)
+		}
+	})
+}
+
+func TestMealPlanEntries(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := shortbread(cl)
+		path := "/v1/meal-plans/" + strconv.Itoa(week(cl, nil)) + "/recipes"
+
+		var entry mealplan.MealPlanRecipe
+		cl.Create(path, map[string]any{"recipe_id": recipeID, "meal_type": "snack", "planned_date": "2026-03-03", "servings": 6}, &entry)
+		if entry.RecipeID == nil || *entry.RecipeID != recipeID || entry.Servings == nil || *entry.Servings != 6 ||
+			entry.PlannedDate == nil || *entry.PlannedDate != "2026-03-03" {
+			t.Errorf("create = %+v", entry)
+		}
+		if code := cl.Do("POST", path, map[string]any{"recipe_id": recipeID, "servings": 0}, nil); code != http.StatusBadRequest {
+			t.Errorf("no servings: status %d, want 400", code)
+		}
+		if code := cl.Do("POST", path, map[string]any{"recipe_id": 999}, nil); code != http.StatusNotFound {
+			t.Errorf("missing recipe: status %d, want 404", code)
+		}
+
+		// Cooking for six doubles the shortbread's butter
+		var list mealplan.ShoppingList
+		cl.Do("GET", strings.TrimSuffix(path, "/recipes")+"/shopping-list", nil, &list)
+		if len(list.Items) != 1 || *list.Items[0].Amounts[0].Quantity != 300 {
+			t.Errorf("shopping list = %+v, want 300 g of butter", list.Items)
+		}
+
+		// Clearing servings goes back to the recipe's own
+		entryPath := "/v1/plan-recipes/" + strconv.Itoa(entry.ID)
+		var patched mealplan.MealPlanRecipe
+		if code := cl.Do("PATCH", entryPath, map[string]any{"servings": nil, "meal_type": "dinner"}, &patched); code != http.StatusOK {
+			t.Fatalf("patch: status %d", code)
+		}
+		if patched.Servings != nil || patched.MealType == nil || *patched.MealType != "dinner" || patched.PlannedDate == nil {
+			t.Errorf("patch = %+v", patched)
+		}
+
+		var entries []mealplan.MealPlanRecipe
+		cl.Do("GET", path, nil, &entries)
+		if len(entries) != 1 || entries[0].ID != entry.ID {
+			t.Errorf("list = %+v", entries)
+		}
+
+		// Deleting the recipe leaves the entry, without it
+		cl.Do("DELETE", "/v1/recipes/"+strconv.Itoa(recipeID), nil, nil)
+		var unlinked mealplan.MealPlanRecipe
+		if code := cl.Do("GET", entryPath, nil, &unlinked); code != http.StatusOK {
+			t.Fatalf("get after recipe delete: status %d", code)
+		}
+		if unlinked.RecipeID != nil {
+			t.Errorf("recipe_id = %d after the recipe was deleted", *unlinked.RecipeID)
+		}
+
+		if code := cl.Do("DELETE", entryPath, nil, nil); code != http.StatusNoContent {
+			t.Fatalf("delete: status %d", code)
+		}
+		if code := cl.Do("GET", entryPath, nil, nil); code != http.StatusNotFound {
+			t.Errorf("get after delete: status %d, want 404", code)
+		}
+	})
+}
+
+func TestListMealPlans(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		week(cl, map[string]any{"name": "March"})
+		week(cl, map[string]any{"name": "April", "start_date": "2026-04-06", "end_date": "2026-04-12"})
+		week(cl, map[string]any{"name": "May", "start_date": "2026-05-04", "end_date": "2026-05-10"})
+
+		names := func(query string) []string {
+			t.Helper()
+			var list []mealplan.MealPlan
+			if code := cl.Do("GET", "/v1/meal-plans"+query, nil, &list); code != http.StatusOK {
+				t.Fatalf("list%s: status %d", query, code)
+			}
+			out := []string{}
+			for _, mp := range list {
+				out = append(out, mp.Name)
+			}
+			return out
+		}
+
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"", []string{"March", "April", "May"}},
+			{"?sort=-start_date&limit=2", []string{"May", "April"}},
+			{"?sort=name", []string{"April", "March", "May"}},
+			// Plans overlapping the range, not only those inside it
+			{"?from=2026-03-05&to=2026-04-06", []string{"March", "April"}},
+			{"?from=2026-05-11", []string{}},
+		}
+		for _, tt := range tests {
+			if got := names(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("list%s = %q, want %q", tt.query, got, tt.want)
+			}
+		}
+
+		w := cl.Request("GET", "/v1/meal-plans?limit=1", nil)
+		cursor := w.Header().Get(listing.NextCursorHeader)
+		if got := names("?limit=1&cursor=" + cursor); !slices.Equal(got, []string{"April"}) {
+			t.Errorf("second page = %q, want April", got)
+		}
+		for _, query := range []string{"?from=2026-04-01&to=2026-03-01", "?from=March", "?sort=name&cursor=" + cursor} {
+			if code := cl.Do("GET", "/v1/meal-plans"+query, nil, nil); code != http.StatusBadRequest {
+				t.Errorf("list%s: status %d, want 400", query, code)
+			}
+		}
+	})
+}
+
+func TestMealPlanDates(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		path := "/v1/meal-plans/" + strconv.Itoa(week(cl, nil))
+
+		// A single day, and a leap year's worth, are fine
+		week(cl, map[string]any{"end_date": "2026-03-02"})
+		week(cl, map[string]any{"start_date": "2028-01-01", "end_date": "2028-12-31"})
+
+		tests := []struct {
+			name string
+			body map[string]any
+		}{
+			{"not a date", map[string]any{"start_date": "next monday"}},
+			{"a timestamp", map[string]any{"end_date": "2026-03-08T00:00:00Z"}},
+			{"no such day", map[string]any{"end_date": "2026-02-30"}},
+			{"backwards", map[string]any{"start_date": "2026-03-09"}},
+			{"over a year", map[string]any{"end_date": "2027-03-03"}},
+			{"a mistyped year", map[string]any{"end_date": "20260-03-08"}},
+		}
+		for _, tt := range tests {
+			plan := map[string]any{"name": "Week 10", "start_date": "2026-03-02", "end_date": "2026-03-08"}
+			for k, v := range tt.body {
+				plan[k] = v
+			}
+			if code := cl.Do("POST", "/v1/meal-plans", plan, nil); code != http.StatusBadRequest {
+				t.Errorf("create, %s: status %d, want 400", tt.name, code)
+			}
+			if code := cl.Do("PATCH", path, tt.body, nil); code != http.StatusBadRequest {
+				t.Errorf("patch, %s: status %d, want 400", tt.name, code)
+			}
+		}
+
+		// Moving both ends at once is checked as a whole
+		var moved mealplan.MealPlan
+		if code := cl.Do("PATCH", path, map[string]any{"start_date": "2026-04-06", "end_date": "2026-04-12"}, &moved); code != http.StatusOK {
+			t.Fatalf("move: status %d", code)
+		}
+		if moved.StartDate != "2026-04-06" || moved.EndDate != "2026-04-12" {
+			t.Errorf("moved = %+v", moved)
+		}
+
+		if code := cl.Do("POST", path+"/recipes", map[string]any{"recipe_id": shortbread(cl), "planned_date": "Tuesday"}, nil); code != http.StatusBadRequest {
+			t.Errorf("entry on no date: status %d, want 400", code)
+		}
+	})
+}
+
+func TestMealPlanNutrition(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := shortbread(cl)
+		path := "/v1/meal-plans/" + strconv.Itoa(week(cl, nil))
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": recipeID, "planned_date": "2026-03-02", "servings": 4}, nil)
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": recipeID}, nil)
+
+		var pn mealplan.PlanNutrition
+		if code := cl.Do("GET", path+"/nutrition", nil, &pn); code != http.StatusOK {
+			t.Fatalf("nutrition: status %d", code)
+		}
+		if pn.From != "2026-03-02" || pn.To != "2026-03-08" || len(pn.Days) != 7 || pn.Days[0].Date != "2026-03-02" || pn.Days[6].Date != "2026-03-08" {
+			t.Fatalf("days = %+v, want the plan's seven", pn.Days)
+		}
+		// Butter has 717 kcal per 100 g, and four servings double the recipe
+		if got := pn.Days[0].Total.Calories; got != 1434 {
+			t.Errorf("first day calories = %v, want 1434", got)
+		}
+		if len(pn.Days[1].Entries) != 0 || pn.Days[1].Total.Calories != 0 {
+			t.Errorf("second day = %+v, want nothing planned", pn.Days[1])
+		}
+		if pn.Unscheduled == nil || pn.Unscheduled.Date != "" || pn.Unscheduled.Total.Calories != 717 {
+			t.Errorf("unscheduled = %+v, want 717 calories", pn.Unscheduled)
+		}
+		if !pn.Complete || pn.Total.Calories != 2151 || len(pn.Missing) != 0 {
+			t.Errorf("total = %v, complete %v, missing %+v", pn.Total.Calories, pn.Complete, pn.Missing)
+		}
+
+		// An ingredient the food database doesn't know is reported once
+		cl.Create("/v1/recipes/"+strconv.Itoa(recipeID)+"/ingredients", map[string]any{"name": "moon dust", "quantity": "1", "unit": "g"}, nil)
+		pn = mealplan.PlanNutrition{}
+		cl.Do("GET", path+"/nutrition", nil, &pn)
+		if pn.Complete || len(pn.Missing) != 1 || pn.Missing[0].Name != "moon dust" || pn.Total.Calories != 2151 {
+			t.Errorf("with an unknown food: complete %v, missing %+v, total %v", pn.Complete, pn.Missing, pn.Total.Calories)
+		}
+	})
+}
+
+func TestMealPlanRestrictions(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := shortbread(cl)
+
+		var mp mealplan.MealPlan
+		cl.Create("/v1/meal-plans", map[string]any{
+			"name": "Week 10", "start_date": "2026-03-02", "end_date": "2026-03-08", "restrictions": []string{"Vegan", " NUTS ", "nuts"},
+		}, &mp)
+		if !slices.Equal(mp.Restrictions, []string{"nuts", "vegan"}) {
+			t.Errorf("restrictions = %q, want [nuts vegan]", mp.Restrictions)
+		}
+		planPath := "/v1/meal-plans/" + strconv.Itoa(mp.ID)
+		path := planPath + "/recipes"
+
+		var refused struct {
+			Error      string           `json:"error"`
+			Violations []diet.Violation `json:"violations"`
+		}
+		if code := cl.Do("POST", path, map[string]any{"recipe_id": recipeID}, &refused); code != http.StatusUnprocessableEntity {
+			t.Fatalf("breaking a restriction: status %d, want 422", code)
+		}
+		if len(refused.Violations) != 1 || refused.Violations[0].Restriction != "vegan" ||
+			!slices.Equal(refused.Violations[0].Ingredients, []string{"butter"}) {
+			t.Errorf("violations = %+v", refused.Violations)
+		}
+
+		var entry mealplan.MealPlanRecipe
+		cl.Create(path, map[string]any{"recipe_id": recipeID, "override": true}, &entry)
+		if len(entry.Violations) != 1 || entry.Violations[0].Restriction != "vegan" {
+			t.Errorf("overridden entry has violations %+v, want the one", entry.Violations)
+		}
+
+		// Swapping in another recipe is checked the same way
+		var sorbet recipes.Recipe
+		cl.Create("/v1/recipes", map[string]any{"title": "Sorbet"}, &sorbet)
+		cl.Create("/v1/recipes/"+strconv.Itoa(sorbet.ID)+"/ingredients", map[string]any{"name": "lemons"}, nil)
+		var swapped mealplan.MealPlanRecipe
+		if code := cl.Do("PATCH", "/v1/plan-recipes/"+strconv.Itoa(entry.ID), map[string]any{"recipe_id": sorbet.ID}, &swapped); code != http.StatusOK {
+			t.Fatalf("swap: status %d", code)
+		}
+		if len(swapped.Violations) != 0 {
+			t.Errorf("sorbet violations = %+v, want none", swapped.Violations)
+		}
+		if code := cl.Do("PATCH", "/v1/plan-recipes/"+strconv.Itoa(entry.ID), map[string]any{"recipe_id": recipeID}, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("swapping back: status %d, want 422", code)
+		}
+
+		var patched mealplan.MealPlan
+		if code := cl.Do("PATCH", planPath, map[string]any{"restrictions": []string{"DAIRY"}}, &patched); code != http.StatusOK || !slices.Equal(patched.Restrictions, []string{"dairy"}) {
+			t.Errorf("patch restrictions: status %d, %q", code, patched.Restrictions)
+		}
+		keto := map[string]any{"name": "Keto week", "start_date": "2026-03-02", "end_date": "2026-03-08", "restrictions": []string{"keto"}}
+		if code := cl.Do("POST", "/v1/meal-plans", keto, nil); code != http.StatusBadRequest {
+			t.Errorf("unknown restriction on create: status %d, want 400", code)
+		}
+		if code := cl.Do("PATCH", planPath, map[string]any{"restrictions": []string{"keto"}}, nil); code != http.StatusBadRequest {
+			t.Errorf("unknown restriction on patch: status %d, want 400", code)
+		}
+		if code := cl.Do("PATCH", planPath, map[string]any{"restrictions": nil}, &patched); code != http.StatusOK || len(patched.Restrictions) != 0 {
+			t.Errorf("clearing restrictions: status %d, %q", code, patched.Restrictions)
+		}
+	})
+}
+
+func TestMealPlanCost(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		recipeID := shortbread(cl)
+		path := "/v1/meal-plans/" + strconv.Itoa(week(cl, map[string]any{"budget": 1.5}))
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": recipeID, "planned_date": "2026-03-02", "servings": 4}, nil)
+
+		var pc mealplan.PlanCost
+		cl.Do("GET", path+"/cost", nil, &pc)
+		if pc.Complete || len(pc.Missing) != 1 || pc.Missing[0].Name != "butter" {
+			t.Errorf("without prices: complete %v, missing %+v", pc.Complete, pc.Missing)
+		}
+
+		cl.Create("/v1/prices", map[string]any{
+			"item": "butter", "store": "corner shop", "price": 2.5, "quantity": 250, "unit": "g", "effective_from": "2026-01-01",
+		}, nil)
+
+		pc = mealplan.PlanCost{}
+		if code := cl.Do("GET", path+"/cost", nil, &pc); code != http.StatusOK {
+			t.Fatalf("cost: status %d", code)
+		}
+		if !pc.Complete || pc.Total != 2 || len(pc.Days) != 7 || pc.Days[0].Total != 2 || pc.Unscheduled != nil {
+			t.Errorf("cost = %+v, want 2.00 on the first day", pc)
+		}
+		if pc.Budget == nil || !pc.OverBudget || pc.Overrun == nil || *pc.Overrun != 0.5 {
+			t.Errorf("budget = %+v, want 0.50 over", pc.Budget)
+		}
+
+		// Unscheduled entries and ones planned past the plan get days of their own
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": recipeID}, nil)
+		cl.Create(path+"/recipes", map[string]any{"recipe_id": recipeID, "planned_date": "2026-03-10"}, nil)
+		pc = mealplan.PlanCost{}
+		cl.Do("GET", path+"/cost", nil, &pc)
+		if len(pc.Days) != 8 || pc.Days[7].Date != "2026-03-10" || pc.Days[7].Total != 1 {
+			t.Errorf("days = %+v, want 2026-03-10 last at 1.00", pc.Days)
+		}
+		if pc.Unscheduled == nil || pc.Unscheduled.Total != 1 || pc.Total != 4 {
+			t.Errorf("unscheduled = %+v, total %v", pc.Unscheduled, pc.Total)
+		}
+
+		if code := cl.Do("PATCH", path, map[string]any{"budget": nil}, nil); code != http.StatusOK {
+			t.Fatalf("patch: status %d", code)
+		}
+		pc = mealplan.PlanCost{}
+		cl.Do("GET", path+"/cost", nil, &pc)
+		if pc.Budget != nil {
+			t.Errorf("budget = %+v after clearing it", pc.Budget)
+		}
+
+		if code := cl.Do("PATCH", path, map[string]any{"budget": -1}, nil); code != http.StatusBadRequest {
+			t.Errorf("negative budget on patch: status %d, want 400", code)
+		}
+		negative := map[string]any{"name": "Week 10", "start_date": "2026-03-02", "end_date": "2026-03-08", "budget": -1}
+		if code := cl.Do("POST", "/v1/meal-plans", negative, nil); code != http.StatusBadRequest {
+			t.Errorf("negative budget on create: status %d, want 400", code)
+		}
+	})
+}
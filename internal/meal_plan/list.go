@@ -0,0 +1,47 @@
+package mealplan
+
+import (
+	"fmt"
+	"meal_prep/internal/listing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MealPlanQuery is a page of GET /v1/meal-plans. From and To keep the plans
+// that overlap the range; either end can be left open.
+type MealPlanQuery struct {
+	listing.Query
+	From *string // YYYY-MM-DD, inclusive
+	To   *string // YYYY-MM-DD, inclusive
+}
+
+// MealPlanSorts are the orders of GET /v1/meal-plans; "start_date" is the
+// default.
+var MealPlanSorts = listing.Sorts[MealPlan]{
+	"start_date": func(mp MealPlan) any { return mp.StartDate },
+	"name":       func(mp MealPlan) any { return mp.Name },
+	"created":    func(mp MealPlan) any { return mp.ID },
+}
+
+func mealPlanID(mp MealPlan) int { return mp.ID }
+
+func parseMealPlanQuery(c *gin.Context) (MealPlanQuery, error) {
+	var (
+		q   MealPlanQuery
+		err error
+	)
+	if q.Query, err = listing.Parse(c, MealPlanSorts, "start_date"); err != nil {
+		return q, err
+	}
+	if q.From, err = listing.Date(c, "from"); err != nil {
+		return q, err
+	}
+	if q.To, err = listing.Date(c, "to"); err != nil {
+		return q, err
+	}
+	if q.From != nil && q.To != nil && *q.From > *q.To {
+		return q, fmt.Errorf("%w: from date is after to date", listing.ErrInvalid)
+	}
+
+	return q, nil
+}
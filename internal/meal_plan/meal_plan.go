@@ -1,7 +1,13 @@
 package mealplan
 
 import (
-	"database/sql"
+	"errors"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/diet"
+	"meal_prep/internal/households"
+	"meal_prep/internal/listing"
+	"meal_prep/internal/patch"
+	"meal_prep/internal/recipes"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,24 +15,42 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// MaxPlanDays is the longest a plan can run, so a mistyped year can't ask the
+// per-day reports for thousands of days.
+const MaxPlanDays = 366
+
 type MealPlan struct {
-	ID        int        `json:"id"`
-	Name      string     `json:"name"`
-	StartDate string     `json:"start_date"` // "YYYY-MM-DD"
-	EndDate   string     `json:"end_date"`   // "YYYY-MM-DD"
-	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ID          int        `json:"id"`
+	HouseholdID int        `json:"household_id"`
+	OwnerID     *int       `json:"-"` // the author; nil once their account is gone
+	Name        string     `json:"name"`
+	StartDate   string     `json:"start_date"` // "YYYY-MM-DD"
+	EndDate     string     `json:"end_date"`   // "YYYY-MM-DD"
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+
+	// Restrictions are allergens the plan's recipes must be free of and
+	// diets they must suit, see diet.ParseRestrictions
+	Restrictions []string `json:"restrictions"`
+
+	// Budget caps what the plan's recipes should cost, see
+	// GetMealPlanCostHandler
+	Budget *float64 `json:"budget,omitempty"`
 }
 
 type CreateMealPlanRequest struct {
-	Name      string `json:"name" binding:"required"`
-	StartDate string `json:"start_date" binding:"required"`
-	EndDate   string `json:"end_date" binding:"required"`
+	Name         string   `json:"name" binding:"required"`
+	StartDate    string   `json:"start_date" binding:"required"`
+	EndDate      string   `json:"end_date" binding:"required"`
+	Restrictions []string `json:"restrictions"`
+	Budget       *float64 `json:"budget"`
 }
 
 type UpdateMealPlanRequest struct {
-	Name      *string `json:"name"`
-	StartDate *string `json:"start_date"`
-	EndDate   *string `json:"end_date"`
+	Name         patch.Field[string]   `json:"name"`
+	StartDate    patch.Field[string]   `json:"start_date"`
+	EndDate      patch.Field[string]   `json:"end_date"`
+	Restrictions patch.Field[[]string] `json:"restrictions"` // null clears
+	Budget       patch.Field[float64]  `json:"budget"`       // null clears
 }
 
 type MealPlanRecipe struct {
@@ -35,83 +59,131 @@ type MealPlanRecipe struct {
 	RecipeID    *int    `json:"recipe_id,omitempty"`
 	MealType    *string `json:"meal_type,omitempty"`    // breakfast/lunch/dinner/snack
 	PlannedDate *string `json:"planned_date,omitempty"` // "YYYY-MM-DD"
+	Servings    *int    `json:"servings,omitempty"`     // defaults to the recipe's own servings
+
+	// Violations are the plan's restrictions the recipe breaks, only in the
+	// response to a write that overrode them
+	Violations []diet.Violation `json:"violations,omitempty"`
 }
 
 type CreateMealPlanRecipeRequest struct {
 	RecipeID    int     `json:"recipe_id" binding:"required"`
 	MealType    *string `json:"meal_type"`
 	PlannedDate *string `json:"planned_date"`
+	Servings    *int    `json:"servings" binding:"omitempty,gt=0"`
+	Override    bool    `json:"override"` // plan the recipe even if it breaks the plan's restrictions
 }
 
 type UpdateMealPlanRecipeRequest struct {
-	RecipeID    *int    `json:"recipe_id"`
-	MealType    *string `json:"meal_type"`
-	PlannedDate *string `json:"planned_date"`
+	RecipeID    patch.Field[int]    `json:"recipe_id"`    // null clears
+	MealType    patch.Field[string] `json:"meal_type"`    // null clears
+	PlannedDate patch.Field[string] `json:"planned_date"` // null clears
+	Servings    patch.Field[int]    `json:"servings"`     // null falls back to the recipe's servings
+	Override    bool                `json:"override"`     // as when creating; not stored
 }
 
-func ListMealPlansHandler(c *gin.Context, db *sql.DB) {
-	rows, err := db.Query(`
-		SELECT id, name, start_date, end_date, created_at
-		FROM meal_plans
-		ORDER BY start_date ASC, id ASC
-	`)
+// checkDates reports a message for the client if the plan's dates aren't
+// YYYY-MM-DD, run backwards or span more than MaxPlanDays.
+func checkDates(mp MealPlan) (string, bool) {
+	start, err := time.Parse(time.DateOnly, mp.StartDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query meal plans"})
+		return "start_date must be a date, YYYY-MM-DD", false
+	}
+	end, err := time.Parse(time.DateOnly, mp.EndDate)
+	if err != nil {
+		return "end_date must be a date, YYYY-MM-DD", false
+	}
+
+	switch days := int(end.Sub(start).Hours()/24) + 1; {
+	case days < 1:
+		return "end_date cannot be before start_date", false
+	case days > MaxPlanDays:
+		return "a meal plan can span at most " + strconv.Itoa(MaxPlanDays) + " days", false
+	}
+
+	return "", true
+}
+
+// checkPlannedDate reports whether an entry's date, if any, is YYYY-MM-DD.
+func checkPlannedDate(date *string) bool {
+	if date == nil {
+		return true
+	}
+
+	_, err := time.Parse(time.DateOnly, *date)
+	return err == nil
+}
+
+func ListMealPlansHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Viewer) {
 		return
 	}
-	defer rows.Close()
 
-	var list []MealPlan
-	for rows.Next() {
-		var mp MealPlan
-		if err := rows.Scan(&mp.ID, &mp.Name, &mp.StartDate, &mp.EndDate, &mp.CreatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "scan error"})
-			return
-		}
-		list = append(list, mp)
+	q, err := parseMealPlanQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, list)
+	list, err := store.ListMealPlans(c.Request.Context(), households.ID(c), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query meal plans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listing.Page(c, q.Query, list, MealPlanSorts, mealPlanID))
 }
 
-func CreateMealPlanHandler(c *gin.Context, db *sql.DB) {
+func CreateMealPlanHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	var req CreateMealPlanRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
 
-	res, err := db.Exec(`
-		INSERT INTO meal_plans (name, start_date, end_date)
-		VALUES (?, ?, ?)
-	`, req.Name, req.StartDate, req.EndDate)
+	restrictions, err := diet.ParseRestrictions(req.Restrictions)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert meal plan"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	id64, err := res.LastInsertId()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get id"})
+	owner := auth.UserID(c)
+	mp := MealPlan{
+		HouseholdID:  households.ID(c),
+		OwnerID:      &owner,
+		Name:         req.Name,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		Restrictions: restrictions,
+		Budget:       req.Budget,
+	}
+	if msg, ok := checkDates(mp); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if mp.Budget != nil && *mp.Budget < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "budget cannot be negative"})
 		return
 	}
-	id := int(id64)
 
-	var mp MealPlan
-	err = db.QueryRow(`
-		SELECT id, name, start_date, end_date, created_at
-		FROM meal_plans
-		WHERE id = ?
-	`, id).Scan(&mp.ID, &mp.Name, &mp.StartDate, &mp.EndDate, &mp.CreatedAt)
+	mp, err = store.CreateMealPlan(c.Request.Context(), mp)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "created but failed to reload"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert meal plan"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, mp)
 }
 
-func GetMealPlanHandler(c *gin.Context, db *sql.DB) {
+func GetMealPlanHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -119,13 +191,8 @@ func GetMealPlanHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	var mp MealPlan
-	err = db.QueryRow(`
-		SELECT id, name, start_date, end_date, created_at
-		FROM meal_plans
-		WHERE id = ?
-	`, id).Scan(&mp.ID, &mp.Name, &mp.StartDate, &mp.EndDate, &mp.CreatedAt)
-	if err == sql.ErrNoRows {
+	mp, err := store.GetMealPlan(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -137,7 +204,11 @@ func GetMealPlanHandler(c *gin.Context, db *sql.DB) {
 	c.JSON(http.StatusOK, mp)
 }
 
-func UpdateMealPlanHandler(c *gin.Context, db *sql.DB) {
+func UpdateMealPlanHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -146,19 +217,18 @@ func UpdateMealPlanHandler(c *gin.Context, db *sql.DB) {
 	}
 
 	var req UpdateMealPlanRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
 
 	// Load existing
-	var mp MealPlan
-	err = db.QueryRow(`
-		SELECT id, name, start_date, end_date, created_at
-		FROM meal_plans
-		WHERE id = ?
-	`, id).Scan(&mp.ID, &mp.Name, &mp.StartDate, &mp.EndDate, &mp.CreatedAt)
-	if err == sql.ErrNoRows {
+	mp, err := store.GetMealPlan(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -168,21 +238,42 @@ func UpdateMealPlanHandler(c *gin.Context, db *sql.DB) {
 	}
 
 	// Apply patch
-	if req.Name != nil {
-		mp.Name = *req.Name
+	for _, f := range []struct {
+		name  string
+		field patch.Field[string]
+		dst   *string
+	}{
+		{"name", req.Name, &mp.Name},
+		{"start_date", req.StartDate, &mp.StartDate},
+		{"end_date", req.EndDate, &mp.EndDate},
+	} {
+		if err := f.field.Apply(f.dst); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f.name + " cannot be null"})
+			return
+		}
+	}
+	if msg, ok := checkDates(mp); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
 	}
-	if req.StartDate != nil {
-		mp.StartDate = *req.StartDate
+	if req.Restrictions.Set {
+		mp.Restrictions, err = diet.ParseRestrictions(req.Restrictions.Value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
-	if req.EndDate != nil {
-		mp.EndDate = *req.EndDate
+	req.Budget.ApplyPtr(&mp.Budget)
+	if mp.Budget != nil && *mp.Budget < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "budget cannot be negative"})
+		return
 	}
 
-	_, err = db.Exec(`
-		UPDATE meal_plans
-		SET name = ?, start_date = ?, end_date = ?
-		WHERE id = ?
-	`, mp.Name, mp.StartDate, mp.EndDate, id)
+	mp, err = store.UpdateMealPlan(c.Request.Context(), mp)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
 		return
@@ -191,7 +282,11 @@ func UpdateMealPlanHandler(c *gin.Context, db *sql.DB) {
 	c.JSON(http.StatusOK, mp)
 }
 
-func DeleteMealPlanHandler(c *gin.Context, db *sql.DB) {
+func DeleteMealPlanHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -199,22 +294,25 @@ func DeleteMealPlanHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	res, err := db.Exec(`DELETE FROM meal_plans WHERE id = ?`, id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+	// The plan's entries go with it
+	err = store.DeleteMealPlan(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
 		return
 	}
 
-	// Cascade will remove meal_plan_recipes automatically
 	c.Status(http.StatusNoContent)
 }
 
-func ListMealPlanRecipesHandler(c *gin.Context, db *sql.DB) {
+func ListMealPlanRecipesHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
 	mpIDStr := c.Param("id")
 	mpID, err := strconv.Atoi(mpIDStr)
 	if err != nil || mpID <= 0 {
@@ -222,32 +320,20 @@ func ListMealPlanRecipesHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	rows, err := db.Query(`
-		SELECT id, meal_plan_id, recipe_id, meal_type, planned_date
-		FROM meal_plan_recipes
-		WHERE meal_plan_id = ?
-		ORDER BY planned_date ASC, id ASC
-	`, mpID)
+	list, err := store.ListMealPlanRecipes(c.Request.Context(), households.ID(c), mpID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query meal plan recipes"})
 		return
 	}
-	defer rows.Close()
-
-	var list []MealPlanRecipe
-	for rows.Next() {
-		var mpr MealPlanRecipe
-		if err := rows.Scan(&mpr.ID, &mpr.MealPlanID, &mpr.RecipeID, &mpr.MealType, &mpr.PlannedDate); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "scan error"})
-			return
-		}
-		list = append(list, mpr)
-	}
 
 	c.JSON(http.StatusOK, list)
 }
 
-func CreateMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
+func CreateMealPlanRecipeHandler(c *gin.Context, store MealPlanStore, recipeStore recipes.RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	mpIDStr := c.Param("id")
 	mpID, err := strconv.Atoi(mpIDStr)
 	if err != nil || mpID <= 0 {
@@ -255,64 +341,88 @@ func CreateMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	// Ensure meal plan exists
-	var exists int
-	if err := db.QueryRow(`SELECT 1 FROM meal_plans WHERE id = ?`, mpID).Scan(&exists); err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "meal plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate meal plan"})
-		return
-	}
-
 	var req CreateMealPlanRecipeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
+	if !checkPlannedDate(req.PlannedDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "planned_date must be a date, YYYY-MM-DD"})
+		return
+	}
 
-	// Ensure recipe exists (optional but nicer error than FK)
-	if err := db.QueryRow(`SELECT 1 FROM recipes WHERE id = ?`, req.RecipeID).Scan(&exists); err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate recipe"})
+	violations, ok := checkRestrictions(c, store, recipeStore, mpID, req.RecipeID, req.Override)
+	if !ok {
 		return
 	}
 
-	res, err := db.Exec(`
-		INSERT INTO meal_plan_recipes (meal_plan_id, recipe_id, meal_type, planned_date)
-		VALUES (?, ?, ?, ?)
-	`, mpID, req.RecipeID, req.MealType, req.PlannedDate)
-	if err != nil {
+	mpr, err := store.CreateMealPlanRecipe(c.Request.Context(), households.ID(c), MealPlanRecipe{
+		MealPlanID:  mpID,
+		RecipeID:    &req.RecipeID,
+		MealType:    req.MealType,
+		PlannedDate: req.PlannedDate,
+		Servings:    req.Servings,
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "meal plan not found"})
+		return
+	case errors.Is(err, ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert meal plan recipe"})
 		return
 	}
 
-	id64, err := res.LastInsertId()
+	mpr.Violations = violations
+	c.JSON(http.StatusCreated, mpr)
+}
+
+// checkRestrictions looks up which of the meal plan's restrictions the recipe
+// breaks. Unless override is set it rejects the recipe with 422 when it breaks
+// any; ok is false once a response has been written.
+func checkRestrictions(c *gin.Context, store MealPlanStore, recipeStore recipes.RecipeStore, mpID, recipeID int, override bool) (violations []diet.Violation, ok bool) {
+	mp, err := store.GetMealPlan(c.Request.Context(), households.ID(c), mpID)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "meal plan not found"})
+		return nil, false
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get id"})
-		return
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return nil, false
+	}
+	if len(mp.Restrictions) == 0 {
+		return nil, true
 	}
-	id := int(id64)
 
-	var mpr MealPlanRecipe
-	err = db.QueryRow(`
-		SELECT id, meal_plan_id, recipe_id, meal_type, planned_date
-		FROM meal_plan_recipes
-		WHERE id = ?
-	`, id).Scan(&mpr.ID, &mpr.MealPlanID, &mpr.RecipeID, &mpr.MealType, &mpr.PlannedDate)
+	r, err := recipeStore.GetRecipe(c.Request.Context(), households.ID(c), recipeID)
+	if errors.Is(err, recipes.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return nil, false
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "created but failed to reload"})
-		return
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return nil, false
 	}
 
-	c.JSON(http.StatusCreated, mpr)
+	violations = r.Violations(mp.Restrictions)
+	if len(violations) > 0 && !override {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      "recipe breaks the meal plan's restrictions; set override to plan it anyway",
+			"violations": violations,
+		})
+		return nil, false
+	}
+
+	return violations, true
 }
 
-func GetMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
+func GetMealPlanRecipeHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -320,13 +430,8 @@ func GetMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	var mpr MealPlanRecipe
-	err = db.QueryRow(`
-		SELECT id, meal_plan_id, recipe_id, meal_type, planned_date
-		FROM meal_plan_recipes
-		WHERE id = ?
-	`, id).Scan(&mpr.ID, &mpr.MealPlanID, &mpr.RecipeID, &mpr.MealType, &mpr.PlannedDate)
-	if err == sql.ErrNoRows {
+	mpr, err := store.GetMealPlanRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrEntryNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -338,7 +443,11 @@ func GetMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
 	c.JSON(http.StatusOK, mpr)
 }
 
-func UpdateMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
+func UpdateMealPlanRecipeHandler(c *gin.Context, store MealPlanStore, recipeStore recipes.RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -347,19 +456,18 @@ func UpdateMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
 	}
 
 	var req UpdateMealPlanRecipeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
 
 	// Load existing
-	var current MealPlanRecipe
-	err = db.QueryRow(`
-		SELECT id, meal_plan_id, recipe_id, meal_type, planned_date
-		FROM meal_plan_recipes
-		WHERE id = ?
-	`, id).Scan(&current.ID, &current.MealPlanID, &current.RecipeID, &current.MealType, &current.PlannedDate)
-	if err == sql.ErrNoRows {
+	current, err := store.GetMealPlanRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrEntryNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -369,31 +477,50 @@ func UpdateMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
 	}
 
 	// Apply patch
-	if req.RecipeID != nil {
-		// optionally check recipe exists
-		current.RecipeID = req.RecipeID
+	req.RecipeID.ApplyPtr(&current.RecipeID)
+	req.MealType.ApplyPtr(&current.MealType)
+	req.PlannedDate.ApplyPtr(&current.PlannedDate)
+	req.Servings.ApplyPtr(&current.Servings)
+	if !checkPlannedDate(current.PlannedDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "planned_date must be a date, YYYY-MM-DD"})
+		return
 	}
-	if req.MealType != nil {
-		current.MealType = req.MealType
+	if current.Servings != nil && *current.Servings <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "servings must be positive"})
+		return
 	}
-	if req.PlannedDate != nil {
-		current.PlannedDate = req.PlannedDate
+
+	// Only a newly planned recipe is checked against the restrictions
+	var violations []diet.Violation
+	if req.RecipeID.Set && current.RecipeID != nil {
+		var ok bool
+		if violations, ok = checkRestrictions(c, store, recipeStore, current.MealPlanID, *current.RecipeID, req.Override); !ok {
+			return
+		}
 	}
 
-	_, err = db.Exec(`
-		UPDATE meal_plan_recipes
-		SET recipe_id = ?, meal_type = ?, planned_date = ?
-		WHERE id = ?
-	`, current.RecipeID, current.MealType, current.PlannedDate, id)
-	if err != nil {
+	mpr, err := store.UpdateMealPlanRecipe(c.Request.Context(), households.ID(c), current)
+	switch {
+	case errors.Is(err, ErrEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
 		return
 	}
 
-	c.JSON(http.StatusOK, current)
+	mpr.Violations = violations
+	c.JSON(http.StatusOK, mpr)
 }
 
-func DeleteMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
+func DeleteMealPlanRecipeHandler(c *gin.Context, store MealPlanStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -401,14 +528,13 @@ func DeleteMealPlanRecipeHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	res, err := db.Exec(`DELETE FROM meal_plan_recipes WHERE id = ?`, id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+	err = store.DeleteMealPlanRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
 		return
 	}
 
@@ -0,0 +1,172 @@
+package mealplan
+
+import (
+	"cmp"
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/nutrition"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PlanNutrition struct {
+	MealPlanID  int              `json:"meal_plan_id"`
+	From        string           `json:"from"` // "YYYY-MM-DD"
+	To          string           `json:"to"`   // "YYYY-MM-DD"
+	Days        []DayNutrition   `json:"days"`
+	Unscheduled *DayNutrition    `json:"unscheduled,omitempty"` // entries without a planned date
+	Total       nutrition.Facts  `json:"total"`
+	Complete    bool             `json:"complete"` // false if an ingredient couldn't be counted
+	Missing     []nutrition.Line `json:"missing"`  // the ingredients that couldn't, once per recipe
+}
+
+// DayNutrition totals the entries planned for one day, each scaled to the
+// servings it is cooked for.
+type DayNutrition struct {
+	Date    string           `json:"date,omitempty"`
+	Total   nutrition.Facts  `json:"total"`
+	Entries []EntryNutrition `json:"entries"`
+}
+
+type EntryNutrition struct {
+	EntryID  int             `json:"entry_id"`
+	RecipeID int             `json:"recipe_id"`
+	Factor   float64         `json:"factor"` // of the recipe as written
+	Facts    nutrition.Facts `json:"facts"`
+	Complete bool            `json:"complete"`
+}
+
+func GetMealPlanNutritionHandler(c *gin.Context, store MealPlanStore, foods *nutrition.Database) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	mpID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || mpID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal plan id"})
+		return
+	}
+
+	mp, err := store.GetMealPlan(c.Request.Context(), households.ID(c), mpID)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "meal plan not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	planned, err := store.ListPlannedIngredients(c.Request.Context(), households.ID(c), mpID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
+		return
+	}
+
+	n := PlanNutrition{MealPlanID: mpID, Days: []DayNutrition{}, Complete: true, Missing: []nutrition.Line{}}
+	n.From = mp.StartDate
+	n.To = mp.EndDate
+
+	missing := map[[2]int]bool{} // recipe and ingredient ids already reported
+	total := func(d planDay) DayNutrition {
+		day := DayNutrition{Date: d.Date, Entries: []EntryNutrition{}}
+		for _, rows := range d.Entries {
+			p := rows[0]
+			entry := EntryNutrition{EntryID: p.EntryID, RecipeID: p.RecipeID, Factor: p.Factor(), Complete: true}
+			for _, row := range rows {
+				line := foods.Ingredient(row.Ingredient)
+				if line.Facts != nil {
+					entry.Facts = entry.Facts.Add(line.Facts.Scale(entry.Factor))
+					continue
+				}
+
+				entry.Complete, n.Complete = false, false
+				if key := [2]int{p.RecipeID, line.IngredientID}; !missing[key] {
+					missing[key] = true
+					n.Missing = append(n.Missing, line)
+				}
+			}
+
+			day.Entries = append(day.Entries, entry)
+			day.Total = day.Total.Add(entry.Facts)
+		}
+
+		n.Total = n.Total.Add(day.Total)
+		return day
+	}
+
+	days, unscheduled := byDay(mp, planned)
+	for _, d := range days {
+		n.Days = append(n.Days, total(d))
+	}
+	if unscheduled != nil {
+		day := total(*unscheduled)
+		n.Unscheduled = &day
+	}
+
+	c.JSON(http.StatusOK, n)
+}
+
+// byEntry splits the rows ListPlannedIngredients returns, one per entry and
+// ingredient in entry order, into the rows of each entry.
+func byEntry(planned []PlannedIngredient) [][]PlannedIngredient {
+	var entries [][]PlannedIngredient
+	for i := 0; i < len(planned); {
+		j := i + 1
+		for j < len(planned) && planned[j].EntryID == planned[i].EntryID {
+			j++
+		}
+		entries = append(entries, planned[i:j])
+		i = j
+	}
+
+	return entries
+}
+
+// planDay is the entries planned for one day, each as the rows byEntry
+// splits out for it.
+type planDay struct {
+	Date    string // "" for the entries without a planned date
+	Entries [][]PlannedIngredient
+}
+
+// byDay groups a plan's entries by day for the per-day reports. Every day of
+// the plan is listed, planned or not, along with any day outside the plan an
+// entry is planned for, all in date order. Entries without a planned date
+// are unscheduled, nil if there are none.
+func byDay(mp MealPlan, planned []PlannedIngredient) (days []planDay, unscheduled *planDay) {
+	index := map[string]int{}
+	if from, err := time.Parse(time.DateOnly, mp.StartDate); err == nil {
+		to, _ := time.Parse(time.DateOnly, mp.EndDate)
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			index[d.Format(time.DateOnly)] = len(days)
+			days = append(days, planDay{Date: d.Format(time.DateOnly)})
+		}
+	}
+
+	for _, rows := range byEntry(planned) {
+		date := rows[0].PlannedDate
+		if date == nil {
+			if unscheduled == nil {
+				unscheduled = &planDay{}
+			}
+			unscheduled.Entries = append(unscheduled.Entries, rows)
+			continue
+		}
+
+		i, ok := index[*date]
+		if !ok {
+			i = len(days)
+			index[*date] = i
+			days = append(days, planDay{Date: *date})
+		}
+		days[i].Entries = append(days[i].Entries, rows)
+	}
+	slices.SortStableFunc(days, func(a, b planDay) int { return cmp.Compare(a.Date, b.Date) })
+
+	return days, unscheduled
+}
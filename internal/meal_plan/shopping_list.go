@@ -0,0 +1,252 @@
+package mealplan
+
+import (
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/nutrition"
+	"meal_prep/internal/prices"
+	"meal_prep/internal/units"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShoppingList struct {
+	MealPlanID int                 `json:"meal_plan_id"`
+	From       string              `json:"from"` // "YYYY-MM-DD"
+	To         string              `json:"to"`   // "YYYY-MM-DD"
+	Items      []ShoppingListGroup `json:"items"`
+
+	// Cost is what the items cost with the prices in effect on From, at the
+	// cheapest store or only at Store
+	Store        string       `json:"store,omitempty"`
+	Cost         prices.Money `json:"cost"`
+	CostComplete bool         `json:"cost_complete"` // false if an item couldn't be costed
+
+	// Budget is checked only when the list covers the whole plan
+	*prices.Budget
+}
+
+// ShoppingListGroup gathers every use of one ingredient across the plan.
+// Amounts in compatible units are summed; anything that can't be summed is kept
+// as written so nothing silently drops off the list.
+type ShoppingListGroup struct {
+	Name      string               `json:"name"`
+	Amounts   []ShoppingListAmount `json:"amounts"`
+	RecipeIDs []int                `json:"recipe_ids"`
+
+	Cost        *prices.Money `json:"cost,omitempty"`         // of the amounts that could be costed
+	CostProblem string        `json:"cost_problem,omitempty"` // why the others couldn't
+}
+
+type ShoppingListAmount struct {
+	Quantity *float64 `json:"quantity,omitempty"`
+	Unit     *string  `json:"unit,omitempty"`
+	Note     *string  `json:"note,omitempty"` // qualitative or unparseable quantity, as written
+}
+
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+func normalizeUnit(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return normalizeName(*s)
+}
+
+func GetShoppingListHandler(c *gin.Context, store MealPlanStore, priceStore prices.PriceStore, foods *nutrition.Database) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	mpIDStr := c.Param("id")
+	mpID, err := strconv.Atoi(mpIDStr)
+	if err != nil || mpID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal plan id"})
+		return
+	}
+
+	mp, err := store.GetMealPlan(c.Request.Context(), households.ID(c), mpID)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "meal plan not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	list := ShoppingList{MealPlanID: mpID, Items: []ShoppingListGroup{}, Store: prices.Normalize(c.Query("store")), CostComplete: true}
+	list.From = mp.StartDate
+	list.To = mp.EndDate
+
+	// Optional sub-range of the plan
+	ranged := false
+	for _, p := range []struct {
+		name string
+		dst  *string
+	}{{"from", &list.From}, {"to", &list.To}} {
+		v, ok := c.GetQuery(p.name)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.name + " date, expected YYYY-MM-DD"})
+			return
+		}
+		if v < list.From || v > list.To {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.name + " date is outside the meal plan"})
+			return
+		}
+		*p.dst = v
+		ranged = true
+	}
+	if list.From > list.To {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from date is after to date"})
+		return
+	}
+
+	planned, err := store.ListPlannedIngredients(c.Request.Context(), households.ID(c), mpID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
+		return
+	}
+
+	priceList, err := priceStore.ListPrices(c.Request.Context(), households.ID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query prices"})
+		return
+	}
+
+	groups := map[string]*ShoppingListGroup{}
+	for _, p := range planned {
+		if ranged {
+			// Unscheduled entries can't be placed inside a range, so they drop out
+			if p.PlannedDate == nil || *p.PlannedDate < list.From || *p.PlannedDate > list.To {
+				continue
+			}
+		}
+
+		ing := p.Ingredient
+		key := normalizeName(ing.Name)
+		g, ok := groups[key]
+		if !ok {
+			g = &ShoppingListGroup{Name: key, Amounts: []ShoppingListAmount{}}
+			groups[key] = g
+		}
+		if !slices.Contains(g.RecipeIDs, p.RecipeID) {
+			g.RecipeIDs = append(g.RecipeIDs, p.RecipeID)
+		}
+
+		if ing.Quantity == nil || strings.TrimSpace(*ing.Quantity) == "" {
+			continue
+		}
+
+		if ing.QuantityValue == nil {
+			g.Amounts = append(g.Amounts, ShoppingListAmount{Unit: ing.Unit, Note: ing.Quantity})
+			continue
+		}
+
+		// Ranges are budgeted at their upper end so the list never falls short
+		amount := *ing.QuantityValue
+		if ing.QuantityMax != nil {
+			amount = *ing.QuantityMax
+		}
+		g.addAmount(amount*p.Factor(), ing.Unit)
+	}
+
+	catalog := prices.NewCatalog(priceList, list.Store, foods)
+	for _, g := range groups {
+		g.cost(catalog, list.From)
+		if g.Cost != nil {
+			list.Cost += *g.Cost
+		}
+		if g.CostProblem != "" {
+			list.CostComplete = false
+		}
+		list.Items = append(list.Items, *g)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+	if !ranged {
+		list.Budget = prices.CheckBudget(list.Cost, mp.Budget)
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// Factor scales a recipe cooked for a different number of people than it
+// was written for. Without both counts the recipe is taken as written.
+func (p PlannedIngredient) Factor() float64 {
+	if p.EntryServings == nil || p.RecipeServings == nil || *p.RecipeServings <= 0 {
+		return 1
+	}
+
+	return float64(*p.EntryServings) / float64(*p.RecipeServings)
+}
+
+// addAmount sums v into an existing amount whose unit it can be converted
+// to, if any. Units the registry doesn't know only merge on identical
+// spelling.
+func (g *ShoppingListGroup) addAmount(v float64, unit *string) {
+	for i, a := range g.Amounts {
+		if a.Quantity == nil {
+			continue
+		}
+
+		converted, err := units.Convert(v, unitName(unit), unitName(a.Unit))
+		if err != nil {
+			if normalizeUnit(a.Unit) != normalizeUnit(unit) {
+				continue
+			}
+			converted = v
+		}
+
+		sum := *a.Quantity + converted
+		g.Amounts[i].Quantity = &sum
+		return
+	}
+
+	// Known units are listed under their canonical spelling
+	if u, ok := units.Lookup(unitName(unit)); ok && unit != nil {
+		unit = &u.Name
+	}
+	g.Amounts = append(g.Amounts, ShoppingListAmount{Quantity: &v, Unit: unit})
+}
+
+// cost prices the group's amounts on the day. Amounts kept as written can't
+// be priced.
+func (g *ShoppingListGroup) cost(catalog *prices.Catalog, day string) {
+	for _, a := range g.Amounts {
+		if a.Quantity == nil {
+			g.CostProblem = "no numeric quantity"
+			continue
+		}
+
+		line := catalog.Cost(g.Name, *a.Quantity, a.Unit, day)
+		if line.Cost == nil {
+			g.CostProblem = line.Problem
+			continue
+		}
+		total := *line.Cost
+		if g.Cost != nil {
+			total += *g.Cost
+		}
+		g.Cost = &total
+	}
+}
+
+func unitName(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
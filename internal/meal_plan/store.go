@@ -0,0 +1,54 @@
+package mealplan
+
+import (
+	"context"
+	"errors"
+	"meal_prep/internal/ingredients"
+)
+
+var (
+	ErrNotFound       = errors.New("meal plan not found")
+	ErrEntryNotFound  = errors.New("meal plan recipe not found")
+	ErrRecipeNotFound = errors.New("recipe not found")
+)
+
+// PlannedIngredient is one ingredient of one planned recipe, with what's
+// needed to scale it to the servings the entry is cooked for.
+type PlannedIngredient struct {
+	EntryID        int
+	RecipeID       int
+	PlannedDate    *string
+	EntryServings  *int
+	RecipeServings *int
+	Ingredient     ingredients.Ingredient
+}
+
+// MealPlanStore is the persistence behind the meal plan handlers. Plans, and
+// through them their entries, belong to a household and every call is scoped
+// to one; another household's plan or entry reads as missing. A missing plan is reported as
+// ErrNotFound, a missing meal_plan_recipes entry as ErrEntryNotFound, and an
+// entry pointing at a recipe the household doesn't have as ErrRecipeNotFound.
+type MealPlanStore interface {
+	// ListMealPlans returns the page q asks for, fetching q.Fetch() rows.
+	ListMealPlans(ctx context.Context, householdID int, q MealPlanQuery) ([]MealPlan, error)
+	GetMealPlan(ctx context.Context, householdID, id int) (MealPlan, error)
+	// CreateMealPlan stores mp in mp.HouseholdID, recording mp.OwnerID as its
+	// author.
+	CreateMealPlan(ctx context.Context, mp MealPlan) (MealPlan, error)
+	// UpdateMealPlan overwrites every column of mp.ID, if it is in
+	// mp.HouseholdID.
+	UpdateMealPlan(ctx context.Context, mp MealPlan) (MealPlan, error)
+	// DeleteMealPlan removes the plan together with its entries.
+	DeleteMealPlan(ctx context.Context, householdID, id int) error
+
+	ListMealPlanRecipes(ctx context.Context, householdID, mealPlanID int) ([]MealPlanRecipe, error)
+	GetMealPlanRecipe(ctx context.Context, householdID, id int) (MealPlanRecipe, error)
+	CreateMealPlanRecipe(ctx context.Context, householdID int, mpr MealPlanRecipe) (MealPlanRecipe, error)
+	// UpdateMealPlanRecipe overwrites every column of mpr.ID.
+	UpdateMealPlanRecipe(ctx context.Context, householdID int, mpr MealPlanRecipe) (MealPlanRecipe, error)
+	DeleteMealPlanRecipe(ctx context.Context, householdID, id int) error
+
+	// ListPlannedIngredients returns the ingredients of every recipe planned
+	// in the meal plan, one row per entry and ingredient, in entry order.
+	ListPlannedIngredients(ctx context.Context, householdID, mealPlanID int) ([]PlannedIngredient, error)
+}
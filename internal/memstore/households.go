@@ -0,0 +1,252 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/households"
+	"slices"
+	"time"
+)
+
+type household struct {
+	households.Household // Role is filled in per caller
+	members              map[int]member
+}
+
+type member struct {
+	role     households.Role
+	joinedAt *time.Time
+	seq      int // join order, finer than joinedAt
+}
+
+// createHousehold makes userID the owner of a new household. Callers hold
+// s.mu.
+func (s *Store) createHousehold(userID int, name string) int {
+	h := household{
+		Household: households.Household{ID: s.nextID("households"), Name: name, CreatedAt: now()},
+		members:   map[int]member{},
+	}
+	h.members[userID] = member{role: households.Owner, joinedAt: now(), seq: s.nextID("household_members")}
+	s.households[h.ID] = h
+
+	return h.ID
+}
+
+// getHousehold returns the household as seen by userID. Callers hold s.mu.
+func (s *Store) getHousehold(userID, id int) (households.Household, error) {
+	h, ok := s.households[id]
+	if !ok {
+		return households.Household{}, households.ErrNotFound
+	}
+	m, ok := h.members[userID]
+	if !ok {
+		return households.Household{}, households.ErrNotFound
+	}
+
+	hh := h.Household
+	hh.Role = m.role
+	return hh, nil
+}
+
+func (s *Store) ListHouseholds(ctx context.Context, userID int) ([]households.Household, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		list []households.Household
+		seqs = map[int]int{}
+	)
+	for id, h := range s.households {
+		if m, ok := h.members[userID]; ok {
+			hh, _ := s.getHousehold(userID, id)
+			list = append(list, hh)
+			seqs[id] = m.seq
+		}
+	}
+	slices.SortFunc(list, func(a, b households.Household) int { return cmp.Compare(seqs[a.ID], seqs[b.ID]) })
+
+	return list, nil
+}
+
+func (s *Store) GetHousehold(ctx context.Context, userID, id int) (households.Household, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getHousehold(userID, id)
+}
+
+func (s *Store) CreateHousehold(ctx context.Context, userID int, name string) (households.Household, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getHousehold(userID, s.createHousehold(userID, name))
+}
+
+func (s *Store) RenameHousehold(ctx context.Context, userID, id int, name string) (households.Household, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.households[id]
+	if !ok {
+		return households.Household{}, households.ErrNotFound
+	}
+	h.Name = name
+	s.households[id] = h
+
+	return s.getHousehold(userID, id)
+}
+
+func (s *Store) DeleteHousehold(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.households[id]; !ok {
+		return households.ErrNotFound
+	}
+	delete(s.households, id)
+
+	for recipeID, r := range s.recipes {
+		if r.HouseholdID == id {
+			s.deleteRecipe(recipeID)
+		}
+	}
+	for planID, mp := range s.plans {
+		if mp.HouseholdID == id {
+			s.deletePlan(planID)
+		}
+	}
+	for hash, inv := range s.invites {
+		if inv.HouseholdID == id {
+			delete(s.invites, hash)
+		}
+	}
+	for tagID, t := range s.tags {
+		if t.HouseholdID == id {
+			delete(s.tags, tagID)
+		}
+	}
+	for priceID, p := range s.prices {
+		if p.HouseholdID == id {
+			delete(s.prices, priceID)
+		}
+	}
+
+	return nil
+}
+
+// memberOf returns the user's membership of an existing household. Callers
+// hold s.mu.
+func (s *Store) memberOf(householdID, userID int) (households.Member, error) {
+	m, ok := s.households[householdID].members[userID]
+	if !ok {
+		return households.Member{}, households.ErrMemberNotFound
+	}
+
+	return households.Member{UserID: userID, Email: s.users[userID].Email, Role: m.role, JoinedAt: m.joinedAt}, nil
+}
+
+func (s *Store) ListMembers(ctx context.Context, householdID int) ([]households.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.households[householdID]
+	var list []households.Member
+	for userID := range h.members {
+		m, _ := s.memberOf(householdID, userID)
+		list = append(list, m)
+	}
+	slices.SortFunc(list, func(a, b households.Member) int {
+		return cmp.Compare(h.members[a.UserID].seq, h.members[b.UserID].seq)
+	})
+
+	return list, nil
+}
+
+// checkOwnersLeft fails with ErrLastOwner if taking the owner role away from
+// userID would leave the household without one. Callers hold s.mu.
+func (s *Store) checkOwnersLeft(householdID, userID int) error {
+	h := s.households[householdID]
+	if h.members[userID].role != households.Owner {
+		return nil
+	}
+
+	owners := 0
+	for _, m := range h.members {
+		if m.role == households.Owner {
+			owners++
+		}
+	}
+	if owners <= 1 {
+		return households.ErrLastOwner
+	}
+
+	return nil
+}
+
+func (s *Store) SetMemberRole(ctx context.Context, householdID, userID int, role households.Role) (households.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.memberOf(householdID, userID); err != nil {
+		return households.Member{}, err
+	}
+	if role != households.Owner {
+		if err := s.checkOwnersLeft(householdID, userID); err != nil {
+			return households.Member{}, err
+		}
+	}
+
+	m := s.households[householdID].members[userID]
+	m.role = role
+	s.households[householdID].members[userID] = m
+
+	return s.memberOf(householdID, userID)
+}
+
+func (s *Store) RemoveMember(ctx context.Context, householdID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.memberOf(householdID, userID); err != nil {
+		return err
+	}
+	if err := s.checkOwnersLeft(householdID, userID); err != nil {
+		return err
+	}
+	delete(s.households[householdID].members, userID)
+
+	return nil
+}
+
+func (s *Store) CreateInvite(ctx context.Context, inv households.Invite) (households.Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv.ID = s.nextID("household_invites")
+	s.invites[inv.CodeHash] = inv
+
+	return inv, nil
+}
+
+func (s *Store) AcceptInvite(ctx context.Context, codeHash string, userID int) (households.Household, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, ok := s.invites[codeHash]
+	if !ok || !time.Now().Before(inv.ExpiresAt) {
+		return households.Household{}, households.ErrInviteNotFound
+	}
+	h, ok := s.households[inv.HouseholdID]
+	if !ok {
+		return households.Household{}, households.ErrInviteNotFound
+	}
+	if _, ok := h.members[userID]; ok {
+		return households.Household{}, households.ErrAlreadyMember
+	}
+
+	// Invites are single use
+	delete(s.invites, codeHash)
+	h.members[userID] = member{role: inv.Role, joinedAt: now(), seq: s.nextID("household_members")}
+
+	return s.getHousehold(userID, inv.HouseholdID)
+}
@@ -0,0 +1,98 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/ingredients"
+	"meal_prep/internal/listing"
+	"slices"
+	"strings"
+)
+
+func (s *Store) ListIngredients(ctx context.Context, householdID, recipeID int, q ingredients.IngredientQuery) ([]ingredients.Ingredient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, recipeID); !ok {
+		return nil, nil
+	}
+
+	name := strings.ToLower(q.Name)
+	list := slices.DeleteFunc(s.ingredientsOf(recipeID), func(ing ingredients.Ingredient) bool {
+		return !strings.Contains(strings.ToLower(ing.Name), name)
+	})
+
+	return listing.Apply(list, q.Query, ingredients.IngredientSorts, func(ing ingredients.Ingredient) int { return ing.ID }), nil
+}
+
+// ingredientsOf returns the recipe's ingredients in the order they were
+// added. Callers hold s.mu.
+func (s *Store) ingredientsOf(recipeID int) []ingredients.Ingredient {
+	return sorted(s.ingredients,
+		func(ing ingredients.Ingredient) bool { return ing.RecipeID == recipeID },
+		func(a, b ingredients.Ingredient) int { return cmp.Compare(a.ID, b.ID) },
+	)
+}
+
+// getIngredient returns the ingredient if householdID owns its recipe. Callers
+// hold s.mu.
+func (s *Store) getIngredient(householdID, id int) (ingredients.Ingredient, error) {
+	ing, ok := s.ingredients[id]
+	if !ok {
+		return ingredients.Ingredient{}, ingredients.ErrNotFound
+	}
+	if _, ok := s.householdRecipe(householdID, ing.RecipeID); !ok {
+		return ingredients.Ingredient{}, ingredients.ErrNotFound
+	}
+
+	return ing, nil
+}
+
+func (s *Store) GetIngredient(ctx context.Context, householdID, id int) (ingredients.Ingredient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getIngredient(householdID, id)
+}
+
+func (s *Store) CreateIngredient(ctx context.Context, householdID int, ing ingredients.Ingredient) (ingredients.Ingredient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, ing.RecipeID); !ok {
+		return ing, ingredients.ErrRecipeNotFound
+	}
+
+	ing.ID = s.nextID("recipe_ingredients")
+	s.ingredients[ing.ID] = ing
+
+	return ing, nil
+}
+
+func (s *Store) UpdateIngredient(ctx context.Context, householdID int, ing ingredients.Ingredient) (ingredients.Ingredient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.getIngredient(householdID, ing.ID)
+	if err != nil {
+		return ing, err
+	}
+
+	// The recipe an ingredient belongs to never changes
+	ing.RecipeID = current.RecipeID
+	s.ingredients[ing.ID] = ing
+
+	return ing, nil
+}
+
+func (s *Store) DeleteIngredient(ctx context.Context, householdID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.getIngredient(householdID, id); err != nil {
+		return err
+	}
+	delete(s.ingredients, id)
+
+	return nil
+}
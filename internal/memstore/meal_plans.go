@@ -0,0 +1,221 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/listing"
+	mealplan "meal_prep/internal/meal_plan"
+)
+
+func (s *Store) ListMealPlans(ctx context.Context, householdID int, q mealplan.MealPlanQuery) ([]mealplan.MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []mealplan.MealPlan
+	for _, mp := range s.plans {
+		if mp.HouseholdID == 0 || mp.HouseholdID != householdID {
+			continue
+		}
+		if q.From != nil && mp.EndDate < *q.From || q.To != nil && mp.StartDate > *q.To {
+			continue
+		}
+		list = append(list, mp)
+	}
+
+	return listing.Apply(list, q.Query, mealplan.MealPlanSorts, func(mp mealplan.MealPlan) int { return mp.ID }), nil
+}
+
+func (s *Store) GetMealPlan(ctx context.Context, householdID, id int) (mealplan.MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mp, ok := s.householdPlan(householdID, id)
+	if !ok {
+		return mealplan.MealPlan{}, mealplan.ErrNotFound
+	}
+
+	return mp, nil
+}
+
+func (s *Store) CreateMealPlan(ctx context.Context, mp mealplan.MealPlan) (mealplan.MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mp.ID = s.nextID("meal_plans")
+	mp.CreatedAt = now()
+	s.plans[mp.ID] = mp
+
+	return mp, nil
+}
+
+func (s *Store) UpdateMealPlan(ctx context.Context, mp mealplan.MealPlan) (mealplan.MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.householdPlan(mp.HouseholdID, mp.ID)
+	if !ok {
+		return mp, mealplan.ErrNotFound
+	}
+
+	mp.CreatedAt = current.CreatedAt
+	s.plans[mp.ID] = mp
+
+	return mp, nil
+}
+
+func (s *Store) DeleteMealPlan(ctx context.Context, householdID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdPlan(householdID, id); !ok {
+		return mealplan.ErrNotFound
+	}
+	s.deletePlan(id)
+
+	return nil
+}
+
+// deletePlan removes the plan with its entries. Callers hold s.mu.
+func (s *Store) deletePlan(id int) {
+	delete(s.plans, id)
+
+	for entryID, e := range s.entries {
+		if e.MealPlanID == id {
+			delete(s.entries, entryID)
+		}
+	}
+}
+
+// entriesOf returns the plan's entries, unscheduled ones first. Callers hold
+// s.mu.
+func (s *Store) entriesOf(mealPlanID int) []mealplan.MealPlanRecipe {
+	return sorted(s.entries,
+		func(e mealplan.MealPlanRecipe) bool { return e.MealPlanID == mealPlanID },
+		func(a, b mealplan.MealPlanRecipe) int {
+			return cmp.Or(nilsFirst(a.PlannedDate, b.PlannedDate), cmp.Compare(a.ID, b.ID))
+		},
+	)
+}
+
+func (s *Store) ListMealPlanRecipes(ctx context.Context, householdID, mealPlanID int) ([]mealplan.MealPlanRecipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdPlan(householdID, mealPlanID); !ok {
+		return nil, nil
+	}
+
+	return s.entriesOf(mealPlanID), nil
+}
+
+// getEntry returns the entry if householdID owns its plan. Callers hold s.mu.
+func (s *Store) getEntry(householdID, id int) (mealplan.MealPlanRecipe, error) {
+	mpr, ok := s.entries[id]
+	if !ok {
+		return mealplan.MealPlanRecipe{}, mealplan.ErrEntryNotFound
+	}
+	if _, ok := s.householdPlan(householdID, mpr.MealPlanID); !ok {
+		return mealplan.MealPlanRecipe{}, mealplan.ErrEntryNotFound
+	}
+
+	return mpr, nil
+}
+
+// checkRecipeRef keeps entries from pointing at missing or other users'
+// recipes. Callers hold s.mu.
+func (s *Store) checkRecipeRef(householdID int, mpr mealplan.MealPlanRecipe) error {
+	if mpr.RecipeID == nil {
+		return nil
+	}
+	if _, ok := s.householdRecipe(householdID, *mpr.RecipeID); !ok {
+		return mealplan.ErrRecipeNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) GetMealPlanRecipe(ctx context.Context, householdID, id int) (mealplan.MealPlanRecipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getEntry(householdID, id)
+}
+
+func (s *Store) CreateMealPlanRecipe(ctx context.Context, householdID int, mpr mealplan.MealPlanRecipe) (mealplan.MealPlanRecipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdPlan(householdID, mpr.MealPlanID); !ok {
+		return mpr, mealplan.ErrNotFound
+	}
+	if err := s.checkRecipeRef(householdID, mpr); err != nil {
+		return mpr, err
+	}
+
+	mpr.ID = s.nextID("meal_plan_recipes")
+	s.entries[mpr.ID] = mpr
+
+	return mpr, nil
+}
+
+func (s *Store) UpdateMealPlanRecipe(ctx context.Context, householdID int, mpr mealplan.MealPlanRecipe) (mealplan.MealPlanRecipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.getEntry(householdID, mpr.ID)
+	if err != nil {
+		return mpr, err
+	}
+	if err := s.checkRecipeRef(householdID, mpr); err != nil {
+		return mpr, err
+	}
+
+	// Entries never move between plans
+	mpr.MealPlanID = current.MealPlanID
+	s.entries[mpr.ID] = mpr
+
+	return mpr, nil
+}
+
+func (s *Store) DeleteMealPlanRecipe(ctx context.Context, householdID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.getEntry(householdID, id); err != nil {
+		return err
+	}
+	delete(s.entries, id)
+
+	return nil
+}
+
+func (s *Store) ListPlannedIngredients(ctx context.Context, householdID, mealPlanID int) ([]mealplan.PlannedIngredient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdPlan(householdID, mealPlanID); !ok {
+		return nil, nil
+	}
+
+	entries := sorted(s.entries,
+		func(e mealplan.MealPlanRecipe) bool { return e.MealPlanID == mealPlanID && e.RecipeID != nil },
+		func(a, b mealplan.MealPlanRecipe) int { return cmp.Compare(a.ID, b.ID) },
+	)
+
+	var list []mealplan.PlannedIngredient
+	for _, e := range entries {
+		r := s.recipes[*e.RecipeID]
+		for _, ing := range s.ingredientsOf(r.ID) {
+			list = append(list, mealplan.PlannedIngredient{
+				EntryID:        e.ID,
+				RecipeID:       r.ID,
+				PlannedDate:    e.PlannedDate,
+				EntryServings:  e.Servings,
+				RecipeServings: r.Servings,
+				Ingredient:     ing,
+			})
+		}
+	}
+
+	return list, nil
+}
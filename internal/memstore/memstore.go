@@ -0,0 +1,121 @@
+// Package memstore is an in-memory implementation of the recipe, ingredient,
+// meal plan, user, API token, household, tag and price stores, for exercising
+// handlers without a database. It keeps the same invariants as the SQL
+// schema: deleting a recipe removes its ingredients, steps and tags and
+// unlinks it from meal plans, deleting a meal plan removes its entries, and
+// deleting a household removes everything in it. A HouseholdID of 0 stands in for a NULL household_id.
+package memstore
+
+import (
+	"cmp"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/households"
+	"meal_prep/internal/ingredients"
+	mealplan "meal_prep/internal/meal_plan"
+	"meal_prep/internal/prices"
+	"meal_prep/internal/recipes"
+	"meal_prep/internal/tags"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	_ recipes.RecipeStore         = (*Store)(nil)
+	_ ingredients.IngredientStore = (*Store)(nil)
+	_ mealplan.MealPlanStore      = (*Store)(nil)
+	_ auth.UserStore              = (*Store)(nil)
+	_ auth.TokenStore             = (*Store)(nil)
+	_ households.HouseholdStore   = (*Store)(nil)
+	_ tags.TagStore               = (*Store)(nil)
+	_ prices.PriceStore           = (*Store)(nil)
+)
+
+type Store struct {
+	mu     sync.Mutex
+	lastID map[string]int // per table, like AUTOINCREMENT
+
+	recipes     map[int]recipes.Recipe
+	steps       map[int]recipes.Step
+	ingredients map[int]ingredients.Ingredient
+	plans       map[int]mealplan.MealPlan
+	entries     map[int]mealplan.MealPlanRecipe
+	users       map[int]auth.User
+	sessions    map[string]session // by token hash
+	apiTokens   map[int]auth.APIToken
+	households  map[int]household
+	invites     map[string]households.Invite // by code hash
+	tags        map[int]tags.Tag             // RecipeCount left at 0
+	recipeTags  map[int]map[int]bool         // tag ids by recipe id
+	prices      map[int]prices.Price
+}
+
+func New() *Store {
+	return &Store{
+		lastID:      map[string]int{},
+		recipes:     map[int]recipes.Recipe{},
+		steps:       map[int]recipes.Step{},
+		ingredients: map[int]ingredients.Ingredient{},
+		plans:       map[int]mealplan.MealPlan{},
+		entries:     map[int]mealplan.MealPlanRecipe{},
+		users:       map[int]auth.User{},
+		sessions:    map[string]session{},
+		apiTokens:   map[int]auth.APIToken{},
+		households:  map[int]household{},
+		invites:     map[string]households.Invite{},
+		tags:        map[int]tags.Tag{},
+		recipeTags:  map[int]map[int]bool{},
+		prices:      map[int]prices.Price{},
+	}
+}
+
+func (s *Store) nextID(table string) int {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// householdRecipe and householdPlan look a row up the way the household
+// scoped queries do: another household's row is as missing as one that
+// doesn't exist. Callers hold s.mu.
+func (s *Store) householdRecipe(householdID, id int) (recipes.Recipe, bool) {
+	r, ok := s.recipes[id]
+	return r, ok && r.HouseholdID != 0 && r.HouseholdID == householdID
+}
+
+func (s *Store) householdPlan(householdID, id int) (mealplan.MealPlan, bool) {
+	mp, ok := s.plans[id]
+	return mp, ok && mp.HouseholdID != 0 && mp.HouseholdID == householdID
+}
+
+// now matches the second resolution of SQLite's CURRENT_TIMESTAMP.
+func now() *time.Time {
+	t := time.Now().UTC().Truncate(time.Second)
+	return &t
+}
+
+// sorted returns the map's values that match keep, ordered by cmp.
+func sorted[T any](m map[int]T, keep func(T) bool, cmp func(a, b T) int) []T {
+	var list []T
+	for _, v := range m {
+		if keep(v) {
+			list = append(list, v)
+		}
+	}
+	slices.SortFunc(list, cmp)
+
+	return list
+}
+
+// nilsFirst orders optional values the way SQLite's ORDER BY does.
+func nilsFirst[T cmp.Ordered](a, b *T) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	return cmp.Compare(*a, *b)
+}
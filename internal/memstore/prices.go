@@ -0,0 +1,71 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/prices"
+)
+
+func (s *Store) ListPrices(ctx context.Context, householdID int) ([]prices.Price, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sorted(s.prices, func(p prices.Price) bool { return p.HouseholdID == householdID }, func(a, b prices.Price) int {
+		return cmp.Or(
+			cmp.Compare(a.Item, b.Item),
+			cmp.Compare(a.Store, b.Store),
+			cmp.Compare(b.EffectiveFrom, a.EffectiveFrom),
+			cmp.Compare(b.ID, a.ID),
+		)
+	}), nil
+}
+
+func (s *Store) GetPrice(ctx context.Context, householdID, id int) (prices.Price, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.prices[id]
+	if !ok || p.HouseholdID != householdID {
+		return prices.Price{}, prices.ErrNotFound
+	}
+
+	return p, nil
+}
+
+func (s *Store) CreatePrice(ctx context.Context, p prices.Price) (prices.Price, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.ID = s.nextID("prices")
+	p.CreatedAt = now()
+	s.prices[p.ID] = p
+
+	return p, nil
+}
+
+func (s *Store) UpdatePrice(ctx context.Context, p prices.Price) (prices.Price, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.prices[p.ID]
+	if !ok || current.HouseholdID != p.HouseholdID {
+		return prices.Price{}, prices.ErrNotFound
+	}
+
+	p.CreatedAt = current.CreatedAt
+	s.prices[p.ID] = p
+
+	return p, nil
+}
+
+func (s *Store) DeletePrice(ctx context.Context, householdID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.prices[id]; !ok || p.HouseholdID != householdID {
+		return prices.ErrNotFound
+	}
+	delete(s.prices, id)
+
+	return nil
+}
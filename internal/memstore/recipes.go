@@ -0,0 +1,213 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/diet"
+	"meal_prep/internal/listing"
+	"meal_prep/internal/recipes"
+	"slices"
+	"strings"
+	"time"
+)
+
+func newestFirst(a, b recipes.Recipe) int {
+	return cmp.Or(b.CreatedAt.Compare(*a.CreatedAt), cmp.Compare(b.ID, a.ID))
+}
+
+// listRecipes returns the newest recipes that match keep. Callers hold s.mu.
+func (s *Store) listRecipes(keep func(recipes.Recipe) bool) []recipes.Recipe {
+	list := sorted(s.recipes, keep, newestFirst)
+	list = list[:min(len(list), 100)]
+	for i, r := range list {
+		list[i] = s.classified(r)
+	}
+
+	return list
+}
+
+// classified returns r with the diet profile of its ingredients. Callers hold
+// s.mu.
+func (s *Store) classified(r recipes.Recipe) recipes.Recipe {
+	var names []string
+	for _, ing := range s.ingredientsOf(r.ID) {
+		names = append(names, ing.Name)
+	}
+	r.Profile = diet.Classify(names)
+
+	return r
+}
+
+func (s *Store) ListRecipes(ctx context.Context, householdID int, q recipes.RecipeQuery) ([]recipes.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []recipes.Recipe
+	for _, r := range s.recipes {
+		if r.HouseholdID == 0 || r.HouseholdID != householdID || !matchRecipe(r, q) || !s.hasTags(r.ID, q.Tags, q.AnyTag) {
+			continue
+		}
+		list = append(list, s.classified(r))
+	}
+
+	return listing.Apply(list, q.Query, recipes.RecipeSorts, func(r recipes.Recipe) int { return r.ID }), nil
+}
+
+// matchRecipe applies the filters of q, the way the SQL store's WHERE clause
+// does.
+func matchRecipe(r recipes.Recipe, q recipes.RecipeQuery) bool {
+	if q.MaxTotalTime != nil && r.TotalTime() > *q.MaxTotalTime {
+		return false
+	}
+	if (q.MinServings != nil || q.MaxServings != nil) && r.Servings == nil {
+		return false
+	}
+	if q.MinServings != nil && *r.Servings < *q.MinServings {
+		return false
+	}
+	if q.MaxServings != nil && *r.Servings > *q.MaxServings {
+		return false
+	}
+
+	created := r.CreatedAt.Format(time.DateOnly)
+	if q.CreatedFrom != nil && created < *q.CreatedFrom {
+		return false
+	}
+	if q.CreatedTo != nil && created > *q.CreatedTo {
+		return false
+	}
+
+	return true
+}
+
+func (s *Store) GetRecipe(ctx context.Context, householdID, id int) (recipes.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.householdRecipe(householdID, id)
+	if !ok {
+		return recipes.Recipe{}, recipes.ErrNotFound
+	}
+
+	return s.classified(r), nil
+}
+
+// SearchRecipes ranks with recipes.SearchDocument, the fallback the SQL store
+// uses when it has no full-text index.
+func (s *Store) SearchRecipes(ctx context.Context, householdID int, terms []string) ([]recipes.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		list   []recipes.Recipe
+		scores = map[int]float64{}
+	)
+	inHousehold := func(r recipes.Recipe) bool { return r.HouseholdID != 0 && r.HouseholdID == householdID }
+	for _, r := range sorted(s.recipes, inHousehold, newestFirst) {
+		doc := recipes.SearchDocument{Title: r.Title}
+		if r.Description != nil {
+			doc.Description = *r.Description
+		}
+		var names, steps []string
+		for _, ing := range s.ingredientsOf(r.ID) {
+			names = append(names, ing.Name)
+		}
+		for _, st := range s.stepsOf(r.ID) {
+			steps = append(steps, st.Instruction)
+		}
+		doc.Ingredients, doc.Steps = strings.Join(names, " "), strings.Join(steps, " ")
+
+		score, snippet, ok := doc.Match(terms)
+		if !ok {
+			continue
+		}
+		r.Snippet = snippet
+		scores[r.ID] = score
+		list = append(list, s.classified(r))
+	}
+
+	slices.SortStableFunc(list, func(a, b recipes.Recipe) int { return cmp.Compare(scores[b.ID], scores[a.ID]) })
+	return list[:min(len(list), 100)], nil
+}
+
+func (s *Store) ListPublicRecipes(ctx context.Context) ([]recipes.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listRecipes(func(r recipes.Recipe) bool { return r.IsPublic && r.HouseholdID != 0 }), nil
+}
+
+func (s *Store) GetPublicRecipe(ctx context.Context, id int) (recipes.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.recipes[id]
+	if !ok || !r.IsPublic || r.HouseholdID == 0 {
+		return recipes.Recipe{}, recipes.ErrNotFound
+	}
+
+	return s.classified(r), nil
+}
+
+func (s *Store) CreateRecipe(ctx context.Context, r recipes.Recipe) (recipes.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ID = s.nextID("recipes")
+	r.CreatedAt, r.UpdatedAt = now(), now()
+	r.Steps = nil
+	s.recipes[r.ID] = r
+
+	return s.classified(r), nil
+}
+
+func (s *Store) UpdateRecipe(ctx context.Context, r recipes.Recipe) (recipes.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.householdRecipe(r.HouseholdID, r.ID)
+	if !ok {
+		return r, recipes.ErrNotFound
+	}
+
+	r.CreatedAt, r.UpdatedAt = current.CreatedAt, now()
+	r.Steps = nil
+	s.recipes[r.ID] = r
+
+	return s.classified(r), nil
+}
+
+func (s *Store) DeleteRecipe(ctx context.Context, householdID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, id); !ok {
+		return recipes.ErrNotFound
+	}
+	s.deleteRecipe(id)
+
+	return nil
+}
+
+// deleteRecipe cascades like the schema does. Callers hold s.mu.
+func (s *Store) deleteRecipe(id int) {
+	delete(s.recipes, id)
+
+	for stepID, st := range s.steps {
+		if st.RecipeID == id {
+			delete(s.steps, stepID)
+		}
+	}
+	for ingID, ing := range s.ingredients {
+		if ing.RecipeID == id {
+			delete(s.ingredients, ingID)
+		}
+	}
+	delete(s.recipeTags, id)
+	for entryID, e := range s.entries {
+		if e.RecipeID != nil && *e.RecipeID == id {
+			e.RecipeID = nil
+			s.entries[entryID] = e
+		}
+	}
+}
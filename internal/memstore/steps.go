@@ -0,0 +1,145 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/recipes"
+)
+
+// stepsOf returns the recipe's steps in order. Callers hold s.mu.
+func (s *Store) stepsOf(recipeID int) []recipes.Step {
+	return sorted(s.steps,
+		func(st recipes.Step) bool { return st.RecipeID == recipeID },
+		func(a, b recipes.Step) int { return cmp.Or(cmp.Compare(a.StepNo, b.StepNo), cmp.Compare(a.ID, b.ID)) },
+	)
+}
+
+// shift moves every step of the recipe numbered within [from, to] by delta.
+// Callers hold s.mu.
+func (s *Store) shift(recipeID, from, to, delta int) {
+	for id, st := range s.steps {
+		if st.RecipeID == recipeID && st.StepNo >= from && st.StepNo <= to {
+			st.StepNo += delta
+			s.steps[id] = st
+		}
+	}
+}
+
+func (s *Store) getStep(householdID, recipeID, stepID int) (recipes.Step, error) {
+	if _, ok := s.householdRecipe(householdID, recipeID); !ok {
+		return recipes.Step{}, recipes.ErrStepNotFound
+	}
+
+	st, ok := s.steps[stepID]
+	if !ok || st.RecipeID != recipeID {
+		return recipes.Step{}, recipes.ErrStepNotFound
+	}
+
+	return st, nil
+}
+
+func (s *Store) ListSteps(ctx context.Context, householdID, recipeID int) ([]recipes.Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, recipeID); !ok {
+		return nil, recipes.ErrNotFound
+	}
+
+	return s.stepsOf(recipeID), nil
+}
+
+func (s *Store) GetStep(ctx context.Context, householdID, recipeID, stepID int) (recipes.Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getStep(householdID, recipeID, stepID)
+}
+
+func (s *Store) CreateStep(ctx context.Context, householdID int, st recipes.Step) (recipes.Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, st.RecipeID); !ok {
+		return st, recipes.ErrNotFound
+	}
+
+	n := len(s.stepsOf(st.RecipeID))
+	if st.StepNo <= 0 || st.StepNo > n {
+		st.StepNo = n + 1
+	}
+	s.shift(st.RecipeID, st.StepNo, n, 1)
+
+	st.ID = s.nextID("recipe_steps")
+	s.steps[st.ID] = st
+
+	return st, nil
+}
+
+func (s *Store) UpdateStep(ctx context.Context, householdID int, st recipes.Step) (recipes.Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.getStep(householdID, st.RecipeID, st.ID)
+	if err != nil {
+		return st, err
+	}
+
+	st.StepNo = min(st.StepNo, len(s.stepsOf(st.RecipeID)))
+	if st.StepNo < current.StepNo {
+		s.shift(st.RecipeID, st.StepNo, current.StepNo-1, 1)
+	} else if st.StepNo > current.StepNo {
+		s.shift(st.RecipeID, current.StepNo+1, st.StepNo, -1)
+	}
+	s.steps[st.ID] = st
+
+	return st, nil
+}
+
+func (s *Store) ReorderSteps(ctx context.Context, householdID, recipeID int, stepIDs []int) ([]recipes.Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, recipeID); !ok {
+		return nil, recipes.ErrNotFound
+	}
+
+	current := s.stepsOf(recipeID)
+	pending := make(map[int]bool, len(current))
+	for _, st := range current {
+		pending[st.ID] = true
+	}
+	if len(stepIDs) != len(current) {
+		return nil, recipes.ErrInvalidStepOrder
+	}
+	for _, id := range stepIDs {
+		if !pending[id] {
+			return nil, recipes.ErrInvalidStepOrder
+		}
+		delete(pending, id)
+	}
+
+	for i, id := range stepIDs {
+		st := s.steps[id]
+		st.StepNo = i + 1
+		s.steps[id] = st
+	}
+
+	return s.stepsOf(recipeID), nil
+}
+
+func (s *Store) DeleteStep(ctx context.Context, householdID, recipeID, stepID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.getStep(householdID, recipeID, stepID)
+	if err != nil {
+		return err
+	}
+
+	n := len(s.stepsOf(recipeID))
+	delete(s.steps, stepID)
+	s.shift(recipeID, current.StepNo+1, n, -1)
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/tags"
+	"slices"
+)
+
+// getTag returns the household's tag with its recipe count. Callers hold
+// s.mu.
+func (s *Store) getTag(householdID, id int) (tags.Tag, error) {
+	t, ok := s.tags[id]
+	if !ok || t.HouseholdID != householdID {
+		return tags.Tag{}, tags.ErrNotFound
+	}
+
+	t.RecipeCount = 0
+	for _, tagIDs := range s.recipeTags {
+		if tagIDs[id] {
+			t.RecipeCount++
+		}
+	}
+
+	return t, nil
+}
+
+// tagNamed returns the id of the household's tag called name, or 0. Callers
+// hold s.mu.
+func (s *Store) tagNamed(householdID int, name string) int {
+	for id, t := range s.tags {
+		if t.HouseholdID == householdID && t.Name == name {
+			return id
+		}
+	}
+
+	return 0
+}
+
+// hasTags reports whether the recipe carries all of the named tags, or one
+// of them if anyTag is set. Callers hold s.mu.
+func (s *Store) hasTags(recipeID int, names []string, anyTag bool) bool {
+	if len(names) == 0 {
+		return true
+	}
+
+	n := 0
+	for id := range s.recipeTags[recipeID] {
+		if slices.Contains(names, s.tags[id].Name) {
+			n++
+		}
+	}
+	if anyTag {
+		return n > 0
+	}
+
+	return n == len(names)
+}
+
+// listTags returns the tags that match keep by name, with their recipe
+// counts. Callers hold s.mu.
+func (s *Store) listTags(keep func(tags.Tag) bool) []tags.Tag {
+	list := sorted(s.tags, keep, func(a, b tags.Tag) int { return cmp.Compare(a.Name, b.Name) })
+	for i, t := range list {
+		list[i], _ = s.getTag(t.HouseholdID, t.ID)
+	}
+
+	return list
+}
+
+func (s *Store) ListTags(ctx context.Context, householdID int) ([]tags.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listTags(func(t tags.Tag) bool { return t.HouseholdID == householdID }), nil
+}
+
+func (s *Store) GetTag(ctx context.Context, householdID, id int) (tags.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getTag(householdID, id)
+}
+
+func (s *Store) CreateTag(ctx context.Context, t tags.Tag) (tags.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tagNamed(t.HouseholdID, t.Name) != 0 {
+		return tags.Tag{}, tags.ErrExists
+	}
+
+	t.ID = s.nextID("tags")
+	t.CreatedAt = now()
+	t.RecipeCount = 0
+	s.tags[t.ID] = t
+
+	return t, nil
+}
+
+func (s *Store) UpdateTag(ctx context.Context, t tags.Tag) (tags.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.getTag(t.HouseholdID, t.ID)
+	if err != nil {
+		return tags.Tag{}, err
+	}
+	if id := s.tagNamed(t.HouseholdID, t.Name); id != 0 && id != t.ID {
+		return tags.Tag{}, tags.ErrExists
+	}
+
+	current.Name, current.Category = t.Name, t.Category
+	s.tags[t.ID] = current
+
+	return current, nil
+}
+
+func (s *Store) DeleteTag(ctx context.Context, householdID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.getTag(householdID, id); err != nil {
+		return err
+	}
+	delete(s.tags, id)
+	for _, tagIDs := range s.recipeTags {
+		delete(tagIDs, id)
+	}
+
+	return nil
+}
+
+func (s *Store) ListRecipeTags(ctx context.Context, householdID, recipeID int) ([]tags.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, recipeID); !ok {
+		return nil, tags.ErrRecipeNotFound
+	}
+
+	tagIDs := s.recipeTags[recipeID]
+	return s.listTags(func(t tags.Tag) bool { return tagIDs[t.ID] }), nil
+}
+
+func (s *Store) TagRecipe(ctx context.Context, householdID, recipeID int, name string) (tags.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, recipeID); !ok {
+		return tags.Tag{}, tags.ErrRecipeNotFound
+	}
+
+	id := s.tagNamed(householdID, name)
+	if id == 0 {
+		id = s.nextID("tags")
+		s.tags[id] = tags.Tag{ID: id, HouseholdID: householdID, Name: name, CreatedAt: now()}
+	}
+	if s.recipeTags[recipeID] == nil {
+		s.recipeTags[recipeID] = map[int]bool{}
+	}
+	s.recipeTags[recipeID][id] = true
+
+	return s.getTag(householdID, id)
+}
+
+func (s *Store) UntagRecipe(ctx context.Context, householdID, recipeID, tagID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.householdRecipe(householdID, recipeID); !ok {
+		return tags.ErrRecipeNotFound
+	}
+	if !s.recipeTags[recipeID][tagID] {
+		return tags.ErrNotFound
+	}
+	delete(s.recipeTags[recipeID], tagID)
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"meal_prep/internal/auth"
+)
+
+func (s *Store) CreateAPIToken(ctx context.Context, t auth.APIToken) (auth.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.ID = s.nextID("api_tokens")
+	t.CreatedAt = now()
+	t.LastUsedAt = nil
+	s.apiTokens[t.ID] = t
+
+	return t, nil
+}
+
+func (s *Store) ListAPITokens(ctx context.Context, userID int) ([]auth.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sorted(s.apiTokens,
+		func(t auth.APIToken) bool { return t.UserID == userID },
+		func(a, b auth.APIToken) int { return cmp.Compare(a.ID, b.ID) },
+	), nil
+}
+
+func (s *Store) RenameAPIToken(ctx context.Context, userID, id int, name string) (auth.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.apiTokens[id]
+	if !ok || t.UserID != userID {
+		return auth.APIToken{}, auth.ErrTokenNotFound
+	}
+	t.Name = name
+	s.apiTokens[id] = t
+
+	return t, nil
+}
+
+func (s *Store) DeleteAPIToken(ctx context.Context, userID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.apiTokens[id]; !ok || t.UserID != userID {
+		return auth.ErrTokenNotFound
+	}
+	delete(s.apiTokens, id)
+
+	return nil
+}
+
+func (s *Store) UseAPIToken(ctx context.Context, tokenHash string) (auth.User, auth.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.apiTokens {
+		if t.TokenHash != tokenHash {
+			continue
+		}
+		u, ok := s.users[t.UserID]
+		if !ok {
+			break
+		}
+
+		if used := now(); t.LastUsedAt == nil || used.Sub(*t.LastUsedAt) >= auth.LastUsedGranularity {
+			t.LastUsedAt = used
+			s.apiTokens[id] = t
+		}
+		return u, t, nil
+	}
+
+	return auth.User{}, auth.APIToken{}, auth.ErrTokenNotFound
+}
@@ -0,0 +1,100 @@
+package memstore
+
+import (
+	"context"
+	"meal_prep/internal/auth"
+	"time"
+)
+
+type session struct {
+	userID    int
+	expiresAt time.Time
+}
+
+// CreateUser also gives the user a household of their own, named after them.
+func (s *Store) CreateUser(ctx context.Context, u auth.User) (auth.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == u.Email {
+			return auth.User{}, auth.ErrEmailTaken
+		}
+	}
+
+	u.ID = s.nextID("users")
+	u.CreatedAt = now()
+	s.users[u.ID] = u
+	householdID := s.createHousehold(u.ID, u.Email)
+
+	// The first user takes over everything created before there were accounts
+	if len(s.users) == 1 {
+		owner := u.ID
+		for id, r := range s.recipes {
+			if r.HouseholdID == 0 {
+				r.OwnerID, r.HouseholdID = &owner, householdID
+				s.recipes[id] = r
+			}
+		}
+		for id, mp := range s.plans {
+			if mp.HouseholdID == 0 {
+				mp.OwnerID, mp.HouseholdID = &owner, householdID
+				s.plans[id] = mp
+			}
+		}
+	}
+
+	return u, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (auth.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return auth.User{}, auth.ErrUserNotFound
+}
+
+func (s *Store) CreateSession(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, sess := range s.sessions {
+		if sess.userID == userID && sess.expiresAt.Before(time.Now()) {
+			delete(s.sessions, hash)
+		}
+	}
+	s.sessions[tokenHash] = session{userID: userID, expiresAt: expiresAt}
+
+	return nil
+}
+
+func (s *Store) GetSessionUser(ctx context.Context, tokenHash string) (auth.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[tokenHash]
+	if !ok || !time.Now().Before(sess.expiresAt) {
+		return auth.User{}, auth.ErrSessionNotFound
+	}
+
+	u, ok := s.users[sess.userID]
+	if !ok {
+		return auth.User{}, auth.ErrSessionNotFound
+	}
+
+	return u, nil
+}
+
+func (s *Store) DeleteSession(ctx context.Context, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, tokenHash)
+	return nil
+}
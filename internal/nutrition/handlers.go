@@ -0,0 +1,28 @@
+package nutrition
+
+import (
+	"meal_prep/internal/words"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListFoodsHandler lists the foods ingredients can be mapped to, optionally
+// only those whose name or an alias contains ?q=.
+func ListFoodsHandler(c *gin.Context, foods *Database) {
+	q := words.Key(c.Query("q"))
+
+	list := []Food{}
+	for _, food := range foods.Foods() {
+		names := append([]string{food.Name}, food.Aliases...)
+		for _, name := range names {
+			if strings.Contains(words.Key(name), q) {
+				list = append(list, food)
+				break
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,114 @@
+package nutrition
+
+import (
+	"fmt"
+	"math"
+	"meal_prep/internal/ingredients"
+	"meal_prep/internal/units"
+	"meal_prep/internal/words"
+	"slices"
+)
+
+// Line is what one ingredient contributes. Ingredients that can't be
+// counted have a Problem instead of Facts and are left out of the totals.
+type Line struct {
+	IngredientID int      `json:"ingredient_id"`
+	Name         string   `json:"name"`
+	Food         string   `json:"food,omitempty"`
+	Grams        *float64 `json:"grams,omitempty"`
+	Facts        *Facts   `json:"facts,omitempty"`
+	Problem      string   `json:"problem,omitempty"`
+}
+
+// Ingredient works out the nutrients of one ingredient. Ranges count at
+// their midpoint.
+func (d *Database) Ingredient(ing ingredients.Ingredient) Line {
+	line := Line{IngredientID: ing.ID, Name: ing.Name}
+
+	food, ok := d.IngredientFood(ing)
+	switch {
+	case !ok && ing.Food != nil:
+		line.Problem = fmt.Sprintf("food %q is not in the nutrition database", *ing.Food)
+		return line
+	case !ok:
+		line.Problem = "no matching food in the nutrition database"
+		return line
+	}
+	line.Food = food.Name
+
+	if ing.QuantityValue == nil || ing.QuantityMax == nil {
+		line.Problem = "no numeric quantity"
+		return line
+	}
+	amount := (*ing.QuantityValue + *ing.QuantityMax) / 2
+
+	grams, problem := food.Grams(amount, ing.Unit)
+	if problem != "" {
+		line.Problem = problem
+		return line
+	}
+
+	facts := food.Per100g.Scale(grams / 100)
+	rounded := math.Round(grams*10) / 10
+	line.Grams, line.Facts = &rounded, &facts
+	return line
+}
+
+// IngredientFood is the food the ingredient was mapped to, or else the one
+// its name matches.
+func (d *Database) IngredientFood(ing ingredients.Ingredient) (Food, bool) {
+	if ing.Food != nil {
+		return d.Lookup(*ing.Food)
+	}
+
+	return d.Match(ing.Name)
+}
+
+// Grams converts an amount of the food to grams, or says why it can't.
+func (f Food) Grams(amount float64, unit *string) (float64, string) {
+	name := ""
+	if unit != nil {
+		name = *unit
+	}
+
+	u, known := units.Lookup(name)
+	switch {
+	case !known && slices.ContainsFunc(f.Pieces, func(p string) bool { return words.Key(p) == words.Key(name) }):
+		u = units.Unit{Dimension: units.Count, Factor: 1}
+	case !known:
+		return 0, fmt.Sprintf("unknown unit %q", name)
+	}
+
+	switch u.Dimension {
+	case units.Mass:
+		return amount * u.Factor, ""
+	case units.Volume:
+		if f.GramsPerML == nil {
+			return 0, fmt.Sprintf("no density for %s, give the amount by weight", f.Name)
+		}
+		return amount * u.Factor * *f.GramsPerML, ""
+	default:
+		if f.GramsPerPiece == nil {
+			return 0, fmt.Sprintf("no weight per piece for %s, give the amount by weight or volume", f.Name)
+		}
+		return amount * u.Factor * *f.GramsPerPiece, ""
+	}
+}
+
+// Sum adds up the ingredients of a recipe. complete is false if any of them
+// couldn't be counted.
+func (d *Database) Sum(list []ingredients.Ingredient) (total Facts, lines []Line, complete bool) {
+	lines = []Line{}
+	complete = true
+	for _, ing := range list {
+		line := d.Ingredient(ing)
+		if line.Facts != nil {
+			total = total.Add(*line.Facts)
+		} else {
+			complete = false
+		}
+		lines = append(lines, line)
+	}
+
+	return total, lines, complete
+}
@@ -0,0 +1,225 @@
+// Package nutrition works out what recipes contain from a local food
+// database: calories, protein, carbohydrates, fat and fiber per 100 g of
+// each food, with the densities and piece weights that turn "2 cups" or
+// "3 eggs" into grams. A database derived from USDA FoodData Central ships
+// with the server; a CSV in the same format can replace it.
+//
+// Ingredients are matched to foods by name, the longest run of words in the
+// ingredient's name that names a food winning, or by the food an ingredient
+// was mapped to explicitly.
+package nutrition
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"meal_prep/internal/words"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Facts are nutrient amounts: kcal for energy, grams for the rest.
+type Facts struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein_g"`
+	Carbs    float64 `json:"carbs_g"`
+	Fat      float64 `json:"fat_g"`
+	Fiber    float64 `json:"fiber_g"`
+}
+
+func (f Facts) Add(o Facts) Facts {
+	return Facts{f.Calories + o.Calories, f.Protein + o.Protein, f.Carbs + o.Carbs, f.Fat + o.Fat, f.Fiber + o.Fiber}
+}
+
+func (f Facts) Scale(k float64) Facts {
+	return Facts{f.Calories * k, f.Protein * k, f.Carbs * k, f.Fat * k, f.Fiber * k}
+}
+
+// MarshalJSON rounds to one decimal; sums are kept exact until then.
+func (f Facts) MarshalJSON() ([]byte, error) {
+	round := func(v float64) float64 { return math.Round(v*10) / 10 }
+
+	type plain Facts
+	return json.Marshal(plain{round(f.Calories), round(f.Protein), round(f.Carbs), round(f.Fat), round(f.Fiber)})
+}
+
+type Food struct {
+	Name          string   `json:"name"`
+	Aliases       []string `json:"aliases,omitempty"`
+	Per100g       Facts    `json:"per_100g"`
+	GramsPerML    *float64 `json:"grams_per_ml,omitempty"`
+	GramsPerPiece *float64 `json:"grams_per_piece,omitempty"`
+	Pieces        []string `json:"pieces,omitempty"` // unit words counting one piece, e.g. "clove"
+}
+
+// Database is a set of foods, read-only once loaded.
+type Database struct {
+	foods []Food
+	index map[string]int // food by words.Key of its name and aliases
+}
+
+var ErrInvalidCSV = errors.New("invalid food CSV")
+
+// csvColumns is the header a food CSV must start with.
+var csvColumns = []string{"name", "aliases", "calories", "protein", "carbs", "fat", "fiber", "grams_per_ml", "grams_per_piece", "pieces"}
+
+//go:embed foods.csv
+var bundledCSV string
+
+// Bundled is the database that ships with the server.
+var Bundled = sync.OnceValue(func() *Database {
+	d, err := Load(strings.NewReader(bundledCSV))
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+})
+
+// Open loads the CSV at path, or returns the bundled database for an empty
+// path.
+func Open(path string) (*Database, error) {
+	if path == "" {
+		return Bundled(), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+// Load reads a food CSV: a header of csvColumns, then one food per row.
+// Lists within a cell are separated by semicolons and lines starting with #
+// are comments.
+func Load(r io.Reader) (*Database, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSV, err)
+	}
+	if !slices.Equal(header, csvColumns) {
+		return nil, fmt.Errorf("%w: header must be %s", ErrInvalidCSV, strings.Join(csvColumns, ","))
+	}
+
+	d := &Database{index: map[string]int{}}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCSV, err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		food, err := parseFood(rec)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidCSV, line, err)
+		}
+
+		for _, name := range append([]string{food.Name}, food.Aliases...) {
+			key := words.Key(name)
+			if i, ok := d.index[key]; ok && i != len(d.foods) {
+				return nil, fmt.Errorf("%w: line %d: %q already names %s", ErrInvalidCSV, line, name, d.foods[i].Name)
+			}
+			d.index[key] = len(d.foods)
+		}
+		d.foods = append(d.foods, food)
+	}
+
+	return d, nil
+}
+
+func parseFood(rec []string) (Food, error) {
+	food := Food{
+		Name:    strings.TrimSpace(rec[0]),
+		Aliases: splitList(rec[1]),
+		Pieces:  splitList(rec[9]),
+	}
+	if words.Key(food.Name) == "" {
+		return food, errors.New("food without a name")
+	}
+
+	nutrients := []*float64{&food.Per100g.Calories, &food.Per100g.Protein, &food.Per100g.Carbs, &food.Per100g.Fat, &food.Per100g.Fiber}
+	for i, dst := range nutrients {
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[2+i]), 64)
+		if err != nil || v < 0 {
+			return food, fmt.Errorf("%s: %s must be a number, 0 or more", food.Name, csvColumns[2+i])
+		}
+		*dst = v
+	}
+
+	for i, dst := range []**float64{&food.GramsPerML, &food.GramsPerPiece} {
+		s := strings.TrimSpace(rec[7+i])
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 {
+			return food, fmt.Errorf("%s: %s must be a positive number", food.Name, csvColumns[7+i])
+		}
+		*dst = &v
+	}
+
+	return food, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// Foods returns every food, in the order the CSV lists them.
+func (d *Database) Foods() []Food {
+	return d.foods
+}
+
+// Lookup finds the food with the name or alias.
+func (d *Database) Lookup(name string) (Food, bool) {
+	i, ok := d.index[words.Key(name)]
+	if !ok {
+		return Food{}, false
+	}
+
+	return d.foods[i], true
+}
+
+// Match finds the food an ingredient name is about: the longest run of its
+// words that names a food, the later of equally long runs, since the food
+// usually comes last ("rice vinegar" is vinegar).
+func (d *Database) Match(ingredientName string) (Food, bool) {
+	split := words.Split(ingredientName)
+	for n := len(split); n > 0; n-- {
+		for start := len(split) - n; start >= 0; start-- {
+			if i, ok := d.index[strings.Join(split[start:start+n], " ")]; ok {
+				return d.foods[i], true
+			}
+		}
+	}
+
+	return Food{}, false
+}
@@ -0,0 +1,80 @@
+// Package patch implements JSON Merge Patch (RFC 7386) request bodies for the
+// flat resources served under /v1: a member that is absent leaves the column
+// alone, an explicit null clears it and any other value replaces it.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrNull                 = errors.New("field cannot be null")
+)
+
+// Field records whether a member was present in the patch document and
+// whether it was null, which a plain pointer cannot tell apart.
+type Field[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (f *Field[T]) UnmarshalJSON(b []byte) error {
+	f.Set = true
+	if string(b) == "null" {
+		f.Null = true
+		return nil
+	}
+
+	return json.Unmarshal(b, &f.Value)
+}
+
+// Apply patches a NOT NULL column; a null member yields ErrNull.
+func (f Field[T]) Apply(dst *T) error {
+	if !f.Set {
+		return nil
+	}
+	if f.Null {
+		return ErrNull
+	}
+
+	*dst = f.Value
+	return nil
+}
+
+// ApplyPtr patches a nullable column.
+func (f Field[T]) ApplyPtr(dst **T) {
+	if !f.Set {
+		return
+	}
+	if f.Null {
+		*dst = nil
+		return
+	}
+
+	v := f.Value
+	*dst = &v
+}
+
+// Bind decodes a merge patch document from the request body. PATCH requests
+// must declare application/merge-patch+json or plain application/json, which
+// share the same semantics for the flat objects we patch; PUT keeps accepting
+// whatever older clients already send.
+func Bind(c *gin.Context, obj any) error {
+	if ct := c.GetHeader("Content-Type"); ct != "" && c.Request.Method == http.MethodPatch {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != MergePatchContentType && mediaType != gin.MIMEJSON) {
+			return ErrUnsupportedMediaType
+		}
+	}
+
+	return c.ShouldBindJSON(obj)
+}
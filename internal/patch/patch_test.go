@@ -0,0 +1,75 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFieldUnmarshal(t *testing.T) {
+	type doc struct {
+		Name  Field[string] `json:"name"`
+		Count Field[int]    `json:"count"`
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want doc
+	}{
+		{"missing", `{}`, doc{}},
+		{"null", `{"name": null, "count": null}`, doc{
+			Name:  Field[string]{Set: true, Null: true},
+			Count: Field[int]{Set: true, Null: true},
+		}},
+		{"value", `{"name": "Soup", "count": 0}`, doc{
+			Name:  Field[string]{Set: true, Value: "Soup"},
+			Count: Field[int]{Set: true},
+		}},
+		{"empty string is a value", `{"name": ""}`, doc{Name: Field[string]{Set: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got doc
+			if err := json.Unmarshal([]byte(tt.body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	var d doc
+	if err := json.Unmarshal([]byte(`{"count": "three"}`), &d); err == nil {
+		t.Error("a value of the wrong type decoded without an error")
+	}
+}
+
+func TestFieldApply(t *testing.T) {
+	name := "Soup"
+	if err := (Field[string]{}).Apply(&name); err != nil || name != "Soup" {
+		t.Errorf("missing: %q, %v; want it left alone", name, err)
+	}
+	if err := (Field[string]{Set: true, Value: "Stew"}).Apply(&name); err != nil || name != "Stew" {
+		t.Errorf("value: %q, %v; want Stew", name, err)
+	}
+	if err := (Field[string]{Set: true, Null: true}).Apply(&name); !errors.Is(err, ErrNull) || name != "Stew" {
+		t.Errorf("null: %q, %v; want ErrNull and no change", name, err)
+	}
+
+	desc := &name
+	Field[string]{}.ApplyPtr(&desc)
+	if desc != &name {
+		t.Error("missing: pointer replaced")
+	}
+	Field[string]{Set: true, Value: "Thick"}.ApplyPtr(&desc)
+	if desc == nil || *desc != "Thick" || name != "Stew" {
+		t.Errorf("value: %v, want a new Thick", desc)
+	}
+	Field[string]{Set: true, Null: true}.ApplyPtr(&desc)
+	if desc != nil {
+		t.Errorf("null: %q, want cleared", *desc)
+	}
+}
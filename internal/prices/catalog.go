@@ -0,0 +1,180 @@
+package prices
+
+import (
+	"fmt"
+	"meal_prep/internal/ingredients"
+	"meal_prep/internal/nutrition"
+	"meal_prep/internal/units"
+	"meal_prep/internal/words"
+	"strings"
+)
+
+// Line is what one ingredient, or one amount on a shopping list, costs.
+// Amounts that can't be costed have a Problem instead of a Cost and are left
+// out of the totals.
+type Line struct {
+	IngredientID int    `json:"ingredient_id,omitempty"`
+	Name         string `json:"name"`
+	Item         string `json:"item,omitempty"` // the catalog item it was priced as
+	Store        string `json:"store,omitempty"`
+	PriceID      int    `json:"price_id,omitempty"`
+	Cost         *Money `json:"cost,omitempty"`
+	Problem      string `json:"problem,omitempty"`
+}
+
+// Catalog costs amounts of things from a household's prices. Where several
+// stores sell an item the cheapest is used, each at its price latest to take
+// effect on the day.
+type Catalog struct {
+	items    map[string][]Price // by words.Key of the item
+	maxWords int
+	foods    *nutrition.Database // densities and piece weights, for converting between mass, volume and count
+}
+
+// NewCatalog indexes the prices, only those of the store unless it is "".
+func NewCatalog(list []Price, store string, foods *nutrition.Database) *Catalog {
+	c := &Catalog{items: map[string][]Price{}, foods: foods}
+	for _, p := range list {
+		if store != "" && p.Store != Normalize(store) {
+			continue
+		}
+		key := words.Key(p.Item)
+		c.items[key] = append(c.items[key], p)
+		c.maxWords = max(c.maxWords, strings.Count(key, " ")+1)
+	}
+
+	return c
+}
+
+// Ingredient costs one ingredient of a recipe as written, on the day. Ranges
+// are costed at their upper end so the budget never falls short.
+func (c *Catalog) Ingredient(ing ingredients.Ingredient, day string) Line {
+	if ing.QuantityMax == nil {
+		return Line{IngredientID: ing.ID, Name: ing.Name, Problem: "no numeric quantity"}
+	}
+
+	food, hasFood := c.foods.IngredientFood(ing)
+	line := c.cost(ing.Name, *ing.QuantityMax, ing.Unit, day, food, hasFood)
+	line.IngredientID = ing.ID
+	return line
+}
+
+// Sum adds up what the ingredients of a recipe cost on the day. complete is
+// false if any of them couldn't be costed.
+func (c *Catalog) Sum(list []ingredients.Ingredient, day string) (total Money, lines []Line, complete bool) {
+	lines = []Line{}
+	complete = true
+	for _, ing := range list {
+		line := c.Ingredient(ing, day)
+		if line.Cost != nil {
+			total += *line.Cost
+		} else {
+			complete = false
+		}
+		lines = append(lines, line)
+	}
+
+	return total, lines, complete
+}
+
+// Cost prices an amount of the thing named on the day.
+func (c *Catalog) Cost(name string, amount float64, unit *string, day string) Line {
+	food, hasFood := c.foods.Match(name)
+	return c.cost(name, amount, unit, day, food, hasFood)
+}
+
+// cost prices an amount of the food, if there is one, which is what amounts
+// not in the price's dimension are converted through.
+func (c *Catalog) cost(name string, amount float64, unit *string, day string, food nutrition.Food, hasFood bool) Line {
+	line := Line{Name: name}
+
+	candidates, ok := c.match(name)
+	if !ok {
+		line.Problem = "no price for this item"
+		return line
+	}
+
+	// The latest price at each store, then the cheapest store
+	latest := map[string]Price{}
+	for _, p := range candidates {
+		if cur, ok := latest[p.Store]; p.EffectiveOn(day) && (!ok || p.EffectiveFrom > cur.EffectiveFrom) {
+			latest[p.Store] = p
+		}
+	}
+	if len(latest) == 0 {
+		line.Item = candidates[0].Item
+		line.Problem = "no price in effect on " + day
+		return line
+	}
+
+	for _, p := range latest {
+		cost, problem := convert(amount, unit, p, food, hasFood)
+		if problem != "" {
+			if line.Cost == nil {
+				line.Item, line.Problem = p.Item, problem
+			}
+			continue
+		}
+		if line.Cost == nil || cost < *line.Cost || (cost == *line.Cost && p.Store < line.Store) {
+			line.Item, line.Store, line.PriceID, line.Cost, line.Problem = p.Item, p.Store, p.ID, &cost, ""
+		}
+	}
+
+	return line
+}
+
+// match finds the prices of the item the name is about: the longest run of
+// its words that is a priced item, the later of equally long runs, as in
+// nutrition.Database.Match.
+func (c *Catalog) match(name string) ([]Price, bool) {
+	split := words.Split(name)
+	for n := min(len(split), c.maxWords); n > 0; n-- {
+		for start := len(split) - n; start >= 0; start-- {
+			if list, ok := c.items[strings.Join(split[start:start+n], " ")]; ok {
+				return list, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// convert works out what an amount costs at the price. Amounts in the
+// price's own dimension convert directly; others go through grams, with the
+// density or piece weight the nutrition database has for the food.
+func convert(amount float64, unit *string, p Price, food nutrition.Food, hasFood bool) (Money, string) {
+	priceUnit, _ := units.Lookup(unitName(p.Unit))
+	if u, ok := units.Lookup(unitName(unit)); ok && u.Dimension == priceUnit.Dimension {
+		return Money(amount * u.Factor / (p.Quantity * priceUnit.Factor) * p.Amount), ""
+	}
+
+	if !hasFood {
+		return 0, fmt.Sprintf("can't convert %s to the %s it is priced by", describeUnit(unit), priceUnit.Dimension)
+	}
+	grams, problem := food.Grams(amount, unit)
+	if problem != "" {
+		return 0, problem
+	}
+	pricedGrams, problem := food.Grams(p.Quantity, p.Unit)
+	if problem != "" {
+		return 0, problem
+	}
+
+	return Money(grams / pricedGrams * p.Amount), ""
+}
+
+func unitName(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func describeUnit(s *string) string {
+	if s == nil {
+		return "a count"
+	}
+
+	return fmt.Sprintf("%q", *s)
+}
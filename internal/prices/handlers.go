@@ -0,0 +1,277 @@
+package prices
+
+import (
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/patch"
+	"meal_prep/internal/units"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreatePriceRequest struct {
+	Item          string   `json:"item" binding:"required"`
+	Store         string   `json:"store" binding:"required"`
+	Price         *float64 `json:"price" binding:"required"`
+	Quantity      *float64 `json:"quantity"` // defaults to 1
+	Unit          *string  `json:"unit"`
+	EffectiveFrom *string  `json:"effective_from"` // defaults to today
+	EffectiveTo   *string  `json:"effective_to"`
+}
+
+type UpdatePriceRequest struct {
+	Item          patch.Field[string]  `json:"item"`
+	Store         patch.Field[string]  `json:"store"`
+	Price         patch.Field[float64] `json:"price"`
+	Quantity      patch.Field[float64] `json:"quantity"`
+	Unit          patch.Field[string]  `json:"unit"` // null prices per item
+	EffectiveFrom patch.Field[string]  `json:"effective_from"`
+	EffectiveTo   patch.Field[string]  `json:"effective_to"` // null leaves it open-ended
+}
+
+// cleanPrice normalizes p, reporting a message for the client if it can't be
+// used.
+func cleanPrice(p *Price) (string, bool) {
+	p.Item, p.Store = Normalize(p.Item), Normalize(p.Store)
+	switch {
+	case p.Item == "":
+		return "item is required", false
+	case p.Store == "":
+		return "store is required", false
+	case p.Amount < 0:
+		return "price cannot be negative", false
+	case p.Quantity <= 0:
+		return "quantity must be positive", false
+	}
+
+	if p.Unit != nil {
+		u, ok := units.Lookup(*p.Unit)
+		if !ok {
+			return "unknown unit " + strconv.Quote(*p.Unit), false
+		}
+		p.Unit = &u.Name
+		if u.Name == "each" {
+			p.Unit = nil
+		}
+	}
+
+	if _, err := time.Parse(time.DateOnly, p.EffectiveFrom); err != nil {
+		return "effective_from must be a YYYY-MM-DD date", false
+	}
+	if p.EffectiveTo != nil {
+		if _, err := time.Parse(time.DateOnly, *p.EffectiveTo); err != nil {
+			return "effective_to must be a YYYY-MM-DD date", false
+		}
+		if *p.EffectiveTo < p.EffectiveFrom {
+			return "effective_to is before effective_from", false
+		}
+	}
+
+	return "", true
+}
+
+func parsePriceID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid price id"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+// ListPricesHandler returns the household's prices, optionally only those
+// whose item contains ?item=, those at ?store= and those in effect ?on= a
+// day.
+func ListPricesHandler(c *gin.Context, store PriceStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	on, hasOn := c.GetQuery("on")
+	if _, err := time.Parse(time.DateOnly, on); hasOn && err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on must be a YYYY-MM-DD date"})
+		return
+	}
+
+	list, err := store.ListPrices(c.Request.Context(), households.ID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query prices"})
+		return
+	}
+
+	item, atStore := Normalize(c.Query("item")), Normalize(c.Query("store"))
+	kept := []Price{}
+	for _, p := range list {
+		if strings.Contains(p.Item, item) && (atStore == "" || p.Store == atStore) && (!hasOn || p.EffectiveOn(on)) {
+			kept = append(kept, p)
+		}
+	}
+
+	c.JSON(http.StatusOK, kept)
+}
+
+func GetPriceHandler(c *gin.Context, store PriceStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	id, ok := parsePriceID(c)
+	if !ok {
+		return
+	}
+
+	p, err := store.GetPrice(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "price not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+func CreatePriceHandler(c *gin.Context, store PriceStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	var req CreatePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	p := Price{
+		HouseholdID:   households.ID(c),
+		Item:          req.Item,
+		Store:         req.Store,
+		Amount:        *req.Price,
+		Quantity:      1,
+		Unit:          req.Unit,
+		EffectiveFrom: Today(),
+		EffectiveTo:   req.EffectiveTo,
+	}
+	if req.Quantity != nil {
+		p.Quantity = *req.Quantity
+	}
+	if req.EffectiveFrom != nil {
+		p.EffectiveFrom = *req.EffectiveFrom
+	}
+	if msg, ok := cleanPrice(&p); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	p, err := store.CreatePrice(c.Request.Context(), p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert price"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+func UpdatePriceHandler(c *gin.Context, store PriceStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	id, ok := parsePriceID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePriceRequest
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	// Load existing
+	p, err := store.GetPrice(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "price not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	// Apply patch
+	for _, f := range []struct {
+		name  string
+		field patch.Field[string]
+		dst   *string
+	}{
+		{"item", req.Item, &p.Item},
+		{"store", req.Store, &p.Store},
+		{"effective_from", req.EffectiveFrom, &p.EffectiveFrom},
+	} {
+		if err := f.field.Apply(f.dst); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f.name + " cannot be null"})
+			return
+		}
+	}
+	if err := req.Price.Apply(&p.Amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price cannot be null"})
+		return
+	}
+	if err := req.Quantity.Apply(&p.Quantity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity cannot be null"})
+		return
+	}
+	req.Unit.ApplyPtr(&p.Unit)
+	req.EffectiveTo.ApplyPtr(&p.EffectiveTo)
+	if msg, ok := cleanPrice(&p); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	p, err = store.UpdatePrice(c.Request.Context(), p)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "price not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+func DeletePriceHandler(c *gin.Context, store PriceStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	id, ok := parsePriceID(c)
+	if !ok {
+		return
+	}
+
+	err := store.DeletePrice(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "price not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+// Package prices keeps a household's grocery prices and costs ingredients
+// with them. A price is what a store charges for an amount of an item, e.g.
+// 2.49 for 1 kg of flour at the corner shop, from the day it took effect
+// until an optional last day. Ingredients are matched to items by name the
+// way they are matched to foods in package nutrition.
+package prices
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("price not found")
+
+type Price struct {
+	ID            int        `json:"id"`
+	HouseholdID   int        `json:"household_id"`
+	Item          string     `json:"item"`                   // e.g. "flour"
+	Store         string     `json:"store"`                  // e.g. "corner shop"
+	Amount        float64    `json:"price"`                  // for Quantity of Unit
+	Quantity      float64    `json:"quantity"`               // e.g. 500 for a 500 g bag
+	Unit          *string    `json:"unit,omitempty"`         // canonical; absent when priced per item
+	EffectiveFrom string     `json:"effective_from"`         // "YYYY-MM-DD"
+	EffectiveTo   *string    `json:"effective_to,omitempty"` // "YYYY-MM-DD", inclusive; open-ended when absent
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+}
+
+// EffectiveOn reports whether the price applies on the day, a "YYYY-MM-DD"
+// date.
+func (p Price) EffectiveOn(day string) bool {
+	return p.EffectiveFrom <= day && (p.EffectiveTo == nil || day <= *p.EffectiveTo)
+}
+
+// PriceStore is the persistence behind the price catalog. Every call is
+// scoped to a household; another household's price reads as missing.
+type PriceStore interface {
+	// ListPrices returns the household's prices by item and store, the
+	// latest to take effect first.
+	ListPrices(ctx context.Context, householdID int) ([]Price, error)
+	GetPrice(ctx context.Context, householdID, id int) (Price, error)
+	CreatePrice(ctx context.Context, p Price) (Price, error)
+	// UpdatePrice overwrites every column of p.ID, if it is in
+	// p.HouseholdID.
+	UpdatePrice(ctx context.Context, p Price) (Price, error)
+	DeletePrice(ctx context.Context, householdID, id int) error
+}
+
+// Today is the day prices are looked up for when none is given.
+func Today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+// Normalize is the form items and stores are kept in, so "Flour" and
+// " flour " are the same item.
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Money is an amount of the household's currency. Sums are kept exact and
+// only rounded to cents for the response.
+type Money float64
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, math.Round(float64(m)*100)/100, 'f', 2, 64), nil
+}
+
+// Budget compares a total with a cap. Embedded by pointer, its fields are
+// left out of responses when there is no cap.
+type Budget struct {
+	Cap        Money  `json:"budget"`
+	OverBudget bool   `json:"over_budget"`
+	Overrun    *Money `json:"overrun,omitempty"` // how far the total goes past the cap
+}
+
+// CheckBudget compares total with the cap, if there is one.
+func CheckBudget(total Money, budget *float64) *Budget {
+	if budget == nil {
+		return nil
+	}
+
+	b := &Budget{Cap: Money(*budget)}
+	if overrun := Money(math.Round(float64(total-b.Cap)*100) / 100); overrun > 0 {
+		b.OverBudget, b.Overrun = true, &overrun
+	}
+
+	return b
+}
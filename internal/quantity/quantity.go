@@ -0,0 +1,179 @@
+// Package quantity parses the free-text amounts recipes are written with.
+// It has no dependencies inside the module, so the database migrations
+// can backfill parsed quantities with the same rules the handlers use.
+package quantity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Quantity is the structured form of the free-text recipe_ingredients.quantity
+// column. Exact amounts have Min == Max; ranges such as "2-3" keep both ends.
+// Qualitative amounts ("a pinch", "to taste") carry no number at all.
+type Quantity struct {
+	Text        string
+	Min         float64
+	Max         float64
+	Qualitative bool
+}
+
+var ErrInvalid = errors.New("invalid quantity")
+
+var vulgarFractions = map[rune]float64{
+	'¼': 1.0 / 4, '½': 1.0 / 2, '¾': 3.0 / 4,
+	'⅐': 1.0 / 7, '⅑': 1.0 / 9, '⅒': 1.0 / 10,
+	'⅓': 1.0 / 3, '⅔': 2.0 / 3,
+	'⅕': 1.0 / 5, '⅖': 2.0 / 5, '⅗': 3.0 / 5, '⅘': 4.0 / 5,
+	'⅙': 1.0 / 6, '⅚': 5.0 / 6,
+	'⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+var qualitativeAmounts = map[string]bool{
+	"pinch": true, "a pinch": true, "dash": true, "a dash": true,
+	"splash": true, "a splash": true, "drizzle": true, "a drizzle": true,
+	"handful": true, "a handful": true, "sprinkle": true, "a sprinkle": true,
+	"some": true, "few": true, "a few": true, "several": true,
+	"to taste": true, "as needed": true, "as required": true, "optional": true,
+}
+
+// Parse understands whole numbers, decimals ("1.5", "1,5"), simple
+// and mixed fractions ("3/4", "1 1/2"), unicode vulgar fractions ("½", "1½"),
+// ranges ("2-3", "1 to 2", "2–3") and a fixed vocabulary of qualitative
+// amounts. Anything else is ErrInvalid.
+func Parse(s string) (Quantity, error) {
+	q := Quantity{Text: s}
+
+	text := strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	if text == "" {
+		return q, fmt.Errorf("%w: empty", ErrInvalid)
+	}
+
+	if qualitativeAmounts[text] {
+		q.Qualitative = true
+		return q, nil
+	}
+
+	text = strings.TrimPrefix(text, "about ")
+	text = strings.TrimPrefix(text, "approx. ")
+	text = strings.TrimPrefix(text, "~")
+
+	lo, hi, isRange := splitRange(text)
+	low, err := parseAmount(lo)
+	if err != nil {
+		return q, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+
+	high := low
+	if isRange {
+		if high, err = parseAmount(hi); err != nil {
+			return q, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		if high < low {
+			return q, fmt.Errorf("%w: range %q runs backwards", ErrInvalid, s)
+		}
+	}
+
+	q.Min, q.Max = low, high
+	return q, nil
+}
+
+func (q Quantity) IsRange() bool {
+	return !q.Qualitative && q.Min != q.Max
+}
+
+func splitRange(s string) (lo, hi string, ok bool) {
+	for _, sep := range []string{" to ", "-", "–", "—"} {
+		if lo, hi, ok = strings.Cut(s, sep); ok {
+			return strings.TrimSpace(lo), strings.TrimSpace(hi), true
+		}
+	}
+
+	return s, "", false
+}
+
+// parseAmount handles a single, non-range amount.
+func parseAmount(s string) (float64, error) {
+	if s == "" {
+		return 0, ErrInvalid
+	}
+
+	// A trailing vulgar fraction, optionally glued to a whole number: "1½"
+	var frac float64
+	if r := []rune(s); len(r) > 0 {
+		if v, ok := vulgarFractions[r[len(r)-1]]; ok {
+			frac = v
+			s = strings.TrimSpace(string(r[:len(r)-1]))
+			if s == "" {
+				return frac, nil
+			}
+		}
+	}
+
+	fields := strings.Fields(s)
+	var total float64
+	switch {
+	case len(fields) == 1:
+		v, err := parseNumber(fields[0])
+		if err != nil {
+			return 0, err
+		}
+		total = v
+	case len(fields) == 2 && frac == 0:
+		// Mixed fraction "1 1/2": whole part then a proper fraction
+		whole, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil || !strings.Contains(fields[1], "/") {
+			return 0, ErrInvalid
+		}
+		f, err := parseNumber(fields[1])
+		if err != nil || f >= 1 {
+			return 0, ErrInvalid
+		}
+		total = float64(whole) + f
+	default:
+		return 0, ErrInvalid
+	}
+
+	if frac != 0 && total != float64(int(total)) {
+		return 0, ErrInvalid
+	}
+
+	return total + frac, nil
+}
+
+// parseNumber accepts "2", "1.5", "1,5", "1,000" and "3/4".
+func parseNumber(s string) (float64, error) {
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.ParseUint(num, 10, 32)
+		if err != nil {
+			return 0, ErrInvalid
+		}
+		d, err := strconv.ParseUint(den, 10, 32)
+		if err != nil || d == 0 {
+			return 0, ErrInvalid
+		}
+		return float64(n) / float64(d), nil
+	}
+
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != '.' && r != ',' {
+			return 0, ErrInvalid
+		}
+	}
+
+	// A comma is a thousands separator when three digits follow it, otherwise
+	// it's a decimal comma
+	if i := strings.IndexByte(s, ','); i >= 0 && len(s)-i-1 == 3 {
+		s = strings.Replace(s, ",", "", 1)
+	}
+
+	v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil || v < 0 {
+		return 0, ErrInvalid
+	}
+
+	return v, nil
+}
@@ -0,0 +1,69 @@
+package quantity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		min, max float64
+	}{
+		{"2", 2, 2},
+		{" 2 ", 2, 2},
+		{"1.5", 1.5, 1.5},
+		{"1,5", 1.5, 1.5},
+		{"1,000", 1000, 1000},
+		{"3/4", 0.75, 0.75},
+		{"1 1/2", 1.5, 1.5},
+		{"2  3/4", 2.75, 2.75},
+		{"½", 0.5, 0.5},
+		{"1½", 1.5, 1.5},
+		{"1 ½", 1.5, 1.5},
+		{"⅔", 2.0 / 3, 2.0 / 3},
+		{"2-3", 2, 3},
+		{"2 - 3", 2, 3},
+		{"2–3", 2, 3},
+		{"1 to 2", 1, 2},
+		{"½-1", 0.5, 1},
+		{"1 1/2-2", 1.5, 2},
+		{"about 2", 2, 2},
+		{"~250", 250, 250},
+		{"3-3", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			q, err := Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Min != tt.min || q.Max != tt.max || q.Qualitative {
+				t.Errorf("Parse(%q) = %v-%v, qualitative %v; want %v-%v", tt.in, q.Min, q.Max, q.Qualitative, tt.min, tt.max)
+			}
+			if q.Text != tt.in {
+				t.Errorf("Text = %q, want the input", q.Text)
+			}
+		})
+	}
+}
+
+func TestParseQualitative(t *testing.T) {
+	for _, in := range []string{"a pinch", "Pinch", "to  taste", "A Few", "optional"} {
+		q, err := Parse(in)
+		if err != nil || !q.Qualitative || q.Min != 0 || q.Max != 0 {
+			t.Errorf("Parse(%q) = %+v, %v; want qualitative", in, q, err)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{
+		"", "   ", "two", "2 cups", "1/0", "-1", "3-2", "2-", "1 3/2", "1.5½", "1 2", "1/2/3", "a lot",
+	} {
+		if q, err := Parse(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) = %+v, %v; want ErrInvalid", in, q, err)
+		}
+	}
+}
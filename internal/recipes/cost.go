@@ -0,0 +1,76 @@
+package recipes
+
+import (
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/ingredients"
+	"meal_prep/internal/nutrition"
+	"meal_prep/internal/prices"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecipeCost struct {
+	RecipeID    int           `json:"recipe_id"`
+	Date        string        `json:"date"`            // the prices in effect on it were used
+	Store       string        `json:"store,omitempty"` // only its prices were used
+	Servings    *int          `json:"servings,omitempty"`
+	Total       prices.Money  `json:"total"`
+	PerServing  *prices.Money `json:"per_serving,omitempty"` // only with servings
+	Complete    bool          `json:"complete"`              // false if an ingredient couldn't be costed
+	Ingredients []prices.Line `json:"ingredients"`
+}
+
+// GetRecipeCostHandler costs the recipe as written with the prices in effect
+// ?date= (today by default), at the cheapest store or only at ?store=.
+func GetRecipeCostHandler(c *gin.Context, store RecipeStore, ingStore ingredients.IngredientStore, priceStore prices.PriceStore, foods *nutrition.Database) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	day := c.DefaultQuery("date", prices.Today())
+	if _, err := time.Parse(time.DateOnly, day); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be a YYYY-MM-DD date"})
+		return
+	}
+
+	r, err := store.GetRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	list, err := ingStore.ListIngredients(c.Request.Context(), r.HouseholdID, id, ingredients.AllIngredients)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
+		return
+	}
+
+	priceList, err := priceStore.ListPrices(c.Request.Context(), r.HouseholdID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query prices"})
+		return
+	}
+
+	cost := RecipeCost{RecipeID: r.ID, Date: day, Store: prices.Normalize(c.Query("store")), Servings: r.Servings}
+	cost.Total, cost.Ingredients, cost.Complete = prices.NewCatalog(priceList, cost.Store, foods).Sum(list, day)
+	if r.Servings != nil && *r.Servings > 0 {
+		per := cost.Total / prices.Money(*r.Servings)
+		cost.PerServing = &per
+	}
+
+	c.JSON(http.StatusOK, cost)
+}
@@ -0,0 +1,427 @@
+package recipes_test
+
+import (
+	"encoding/json"
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/diet"
+	"meal_prep/internal/ingredients"
+	"meal_prep/internal/listing"
+	"meal_prep/internal/recipes"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func createRecipe(cl *apitest.Client, body map[string]any) recipes.Recipe {
+	var r recipes.Recipe
+	cl.Create("/v1/recipes", body, &r)
+	return r
+}
+
+func TestRecipeCRUD(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		r := createRecipe(cl, map[string]any{"title": "Pancakes", "description": "Fluffy", "servings": 4})
+
+		var got recipes.Recipe
+		if code := cl.Do("GET", "/v1/recipes/"+strconv.Itoa(r.ID), nil, &got); code != http.StatusOK {
+			t.Fatalf("get: status %d", code)
+		}
+		if got.Title != "Pancakes" || got.Servings == nil || *got.Servings != 4 {
+			t.Errorf("get = %+v", got)
+		}
+
+		// A null description clears it, and untouched fields stay
+		var patched recipes.Recipe
+		if code := cl.Do("PATCH", "/v1/recipes/"+strconv.Itoa(r.ID), map[string]any{"title": "Crêpes", "description": nil}, &patched); code != http.StatusOK {
+			t.Fatalf("patch: status %d", code)
+		}
+		if patched.Title != "Crêpes" || patched.Description != nil || patched.Servings == nil || *patched.Servings != 4 {
+			t.Errorf("patch = %+v", patched)
+		}
+
+		if code := cl.Do("DELETE", "/v1/recipes/"+strconv.Itoa(r.ID), nil, nil); code != http.StatusOK {
+			t.Fatalf("delete: status %d", code)
+		}
+		if code := cl.Do("GET", "/v1/recipes/"+strconv.Itoa(r.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("get after delete: status %d, want 404", code)
+		}
+	})
+}
+
+func TestRecipesStayInTheirHousehold(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		alice := apitest.NewClient(t, s, "alice@example.com")
+		bob := apitest.NewClient(t, s, "bob@example.com")
+		r := createRecipe(alice, map[string]any{"title": "Soup"})
+		path := "/v1/recipes/" + strconv.Itoa(r.ID)
+
+		for _, req := range []struct {
+			method string
+			path   string
+			body   any
+		}{
+			{"GET", path, nil},
+			{"PATCH", path, map[string]any{"title": "Mine now"}},
+			{"DELETE", path, nil},
+			{"POST", path + "/steps", map[string]any{"instruction": "Stir"}},
+			{"POST", path + "/ingredients", map[string]any{"name": "salt"}},
+		} {
+			if code := bob.Do(req.method, req.path, req.body, nil); code != http.StatusNotFound {
+				t.Errorf("%s %s from another household: status %d, want 404", req.method, req.path, code)
+			}
+		}
+
+		var list []recipes.Recipe
+		bob.Do("GET", "/v1/recipes", nil, &list)
+		if len(list) != 0 {
+			t.Errorf("list = %+v, want none", list)
+		}
+		var got recipes.Recipe
+		alice.Do("GET", path, nil, &got)
+		if got.Title != "Soup" {
+			t.Errorf("title = %q after another household's patch", got.Title)
+		}
+	})
+}
+
+func TestSteps(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		r := createRecipe(cl, map[string]any{"title": "Toast"})
+		base := "/v1/recipes/" + strconv.Itoa(r.ID) + "/steps"
+
+		var slice, toast, butter, first recipes.Step
+		cl.Create(base, map[string]any{"instruction": "Slice bread"}, &slice)
+		cl.Create(base, map[string]any{"instruction": "Toast"}, &toast)
+		cl.Create(base, map[string]any{"instruction": "Butter"}, &butter)
+		cl.Create(base, map[string]any{"instruction": "Find bread", "step_no": 1}, &first)
+
+		assertSteps := func(want ...string) {
+			t.Helper()
+			var list []recipes.Step
+			if code := cl.Do("GET", base, nil, &list); code != http.StatusOK {
+				t.Fatalf("list: status %d", code)
+			}
+			got := []string{}
+			for i, st := range list {
+				if st.StepNo != i+1 {
+					t.Errorf("%q has step_no %d, want %d", st.Instruction, st.StepNo, i+1)
+				}
+				got = append(got, st.Instruction)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("steps = %q, want %q", got, want)
+			}
+		}
+		assertSteps("Find bread", "Slice bread", "Toast", "Butter")
+
+		cl.Do("PATCH", base+"/"+strconv.Itoa(butter.ID), map[string]any{"step_no": 2}, nil)
+		assertSteps("Find bread", "Butter", "Slice bread", "Toast")
+
+		w := cl.Request("DELETE", base+"/"+strconv.Itoa(slice.ID), nil)
+		if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+			t.Fatalf("delete: status %d, body %q, want 204 and none", w.Code, w.Body)
+		}
+		assertSteps("Find bread", "Butter", "Toast")
+		if code := cl.Do("DELETE", base+"/"+strconv.Itoa(slice.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("delete again: status %d, want 404", code)
+		}
+
+		order := []int{toast.ID, butter.ID, first.ID}
+		if code := cl.Do("PUT", base, map[string]any{"step_ids": order}, nil); code != http.StatusOK {
+			t.Fatalf("reorder: status %d", code)
+		}
+		assertSteps("Toast", "Butter", "Find bread")
+
+		if code := cl.Do("PUT", base, map[string]any{"step_ids": order[:2]}, nil); code != http.StatusBadRequest {
+			t.Errorf("reorder leaving a step out: status %d, want 400", code)
+		}
+	})
+}
+
+func TestScaledRecipe(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		r := createRecipe(cl, map[string]any{"title": "Pancakes", "servings": 2})
+		for _, ing := range []map[string]any{
+			{"name": "flour", "quantity": "400", "unit": "g"},
+			{"name": "milk", "quantity": "1/2-2/3", "unit": "cup"},
+			{"name": "sugar", "quantity": "1", "unit": "tsp"},
+			{"name": "salt", "quantity": "a pinch"},
+		} {
+			cl.Create("/v1/recipes/"+strconv.Itoa(r.ID)+"/ingredients", ing, nil)
+		}
+
+		var scaled recipes.ScaledRecipe
+		if code := cl.Do("GET", "/v1/recipes/"+strconv.Itoa(r.ID)+"/scaled?servings=6", nil, &scaled); code != http.StatusOK {
+			t.Fatalf("scaled: status %d", code)
+		}
+		if scaled.Factor != 3 || scaled.OriginalServings != 2 || len(scaled.Ingredients) != 4 {
+			t.Fatalf("scaled = %+v", scaled)
+		}
+
+		want := []struct{ quantity, unit string }{
+			{"1.2", "kg"},
+			{"1 1/2-2", "cup"},
+			{"1", "tbsp"},
+		}
+		for i, w := range want {
+			ing := scaled.Ingredients[i]
+			if !ing.Scaled || ing.Quantity == nil || *ing.Quantity != w.quantity || ing.Unit == nil || *ing.Unit != w.unit {
+				t.Errorf("%s = %+v, want %s %s", ing.Name, ing.Ingredient, w.quantity, w.unit)
+			}
+		}
+		if salt := scaled.Ingredients[3]; salt.Scaled || salt.Note == "" || *salt.Quantity != "a pinch" {
+			t.Errorf("salt = %+v, want it as written with a note", salt)
+		}
+
+		noServings := createRecipe(cl, map[string]any{"title": "Stew"})
+		if code := cl.Do("GET", "/v1/recipes/"+strconv.Itoa(noServings.ID)+"/scaled?servings=6", nil, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("scaling without servings: status %d, want 422", code)
+		}
+	})
+}
+
+func TestPublicRecipes(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		shared := createRecipe(cl, map[string]any{"title": "Bread", "is_public": true})
+		private := createRecipe(cl, map[string]any{"title": "Family stew"})
+		cl.Create("/v1/recipes/"+strconv.Itoa(shared.ID)+"/ingredients", map[string]any{"name": "flour", "quantity": "500", "unit": "g"}, nil)
+		cl.Create("/v1/recipes/"+strconv.Itoa(shared.ID)+"/steps", map[string]any{"instruction": "Knead"}, nil)
+
+		anon := apitest.Anonymous(t, s)
+		w := anon.Request("GET", "/public/recipes", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list: status %d", w.Code)
+		}
+		var list []map[string]any
+		json.Unmarshal(w.Body.Bytes(), &list)
+		if len(list) != 1 || list[0]["title"] != "Bread" {
+			t.Fatalf("list = %v, want Bread alone", list)
+		}
+		if _, ok := list[0]["household_id"]; ok {
+			t.Errorf("list shows household_id: %v", list[0])
+		}
+
+		var got map[string]any
+		if code := anon.Do("GET", "/public/recipes/"+strconv.Itoa(shared.ID), nil, &got); code != http.StatusOK {
+			t.Fatalf("get: status %d", code)
+		}
+		if _, ok := got["household_id"]; ok {
+			t.Errorf("get shows household_id: %v", got)
+		}
+		if ings, _ := got["ingredients"].([]any); len(ings) != 1 {
+			t.Errorf("ingredients = %v", got["ingredients"])
+		}
+		if steps, _ := got["steps"].([]any); len(steps) != 1 {
+			t.Errorf("steps = %v", got["steps"])
+		}
+
+		if code := anon.Do("GET", "/public/recipes/"+strconv.Itoa(private.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("private recipe: status %d, want 404", code)
+		}
+		if code := anon.Do("GET", "/v1/recipes/"+strconv.Itoa(shared.ID), nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("private route without a login: status %d, want 401", code)
+		}
+	})
+}
+
+func titles(list []recipes.Recipe) []string {
+	out := []string{}
+	for _, r := range list {
+		out = append(out, r.Title)
+	}
+	return out
+}
+
+func TestSearchRecipes(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		createRecipe(cl, map[string]any{"title": "Tomato soup"})
+		createRecipe(cl, map[string]any{"title": "Pancakes", "description": "<b>With</b> tomatoes? No."})
+		createRecipe(cl, map[string]any{"title": "Porridge"})
+
+		var found []recipes.Recipe
+		if code := cl.Do("GET", "/v1/recipes?q=tomat", nil, &found); code != http.StatusOK {
+			t.Fatalf("search: status %d", code)
+		}
+		if got := titles(found); len(got) != 2 || got[0] != "Tomato soup" {
+			t.Errorf("search = %q, want Tomato soup first of two", got)
+		}
+		for _, r := range found {
+			if !strings.Contains(r.Snippet, "<mark>") || strings.Contains(r.Snippet, "<b>") {
+				t.Errorf("%q snippet = %q, want escaped text with a match marked", r.Title, r.Snippet)
+			}
+		}
+
+		if code := cl.Do("GET", "/v1/recipes?q=tomat&tag=soup", nil, nil); code != http.StatusBadRequest {
+			t.Errorf("search with a tag: status %d, want 400", code)
+		}
+		if code := cl.Do("GET", "/v1/recipes?q=%25_", nil, nil); code != http.StatusBadRequest {
+			t.Errorf("search without words: status %d, want 400", code)
+		}
+	})
+}
+
+// Snippets mark matches with control characters, so searchable text can't
+// have any.
+func TestSearchableTextIsPlain(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		r := createRecipe(cl, map[string]any{"title": "Soup", "description": "Hot\nand\tthick"})
+		path := "/v1/recipes/" + strconv.Itoa(r.ID)
+		var step recipes.Step
+		cl.Create(path+"/steps", map[string]any{"instruction": "Stir"}, &step)
+
+		tests := []struct {
+			method string
+			path   string
+			body   map[string]any
+		}{
+			{"POST", "/v1/recipes", map[string]any{"title": "Soup" + recipes.MatchStart}},
+			{"PATCH", path, map[string]any{"description": recipes.MatchEnd + "hot"}},
+			{"POST", path + "/steps", map[string]any{"instruction": "Stir\x00"}},
+			{"PATCH", path + "/steps/" + strconv.Itoa(step.ID), map[string]any{"instruction": recipes.MatchStart}},
+			{"POST", path + "/ingredients", map[string]any{"name": recipes.MatchStart + "salt" + recipes.MatchEnd}},
+		}
+		for _, tt := range tests {
+			if code := cl.Do(tt.method, tt.path, tt.body, nil); code != http.StatusBadRequest {
+				t.Errorf("%s %s with %q: status %d, want 400", tt.method, tt.path, tt.body, code)
+			}
+		}
+	})
+}
+
+func TestListRecipes(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		for _, r := range []map[string]any{
+			{"title": "Apple pie", "prep_time": 30, "cook_time": 45, "servings": 8},
+			{"title": "Banana bread", "prep_time": 15, "cook_time": 50, "servings": 10},
+			{"title": "Cherry tart", "prep_time": 20, "servings": 6},
+		} {
+			createRecipe(cl, r)
+		}
+
+		list := func(query string) ([]string, string) {
+			t.Helper()
+			w := cl.Request("GET", "/v1/recipes"+query, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("list%s: status %d", query, w.Code)
+			}
+			var page []recipes.Recipe
+			json.Unmarshal(w.Body.Bytes(), &page)
+			return titles(page), w.Header().Get(listing.NextCursorHeader)
+		}
+
+		first, cursor := list("?sort=title&limit=2")
+		if !slices.Equal(first, []string{"Apple pie", "Banana bread"}) || cursor == "" {
+			t.Fatalf("first page = %q, cursor %q", first, cursor)
+		}
+		// The cursor carries the sort, so it's enough on its own
+		second, next := list("?limit=2&cursor=" + cursor)
+		if !slices.Equal(second, []string{"Cherry tart"}) || next != "" {
+			t.Errorf("second page = %q, cursor %q", second, next)
+		}
+
+		if got, _ := list(""); !slices.Equal(got, []string{"Cherry tart", "Banana bread", "Apple pie"}) {
+			t.Errorf("newest first = %q", got)
+		}
+		if got, _ := list("?sort=-total_time"); !slices.Equal(got, []string{"Apple pie", "Banana bread", "Cherry tart"}) {
+			t.Errorf("?sort=-total_time = %q", got)
+		}
+		if got, _ := list("?max_total_time=60&min_servings=7"); len(got) != 0 {
+			t.Errorf("?max_total_time=60&min_servings=7 = %q, want none", got)
+		}
+		if got, _ := list("?min_servings=7&sort=servings"); !slices.Equal(got, []string{"Apple pie", "Banana bread"}) {
+			t.Errorf("?min_servings=7 = %q", got)
+		}
+
+		for _, query := range []string{
+			"?sort=calories",
+			"?limit=0",
+			"?sort=-title&cursor=" + cursor,
+			"?cursor=not-a-cursor",
+			"?min_servings=-1",
+		} {
+			if code := cl.Do("GET", "/v1/recipes"+query, nil, nil); code != http.StatusBadRequest {
+				t.Errorf("list%s: status %d, want 400", query, code)
+			}
+		}
+	})
+}
+
+func TestListRecipesByTag(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		pie := createRecipe(cl, map[string]any{"title": "Apple pie"})
+		sorbet := createRecipe(cl, map[string]any{"title": "Sorbet"})
+		createRecipe(cl, map[string]any{"title": "Soup"})
+		for _, tag := range []struct {
+			recipeID int
+			name     string
+		}{{pie.ID, "Dessert"}, {sorbet.ID, "Dessert"}, {sorbet.ID, "Vegan"}} {
+			cl.Do("POST", "/v1/recipes/"+strconv.Itoa(tag.recipeID)+"/tags", map[string]any{"name": tag.name}, nil)
+		}
+
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"?tag=dessert&sort=title", []string{"Apple pie", "Sorbet"}},
+			{"?tag=DESSERT&tag=vegan", []string{"Sorbet"}},
+			{"?tag=vegan&tag=dessert&tag_match=any&sort=title", []string{"Apple pie", "Sorbet"}},
+			{"?tag=weeknight", []string{}},
+		}
+		for _, tt := range tests {
+			var list []recipes.Recipe
+			if code := cl.Do("GET", "/v1/recipes"+tt.query, nil, &list); code != http.StatusOK {
+				t.Fatalf("list%s: status %d", tt.query, code)
+			}
+			if got := titles(list); !slices.Equal(got, tt.want) {
+				t.Errorf("list%s = %q, want %q", tt.query, got, tt.want)
+			}
+		}
+		if code := cl.Do("GET", "/v1/recipes?tag=vegan&tag_match=some", nil, nil); code != http.StatusBadRequest {
+			t.Errorf("unknown tag_match: status %d, want 400", code)
+		}
+	})
+}
+
+func TestRecipeAllergensAndDiets(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		r := createRecipe(cl, map[string]any{"title": "Shortbread"})
+		path := "/v1/recipes/" + strconv.Itoa(r.ID)
+
+		if len(r.Allergens) != 0 || len(r.Diets) != 0 {
+			t.Errorf("without ingredients: allergens %v, diets %v, want none", r.Allergens, r.Diets)
+		}
+
+		var butter ingredients.Ingredient
+		cl.Create(path+"/ingredients", map[string]any{"name": "butter"}, &butter)
+		cl.Create(path+"/ingredients", map[string]any{"name": "flour"}, nil)
+
+		var got recipes.Recipe
+		cl.Do("GET", path, nil, &got)
+		if want := []diet.Allergen{diet.Gluten, diet.Dairy}; !slices.Equal(got.Allergens, want) {
+			t.Errorf("allergens = %v, want %v", got.Allergens, want)
+		}
+		if want := []diet.Diet{diet.Vegetarian, diet.Pescatarian}; !slices.Equal(got.Diets, want) {
+			t.Errorf("diets = %v, want %v", got.Diets, want)
+		}
+
+		// Lists carry the profile too, and it follows the ingredients
+		cl.Do("PATCH", "/v1/ingredients/"+strconv.Itoa(butter.ID), map[string]any{"name": "vegan butter"}, nil)
+		var list []recipes.Recipe
+		cl.Do("GET", "/v1/recipes", nil, &list)
+		if len(list) != 1 || !slices.Equal(list[0].Allergens, []diet.Allergen{diet.Gluten}) ||
+			!slices.Equal(list[0].Diets, []diet.Diet{diet.Vegetarian, diet.Vegan, diet.Pescatarian}) {
+			t.Errorf("list = %+v, want gluten only and every diet", list)
+		}
+	})
+}
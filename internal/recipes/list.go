@@ -0,0 +1,107 @@
+package recipes
+
+import (
+	"fmt"
+	"meal_prep/internal/listing"
+	"meal_prep/internal/tags"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecipeQuery is a page of GET /v1/recipes. Filters left nil don't apply;
+// recipes without servings never match a servings filter.
+type RecipeQuery struct {
+	listing.Query
+	MaxTotalTime *int // prep plus cook minutes, a missing time counting as 0
+	MinServings  *int
+	MaxServings  *int
+	CreatedFrom  *string  // YYYY-MM-DD, inclusive
+	CreatedTo    *string  // YYYY-MM-DD, inclusive
+	Tags         []string // normalized tag names the recipe must carry
+	AnyTag       bool     // one of Tags is enough
+}
+
+// RecipeSorts are the orders of GET /v1/recipes; "created", newest first, is
+// the default.
+var RecipeSorts = listing.Sorts[Recipe]{
+	"created":    func(r Recipe) any { return r.ID },
+	"title":      func(r Recipe) any { return r.Title },
+	"total_time": func(r Recipe) any { return r.TotalTime() },
+	"servings":   func(r Recipe) any { return deref(r.Servings) },
+}
+
+func recipeID(r Recipe) int { return r.ID }
+
+// TotalTime is prep plus cook time in minutes, a missing time counting as 0.
+func (r Recipe) TotalTime() int {
+	return deref(r.PrepTime) + deref(r.CookTime)
+}
+
+func deref(p *int) int {
+	if p == nil {
+		return 0
+	}
+
+	return *p
+}
+
+// recipeListParams are the query parameters parseRecipeQuery reads, none of
+// which apply to a ?q= search.
+var recipeListParams = []string{
+	"limit", "cursor", "sort", "max_total_time", "min_servings", "max_servings",
+	"created_from", "created_to", "tag", "tag_match",
+}
+
+// searchOnly rejects list parameters given alongside ?q=, rather than
+// returning results they would have filtered out.
+func searchOnly(c *gin.Context) error {
+	for _, name := range recipeListParams {
+		if _, ok := c.GetQuery(name); ok {
+			return fmt.Errorf("%w: %s can't be combined with q", listing.ErrInvalid, name)
+		}
+	}
+
+	return nil
+}
+
+func parseRecipeQuery(c *gin.Context) (RecipeQuery, error) {
+	var (
+		q   RecipeQuery
+		err error
+	)
+	if q.Query, err = listing.Parse(c, RecipeSorts, "-created"); err != nil {
+		return q, err
+	}
+	if q.MaxTotalTime, err = listing.Int(c, "max_total_time"); err != nil {
+		return q, err
+	}
+	if q.MinServings, err = listing.Int(c, "min_servings"); err != nil {
+		return q, err
+	}
+	if q.MaxServings, err = listing.Int(c, "max_servings"); err != nil {
+		return q, err
+	}
+	if q.CreatedFrom, err = listing.Date(c, "created_from"); err != nil {
+		return q, err
+	}
+	if q.CreatedTo, err = listing.Date(c, "created_to"); err != nil {
+		return q, err
+	}
+
+	// ?tag= repeats; every tag must match unless ?tag_match=any
+	for _, name := range c.QueryArray("tag") {
+		if name = tags.Normalize(name); name != "" && !slices.Contains(q.Tags, name) {
+			q.Tags = append(q.Tags, name)
+		}
+	}
+	switch c.DefaultQuery("tag_match", "all") {
+	case "all":
+	case "any":
+		q.AnyTag = true
+	default:
+		return q, fmt.Errorf("%w: tag_match must be all or any", listing.ErrInvalid)
+	}
+
+	return q, nil
+}
@@ -0,0 +1,58 @@
+package recipes
+
+import (
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/ingredients"
+	"meal_prep/internal/nutrition"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecipeNutrition struct {
+	RecipeID    int              `json:"recipe_id"`
+	Servings    *int             `json:"servings,omitempty"`
+	Total       nutrition.Facts  `json:"total"`
+	PerServing  *nutrition.Facts `json:"per_serving,omitempty"` // only with servings
+	Complete    bool             `json:"complete"`              // false if an ingredient couldn't be counted
+	Ingredients []nutrition.Line `json:"ingredients"`
+}
+
+func GetRecipeNutritionHandler(c *gin.Context, store RecipeStore, ingStore ingredients.IngredientStore, foods *nutrition.Database) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	r, err := store.GetRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	list, err := ingStore.ListIngredients(c.Request.Context(), r.HouseholdID, id, ingredients.AllIngredients)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
+		return
+	}
+
+	n := RecipeNutrition{RecipeID: r.ID, Servings: r.Servings}
+	n.Total, n.Ingredients, n.Complete = foods.Sum(list)
+	if r.Servings != nil && *r.Servings > 0 {
+		per := n.Total.Scale(1 / float64(*r.Servings))
+		n.PerServing = &per
+	}
+
+	c.JSON(http.StatusOK, n)
+}
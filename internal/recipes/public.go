@@ -0,0 +1,76 @@
+package recipes
+
+import (
+	"errors"
+	"meal_prep/internal/ingredients"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publicSummary is a recipe as the /public routes show it. HouseholdID is
+// never set; it shadows the recipe's own so who shares a recipe isn't given
+// away.
+type publicSummary struct {
+	Recipe
+	HouseholdID *int `json:"household_id,omitempty"`
+}
+
+// PublicRecipe is what the read-only /public routes serve: the recipe with
+// everything needed to cook it.
+type PublicRecipe struct {
+	publicSummary
+	Ingredients []ingredients.Ingredient `json:"ingredients"`
+}
+
+func ListPublicRecipesHandler(c *gin.Context, store RecipeStore) {
+	recipes, err := store.ListPublicRecipes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query recipes"})
+		return
+	}
+
+	list := make([]publicSummary, 0, len(recipes))
+	for _, r := range recipes {
+		list = append(list, publicSummary{Recipe: r})
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func GetPublicRecipeHandler(c *gin.Context, store RecipeStore, ingStore ingredients.IngredientStore) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	// Private recipes are reported as missing rather than forbidden so their
+	// ids can't be probed
+	r, err := store.GetPublicRecipe(c.Request.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	pr := PublicRecipe{publicSummary: publicSummary{Recipe: r}, Ingredients: []ingredients.Ingredient{}}
+	if pr.Steps, err = store.ListSteps(c.Request.Context(), r.HouseholdID, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query steps"})
+		return
+	}
+
+	list, err := ingStore.ListIngredients(c.Request.Context(), r.HouseholdID, id, ingredients.AllIngredients)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
+		return
+	}
+	pr.Ingredients = append(pr.Ingredients, list...)
+
+	c.JSON(http.StatusOK, pr)
+}
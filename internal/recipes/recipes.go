@@ -1,8 +1,14 @@
 package recipes
 
 import (
-	"database/sql"
+	"errors"
 	"log"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/diet"
+	"meal_prep/internal/households"
+	"meal_prep/internal/listing"
+	"meal_prep/internal/patch"
+	"meal_prep/internal/words"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,13 +18,21 @@ import (
 
 type Recipe struct {
 	ID          int        `json:"id"`
+	HouseholdID int        `json:"household_id"`
+	OwnerID     *int       `json:"-"` // the author; nil once their account is gone
 	Title       string     `json:"title"`
 	Description *string    `json:"description,omitempty"`
 	Servings    *int       `json:"servings,omitempty"`
 	PrepTime    *int       `json:"prep_time,omitempty"`
 	CookTime    *int       `json:"cook_time,omitempty"`
+	IsPublic    bool       `json:"is_public"`
 	CreatedAt   *time.Time `json:"created_at,omitempty"`
 	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Steps       []Step     `json:"steps,omitempty"`   // only with ?include=steps
+	Snippet     string     `json:"snippet,omitempty"` // only in ?q= results: HTML, matches in <mark>
+
+	// Allergens and diets, worked out from the ingredient names
+	diet.Profile
 }
 
 type CreateRecipeRequest struct {
@@ -27,41 +41,69 @@ type CreateRecipeRequest struct {
 	Servings    *int    `json:"servings"`
 	PrepTime    *int    `json:"prep_time"`
 	CookTime    *int    `json:"cook_time"`
+	IsPublic    bool    `json:"is_public"`
 }
 
-func ListRecipesHandler(c *gin.Context, db *sql.DB) {
-	rows, err := db.Query(`
-SELECT id, title, description, servings, prep_time, cook_time, created_at, updated_at
-FROM recipes
-ORDER BY created_at DESC
-LIMIT 100
-	`)
+type UpdateRecipeRequest struct {
+	Title       patch.Field[string] `json:"title"`       // optional
+	Description patch.Field[string] `json:"description"` // optional, null clears
+	Servings    patch.Field[int]    `json:"servings"`    // optional, null clears
+	PrepTime    patch.Field[int]    `json:"prep_time"`   // optional, null clears
+	CookTime    patch.Field[int]    `json:"cook_time"`   // optional, null clears
+	IsPublic    patch.Field[bool]   `json:"is_public"`   // optional
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query recipes"})
+func ListRecipesHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Viewer) {
 		return
 	}
 
-	defer rows.Close()
+	// ?q= searches titles, descriptions, ingredients and steps, best match
+	// first; the list parameters below don't apply to it
+	if q, ok := c.GetQuery("q"); ok {
+		if err := searchOnly(c); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		terms := SearchTerms(q)
+		if len(terms) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain a word to search for"})
+			return
+		}
 
-	var recipes []Recipe
-	for rows.Next() {
-		var r Recipe
-		if err := rows.Scan(
-			&r.ID, &r.Title, &r.Description, &r.Servings,
-			&r.PrepTime, &r.CookTime, &r.CreatedAt, &r.UpdatedAt,
-		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "scan error"})
+		found, err := store.SearchRecipes(c.Request.Context(), households.ID(c), terms)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search recipes"})
 			return
 		}
+		for i := range found {
+			found[i].Snippet = highlight(found[i].Snippet)
+		}
 
-		recipes = append(recipes, r)
+		c.JSON(http.StatusOK, found)
+		return
 	}
 
-	c.JSON(http.StatusOK, recipes)
+	q, err := parseRecipeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recipes, err := store.ListRecipes(c.Request.Context(), households.ID(c), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query recipes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listing.Page(c, q.Query, recipes, RecipeSorts, recipeID))
 }
 
-func GetRecipeHandler(c *gin.Context, db *sql.DB) {
+func GetRecipeHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
@@ -69,17 +111,8 @@ func GetRecipeHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	var r Recipe
-	err = db.QueryRow(`
-SELECT id, title, description, servings, prep_time, cook_time, created_at, updated_at
-FROM recipes
-WHERE id = ?
-	`, id).Scan(
-		&r.ID, &r.Title, &r.Description, &r.Servings,
-		&r.PrepTime, &r.CookTime, &r.CreatedAt, &r.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
+	r, err := store.GetRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -87,51 +120,154 @@ WHERE id = ?
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
+
+	if c.Query("include") == "steps" {
+		r.Steps, err = store.ListSteps(c.Request.Context(), households.ID(c), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query steps"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, r)
 }
 
-func CreateRecipeHandler(c *gin.Context, db *sql.DB) {
+func CreateRecipeHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	var req CreateRecipeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
+	if !words.Plain(req.Title) || (req.Description != nil && !words.Plain(*req.Description)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title and description cannot contain control characters"})
+		return
+	}
 
-	res, err := db.Exec(`
-		INSERT INTO recipes (title, description, servings, prep_time, cook_time)
-		VALUES (?, ?, ?, ?, ?)
-	`, req.Title, req.Description, req.Servings, req.PrepTime, req.CookTime)
+	owner := auth.UserID(c)
+	r, err := store.CreateRecipe(c.Request.Context(), Recipe{
+		HouseholdID: households.ID(c),
+		OwnerID:     &owner,
+		Title:       req.Title,
+		Description: req.Description,
+		Servings:    req.Servings,
+		PrepTime:    req.PrepTime,
+		CookTime:    req.CookTime,
+		IsPublic:    req.IsPublic,
+	})
 	if err != nil {
 		log.Println(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert"})
 		return
 	}
 
-	id64, err := res.LastInsertId()
+	c.JSON(http.StatusCreated, r)
+}
+
+// replaceField turns an optional PUT member into a patch that sets or clears it.
+func replaceField[T any](v *T) patch.Field[T] {
+	if v == nil {
+		return patch.Field[T]{Set: true, Null: true}
+	}
+
+	return patch.Field[T]{Set: true, Value: *v}
+}
+
+// UpdateRecipeHandler serves both PUT, which replaces every field, and PATCH,
+// which only touches the fields present in the body.
+func UpdateRecipeHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req UpdateRecipeRequest
+	if c.Request.Method == http.MethodPut {
+		var full CreateRecipeRequest
+		if err := c.ShouldBindJSON(&full); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+
+		// A PUT carries every field, so omitted ones end up cleared
+		req = UpdateRecipeRequest{
+			Title:       patch.Field[string]{Set: true, Value: full.Title},
+			Description: replaceField(full.Description),
+			Servings:    replaceField(full.Servings),
+			PrepTime:    replaceField(full.PrepTime),
+			CookTime:    replaceField(full.CookTime),
+			IsPublic:    patch.Field[bool]{Set: true, Value: full.IsPublic},
+		}
+	} else if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	// Load existing
+	current, err := store.GetRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get id"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
-	id := int(id64)
 
-	// Return the created recipe (simple fetch)
-	var r Recipe
-	err = db.QueryRow(`
-		SELECT id, title, description, servings, prep_time, cook_time, created_at, updated_at
-		FROM recipes
-	`, id).Scan(
-		&r.ID, &r.Title, &r.Description, &r.Servings,
-		&r.PrepTime, &r.CookTime, &r.CreatedAt, &r.UpdatedAt,
-	)
+	// Apply patch
+	if err := req.Title.Apply(&current.Title); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title cannot be null"})
+		return
+	}
+	req.Description.ApplyPtr(&current.Description)
+	req.Servings.ApplyPtr(&current.Servings)
+	req.PrepTime.ApplyPtr(&current.PrepTime)
+	req.CookTime.ApplyPtr(&current.CookTime)
+	if err := req.IsPublic.Apply(&current.IsPublic); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "is_public cannot be null"})
+		return
+	}
+
+	if current.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must not be empty"})
+		return
+	}
+	if !words.Plain(current.Title) || (current.Description != nil && !words.Plain(*current.Description)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title and description cannot contain control characters"})
+		return
+	}
+
+	r, err := store.UpdateRecipe(c.Request.Context(), current)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "created but failed to reload"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, r)
+	c.JSON(http.StatusOK, r)
 }
 
-func DeleteRecipeHandler(c *gin.Context, db *sql.DB) {
+func DeleteRecipeHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -139,24 +275,16 @@ func DeleteRecipeHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	// Execute delete
-	res, err := db.Exec(`DELETE FROM recipes WHERE id = ?`, id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	err = store.DeleteRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
 		return
 	}
-
-	rows, err := res.RowsAffected()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if rows == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"status":   "deleted",
 		"recipeId": id,
@@ -0,0 +1,70 @@
+package recipes
+
+import (
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/ingredients"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScaledRecipe struct {
+	Recipe
+	OriginalServings int                            `json:"original_servings"`
+	Factor           float64                        `json:"factor"`
+	Ingredients      []ingredients.ScaledIngredient `json:"ingredients"`
+}
+
+func GetScaledRecipeHandler(c *gin.Context, store RecipeStore, ingStore ingredients.IngredientStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	servings, err := strconv.Atoi(c.Query("servings"))
+	if err != nil || servings <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "servings must be a positive integer"})
+		return
+	}
+
+	r, err := store.GetRecipe(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if r.Servings == nil || *r.Servings <= 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "recipe has no servings to scale from"})
+		return
+	}
+
+	list, err := ingStore.ListIngredients(c.Request.Context(), r.HouseholdID, id, ingredients.AllIngredients)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ingredients"})
+		return
+	}
+
+	scaled := ScaledRecipe{
+		Recipe:           r,
+		OriginalServings: *r.Servings,
+		Factor:           float64(servings) / float64(*r.Servings),
+		Ingredients:      []ingredients.ScaledIngredient{},
+	}
+	scaled.Servings = &servings
+	for _, ing := range list {
+		scaled.Ingredients = append(scaled.Ingredients, ing.Scale(scaled.Factor))
+	}
+
+	c.JSON(http.StatusOK, scaled)
+}
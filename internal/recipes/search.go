@@ -0,0 +1,143 @@
+package recipes
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// MatchStart and MatchEnd surround the matched words in a snippet as stores
+// return it. Handlers only accept searchable text that is words.Plain, so the
+// markers can't come from users, and the snippet can be escaped first and
+// the markers turned into <mark> tags after.
+const (
+	MatchStart = "\x02"
+	MatchEnd   = "\x03"
+)
+
+// maxSearchTerms bounds the work a single query can ask of the index.
+const maxSearchTerms = 10
+
+// SearchTerms splits a search box query into lower-cased words. Everything
+// but letters and digits separates words, so the terms can be put into an
+// FTS5 or tsquery expression without quoting surprises.
+func SearchTerms(q string) []string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	for _, w := range words {
+		if !slices.Contains(terms, w) {
+			terms = append(terms, w)
+		}
+	}
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	return terms
+}
+
+// SearchDocument is the searchable text of a recipe, for stores without a
+// full-text index. Fields are in order of weight.
+type SearchDocument struct {
+	Title       string
+	Description string
+	Ingredients string
+	Steps       string
+}
+
+var searchWeights = [...]float64{10, 4, 2, 1}
+
+// snippetWords is how much text a snippet shows around the first match.
+const snippetWords = 12
+
+// Match reports whether every term starts a word somewhere in the document,
+// which is how the full-text index treats them too. The score weighs each
+// term by the best field it was found in; the snippet comes from the best
+// field that matched.
+func (d SearchDocument) Match(terms []string) (score float64, snippet string, ok bool) {
+	fields := [...]string{d.Title, d.Description, d.Ingredients, d.Steps}
+
+	best := -1
+	for _, term := range terms {
+		found := false
+		for i, f := range fields {
+			if matchWord(strings.Fields(f), term) >= 0 {
+				score += searchWeights[i]
+				if best < 0 || i < best {
+					best = i
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, "", false
+		}
+	}
+	if best < 0 {
+		return 0, "", false
+	}
+
+	return score, markSnippet(strings.Fields(fields[best]), terms), true
+}
+
+// matchWord returns the index of the first word starting with term, or -1.
+func matchWord(words []string, term string) int {
+	for i, w := range words {
+		if strings.HasPrefix(strings.ToLower(strings.TrimLeftFunc(w, notWordRune)), term) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// markSnippet cuts a window of words around the first match and marks every
+// matching word in it.
+func markSnippet(words []string, terms []string) string {
+	first := len(words)
+	for _, term := range terms {
+		if i := matchWord(words, term); i >= 0 && i < first {
+			first = i
+		}
+	}
+
+	start := max(0, first-snippetWords/3)
+	end := min(len(words), start+snippetWords)
+
+	out := make([]string, 0, end-start+2)
+	if start > 0 {
+		out = append(out, "…")
+	}
+	for _, w := range words[start:end] {
+		lw := strings.ToLower(strings.TrimLeftFunc(w, notWordRune))
+		for _, term := range terms {
+			if strings.HasPrefix(lw, term) {
+				w = MatchStart + w + MatchEnd
+				break
+			}
+		}
+		out = append(out, w)
+	}
+	if end < len(words) {
+		out = append(out, "…")
+	}
+
+	return strings.Join(out, " ")
+}
+
+// highlight makes a store's snippet safe to put into a page, with the
+// matches in <mark> tags.
+func highlight(snippet string) string {
+	s := html.EscapeString(snippet)
+	s = strings.ReplaceAll(s, MatchStart, "<mark>")
+	return strings.ReplaceAll(s, MatchEnd, "</mark>")
+}
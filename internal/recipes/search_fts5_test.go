@@ -0,0 +1,64 @@
+//go:build sqlite_fts5 || purego || !cgo
+
+package recipes_test
+
+import (
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/db"
+	"meal_prep/internal/recipes"
+	"meal_prep/internal/sqlstore"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestSearchFTS5 runs in the builds whose SQLite has FTS5: the pure Go one,
+// and cgo builds with -tags sqlite_fts5.
+func TestSearchFTS5(t *testing.T) {
+	conn, err := db.Open(filepath.Join(t.TempDir(), "meal_prep.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close(conn) })
+	if err := db.Init(conn); err != nil {
+		t.Fatal(err)
+	}
+	if !conn.FullText {
+		t.Fatal("no full-text index in a build with FTS5")
+	}
+	cl := apitest.NewClient(t, sqlstore.New(conn), "cook@example.com")
+
+	search := func(q string) []string {
+		t.Helper()
+		var found []recipes.Recipe
+		if code := cl.Do("GET", "/v1/recipes?q="+q, nil, &found); code != http.StatusOK {
+			t.Fatalf("?q=%s: status %d", q, code)
+		}
+		return titles(found)
+	}
+
+	soup := createRecipe(cl, map[string]any{"title": "Tomato soup"})
+	salad := createRecipe(cl, map[string]any{"title": "Summer salad"})
+	cl.Create("/v1/recipes/"+strconv.Itoa(salad.ID)+"/ingredients", map[string]any{"name": "tomatoes"}, nil)
+
+	// Words are stemmed, and a title match ranks over an ingredient
+	if got := search("tomatoes"); len(got) != 2 || got[0] != "Tomato soup" {
+		t.Errorf("?q=tomatoes = %q, want Tomato soup first of two", got)
+	}
+
+	// The triggers keep the index in step with edits
+	cl.Do("PATCH", "/v1/recipes/"+strconv.Itoa(soup.ID), map[string]any{"title": "Gazpacho"}, nil)
+	var step recipes.Step
+	cl.Create("/v1/recipes/"+strconv.Itoa(salad.ID)+"/steps", map[string]any{"instruction": "Dress with vinaigrette"}, &step)
+	if got := search("tomato"); len(got) != 1 || got[0] != "Summer salad" {
+		t.Errorf("after renaming the soup: %q", got)
+	}
+	if got := search("vinaigrette"); len(got) != 1 {
+		t.Errorf("after adding a step: %q", got)
+	}
+	cl.Do("DELETE", "/v1/recipes/"+strconv.Itoa(salad.ID)+"/steps/"+strconv.Itoa(step.ID), nil, nil)
+	if got := search("vinaigrette"); len(got) != 0 {
+		t.Errorf("after deleting the step: %q", got)
+	}
+}
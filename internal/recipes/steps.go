@@ -0,0 +1,260 @@
+package recipes
+
+import (
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/patch"
+	"meal_prep/internal/words"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Step struct {
+	ID          int    `json:"id"`
+	RecipeID    int    `json:"recipe_id"`
+	StepNo      int    `json:"step_no"`
+	Instruction string `json:"instruction"`
+}
+
+type CreateStepRequest struct {
+	Instruction string `json:"instruction" binding:"required"`
+	StepNo      *int   `json:"step_no"` // optional, inserts at this position; appends when absent
+}
+
+type UpdateStepRequest struct {
+	Instruction patch.Field[string] `json:"instruction"` // optional
+	StepNo      patch.Field[int]    `json:"step_no"`     // optional, moves the step and shifts the others
+}
+
+type ReorderStepsRequest struct {
+	StepIDs []int `json:"step_ids" binding:"required"` // every step of the recipe, in the new order
+}
+
+func parseStepParams(c *gin.Context) (recipeID, stepID int, ok bool) {
+	recipeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || recipeID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipe id"})
+		return 0, 0, false
+	}
+
+	stepID, err = strconv.Atoi(c.Param("stepId"))
+	if err != nil || stepID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid step id"})
+		return 0, 0, false
+	}
+
+	return recipeID, stepID, true
+}
+
+func ListStepsHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	recipeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || recipeID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipe id"})
+		return
+	}
+
+	steps, err := store.ListSteps(c.Request.Context(), households.ID(c), recipeID)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query steps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, steps)
+}
+
+func GetStepHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	recipeID, stepID, ok := parseStepParams(c)
+	if !ok {
+		return
+	}
+
+	s, err := store.GetStep(c.Request.Context(), households.ID(c), recipeID, stepID)
+	if errors.Is(err, ErrStepNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
+
+func CreateStepHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	recipeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || recipeID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipe id"})
+		return
+	}
+
+	var req CreateStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if req.StepNo != nil && *req.StepNo <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "step_no must be positive"})
+		return
+	}
+	if !words.Plain(req.Instruction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instruction cannot contain control characters"})
+		return
+	}
+
+	// A zero step_no appends
+	s := Step{RecipeID: recipeID, Instruction: req.Instruction}
+	if req.StepNo != nil {
+		s.StepNo = *req.StepNo
+	}
+
+	s, err = store.CreateStep(c.Request.Context(), households.ID(c), s)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert step"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, s)
+}
+
+func UpdateStepHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	recipeID, stepID, ok := parseStepParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateStepRequest
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if req.Instruction.Null || req.StepNo.Null {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instruction and step_no cannot be null"})
+		return
+	}
+	if req.StepNo.Set && req.StepNo.Value <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "step_no must be positive"})
+		return
+	}
+	if !words.Plain(req.Instruction.Value) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instruction cannot contain control characters"})
+		return
+	}
+
+	// Load existing
+	current, err := store.GetStep(c.Request.Context(), households.ID(c), recipeID, stepID)
+	if errors.Is(err, ErrStepNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	// Apply patch
+	if req.Instruction.Set {
+		current.Instruction = req.Instruction.Value
+	}
+	if req.StepNo.Set {
+		current.StepNo = req.StepNo.Value
+	}
+
+	s, err := store.UpdateStep(c.Request.Context(), households.ID(c), current)
+	if errors.Is(err, ErrStepNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
+
+func ReorderStepsHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	recipeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || recipeID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipe id"})
+		return
+	}
+
+	var req ReorderStepsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	steps, err := store.ReorderSteps(c.Request.Context(), households.ID(c), recipeID, req.StepIDs)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	case errors.Is(err, ErrInvalidStepOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reorder steps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, steps)
+}
+
+func DeleteStepHandler(c *gin.Context, store RecipeStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	recipeID, stepID, ok := parseStepParams(c)
+	if !ok {
+		return
+	}
+
+	err := store.DeleteStep(c.Request.Context(), households.ID(c), recipeID, stepID)
+	if errors.Is(err, ErrStepNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound         = errors.New("recipe not found")
+	ErrStepNotFound     = errors.New("step not found")
+	ErrInvalidStepOrder = errors.New("step_ids must list every step of the recipe exactly once")
+)
+
+// RecipeStore is the persistence behind the recipe and step handlers. Every
+// recipe belongs to a household and every call is scoped to one: another
+// household's recipe is reported as ErrNotFound, exactly like a missing one.
+// A missing step, or one that belongs to another recipe, is ErrStepNotFound.
+// Every recipe returned comes with its diet.Profile classified from its
+// ingredients.
+//
+// Steps are always numbered 1..n without gaps: inserting, moving and deleting
+// a step shift the ones after it.
+type RecipeStore interface {
+	// ListRecipes returns the page q asks for, fetching q.Fetch() rows.
+	ListRecipes(ctx context.Context, householdID int, q RecipeQuery) ([]Recipe, error)
+	GetRecipe(ctx context.Context, householdID, id int) (Recipe, error)
+	// SearchRecipes returns up to 100 recipes in which every term starts a
+	// word of the title, description, ingredient names or steps, best match
+	// first. Each comes with a Snippet of the matching text, the matches
+	// between MatchStart and MatchEnd.
+	SearchRecipes(ctx context.Context, householdID int, terms []string) ([]Recipe, error)
+	// ListPublicRecipes and GetPublicRecipe see the public recipes of every
+	// household.
+	ListPublicRecipes(ctx context.Context) ([]Recipe, error)
+	GetPublicRecipe(ctx context.Context, id int) (Recipe, error)
+	// CreateRecipe stores r in r.HouseholdID, recording r.OwnerID as its
+	// author.
+	CreateRecipe(ctx context.Context, r Recipe) (Recipe, error)
+	// UpdateRecipe overwrites every column of r.ID, if it is in r.HouseholdID,
+	// and bumps updated_at.
+	UpdateRecipe(ctx context.Context, r Recipe) (Recipe, error)
+	DeleteRecipe(ctx context.Context, householdID, id int) error
+
+	// ListSteps returns ErrNotFound, rather than no steps, for a missing
+	// recipe.
+	ListSteps(ctx context.Context, householdID, recipeID int) ([]Step, error)
+	GetStep(ctx context.Context, householdID, recipeID, stepID int) (Step, error)
+	// CreateStep inserts s at s.StepNo, appending when that is 0 or past the
+	// end.
+	CreateStep(ctx context.Context, householdID int, s Step) (Step, error)
+	// UpdateStep sets the instruction and moves the step to s.StepNo, clamped
+	// to the last position.
+	UpdateStep(ctx context.Context, householdID int, s Step) (Step, error)
+	// ReorderSteps renumbers the recipe's steps in the order given, which must
+	// name each of them exactly once or ErrInvalidStepOrder is returned.
+	ReorderSteps(ctx context.Context, householdID, recipeID int, stepIDs []int) ([]Step, error)
+	DeleteStep(ctx context.Context, householdID, recipeID, stepID int) error
+}
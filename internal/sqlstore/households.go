@@ -0,0 +1,305 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/db"
+	"meal_prep/internal/households"
+	"time"
+)
+
+const householdColumns = `h.id, h.name, m.role, h.created_at`
+
+func scanHousehold(row scanner) (households.Household, error) {
+	var h households.Household
+	err := row.Scan(&h.ID, &h.Name, &h.Role, &h.CreatedAt)
+	return h, err
+}
+
+// getHousehold loads a household as seen by one of its members.
+func getHousehold(ctx context.Context, q querier, userID, id int) (households.Household, error) {
+	h, err := scanHousehold(q.QueryRowContext(ctx, `
+		SELECT `+householdColumns+`
+		FROM households h
+		JOIN household_members m ON m.household_id = h.id
+		WHERE h.id = ? AND m.user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return h, households.ErrNotFound
+	}
+
+	return h, err
+}
+
+// createHousehold makes userID the owner of a new household.
+func createHousehold(ctx context.Context, tx *db.Tx, userID int, name string) (int, error) {
+	var id int
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO households (name, created_by)
+		VALUES (?, ?)
+		RETURNING id
+	`, name, userID).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO household_members (household_id, user_id, role)
+		VALUES (?, ?, ?)
+	`, id, userID, households.Owner)
+	return id, err
+}
+
+func (s *Store) ListHouseholds(ctx context.Context, userID int) ([]households.Household, error) {
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+householdColumns+`
+		FROM households h
+		JOIN household_members m ON m.household_id = h.id
+		WHERE m.user_id = ?
+		ORDER BY m.joined_at ASC, h.id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []households.Household
+	for rows.Next() {
+		h, err := scanHousehold(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *Store) GetHousehold(ctx context.Context, userID, id int) (households.Household, error) {
+	return getHousehold(ctx, s.db.Reader(), userID, id)
+}
+
+func (s *Store) CreateHousehold(ctx context.Context, userID int, name string) (households.Household, error) {
+	var created households.Household
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		id, err := createHousehold(ctx, tx, userID, name)
+		if err != nil {
+			return err
+		}
+
+		created, err = getHousehold(ctx, tx, userID, id)
+		return err
+	})
+
+	return created, err
+}
+
+func (s *Store) RenameHousehold(ctx context.Context, userID, id int, name string) (households.Household, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE households SET name = ? WHERE id = ?`, name, id)
+	if err != nil {
+		return households.Household{}, err
+	}
+	if err := checkAffected(res, households.ErrNotFound); err != nil {
+		return households.Household{}, err
+	}
+
+	return getHousehold(ctx, s.db, userID, id)
+}
+
+// DeleteHousehold relies on the cascades to remove everything in it.
+func (s *Store) DeleteHousehold(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM households WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, households.ErrNotFound)
+}
+
+const memberColumns = `u.id, u.email, m.role, m.joined_at`
+
+func scanMember(row scanner) (households.Member, error) {
+	var m households.Member
+	err := row.Scan(&m.UserID, &m.Email, &m.Role, &m.JoinedAt)
+	return m, err
+}
+
+func getMember(ctx context.Context, q querier, householdID, userID int) (households.Member, error) {
+	m, err := scanMember(q.QueryRowContext(ctx, `
+		SELECT `+memberColumns+`
+		FROM household_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.household_id = ? AND m.user_id = ?
+	`, householdID, userID))
+	if err == sql.ErrNoRows {
+		return m, households.ErrMemberNotFound
+	}
+
+	return m, err
+}
+
+func (s *Store) ListMembers(ctx context.Context, householdID int) ([]households.Member, error) {
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+memberColumns+`
+		FROM household_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.household_id = ?
+		ORDER BY m.joined_at ASC, u.id ASC
+	`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []households.Member
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+
+	return list, rows.Err()
+}
+
+// checkOwnersLeft fails with ErrLastOwner if taking the owner role away from
+// member would leave the household without one. On Postgres the owner rows
+// stay locked until the transaction ends, so two owners stepping down at once
+// can't both see the other still in place; SQLite's single writer already
+// runs such transactions one after the other.
+func checkOwnersLeft(ctx context.Context, tx *db.Tx, householdID int, member households.Member) error {
+	if member.Role != households.Owner {
+		return nil
+	}
+
+	query := `SELECT user_id FROM household_members WHERE household_id = ? AND role = ?`
+	if tx.Dialect == db.Postgres {
+		query += ` FOR UPDATE`
+	}
+	rows, err := tx.QueryContext(ctx, query, householdID, households.Owner)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if n <= 1 {
+		return households.ErrLastOwner
+	}
+
+	return nil
+}
+
+func (s *Store) SetMemberRole(ctx context.Context, householdID, userID int, role households.Role) (households.Member, error) {
+	var updated households.Member
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		current, err := getMember(ctx, tx, householdID, userID)
+		if err != nil {
+			return err
+		}
+		if role != households.Owner {
+			if err := checkOwnersLeft(ctx, tx, householdID, current); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE household_members SET role = ? WHERE household_id = ? AND user_id = ?
+		`, role, householdID, userID); err != nil {
+			return err
+		}
+
+		updated, err = getMember(ctx, tx, householdID, userID)
+		return err
+	})
+
+	return updated, err
+}
+
+func (s *Store) RemoveMember(ctx context.Context, householdID, userID int) error {
+	return s.inTx(ctx, func(tx *db.Tx) error {
+		current, err := getMember(ctx, tx, householdID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwnersLeft(ctx, tx, householdID, current); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM household_members WHERE household_id = ? AND user_id = ?
+		`, householdID, userID)
+		return err
+	})
+}
+
+func (s *Store) CreateInvite(ctx context.Context, inv households.Invite) (households.Invite, error) {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO household_invites (household_id, code_hash, role, created_by, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, inv.HouseholdID, inv.CodeHash, inv.Role, inv.CreatedBy, inv.ExpiresAt.UTC()).Scan(&inv.ID)
+
+	return inv, err
+}
+
+func (s *Store) AcceptInvite(ctx context.Context, codeHash string, userID int) (households.Household, error) {
+	var joined households.Household
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		var (
+			id, householdID int
+			role            households.Role
+		)
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, household_id, role FROM household_invites WHERE code_hash = ?
+		`, codeHash).Scan(&id, &householdID, &role)
+		if err == sql.ErrNoRows {
+			return households.ErrInviteNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// Claimed by a conditional update, so of two people redeeming the
+		// code at once only one gets in. Any error below rolls the claim back.
+		res, err := tx.ExecContext(ctx, `
+			UPDATE household_invites
+			SET used_by = ?, used_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND used_at IS NULL AND expires_at > ?
+		`, userID, id, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if err := checkAffected(res, households.ErrInviteNotFound); err != nil {
+			return err
+		}
+
+		if _, err := getMember(ctx, tx, householdID, userID); err == nil {
+			return households.ErrAlreadyMember
+		} else if err != households.ErrMemberNotFound {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO household_members (household_id, user_id, role)
+			VALUES (?, ?, ?)
+		`, householdID, userID, role)
+		if db.IsUniqueViolation(err) {
+			return households.ErrAlreadyMember
+		}
+		if err != nil {
+			return err
+		}
+
+		joined, err = getHousehold(ctx, tx, userID, householdID)
+		return err
+	})
+
+	return joined, err
+}
@@ -0,0 +1,117 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/db"
+	"meal_prep/internal/ingredients"
+	"strings"
+)
+
+const ingredientColumns = `id, recipe_id, name, quantity, quantity_value, quantity_max, unit, food`
+
+func scanIngredient(row scanner) (ingredients.Ingredient, error) {
+	var ing ingredients.Ingredient
+	err := row.Scan(&ing.ID, &ing.RecipeID, &ing.Name, &ing.Quantity, &ing.QuantityValue, &ing.QuantityMax, &ing.Unit, &ing.Food)
+	return ing, err
+}
+
+func getIngredient(ctx context.Context, q querier, householdID, id int) (ingredients.Ingredient, error) {
+	ing, err := scanIngredient(q.QueryRowContext(ctx, `
+		SELECT `+ingredientColumns+`
+		FROM recipe_ingredients
+		WHERE id = ? AND `+householdRecipe+`
+	`, id, householdID))
+	if err == sql.ErrNoRows {
+		return ing, ingredients.ErrNotFound
+	}
+
+	return ing, err
+}
+
+// ingredientSorts is the SQL for each of ingredients.IngredientSorts.
+var ingredientSorts = map[string]string{
+	"position": `id`,
+	"name":     `name`,
+}
+
+func (s *Store) ListIngredients(ctx context.Context, householdID, recipeID int, q ingredients.IngredientQuery) ([]ingredients.Ingredient, error) {
+	var f filter
+	f.add(`recipe_id = ? AND `+householdRecipe, recipeID, householdID)
+	if q.Name != "" {
+		f.add(`lower(name) LIKE ? ESCAPE '\'`, containsPattern(strings.ToLower(q.Name)))
+	}
+	tail := f.page(q.Query, ingredientSorts)
+
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+ingredientColumns+`
+		FROM recipe_ingredients
+		WHERE `+f.String()+`
+		`+tail, f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ingredients.Ingredient
+	for rows.Next() {
+		ing, err := scanIngredient(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, ing)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *Store) GetIngredient(ctx context.Context, householdID, id int) (ingredients.Ingredient, error) {
+	return getIngredient(ctx, s.db.Reader(), householdID, id)
+}
+
+func (s *Store) CreateIngredient(ctx context.Context, householdID int, ing ingredients.Ingredient) (ingredients.Ingredient, error) {
+	var created ingredients.Ingredient
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		ok, err := belongs(ctx, tx, "recipes", householdID, ing.RecipeID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ingredients.ErrRecipeNotFound
+		}
+
+		var id int
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO recipe_ingredients (recipe_id, name, quantity, quantity_value, quantity_max, unit, food)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, ing.RecipeID, ing.Name, ing.Quantity, ing.QuantityValue, ing.QuantityMax, ing.Unit, ing.Food).Scan(&id); err != nil {
+			return err
+		}
+
+		created, err = getIngredient(ctx, tx, householdID, id)
+		return err
+	})
+
+	return created, err
+}
+
+func (s *Store) UpdateIngredient(ctx context.Context, householdID int, ing ingredients.Ingredient) (ingredients.Ingredient, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE recipe_ingredients
+		SET name = ?, quantity = ?, quantity_value = ?, quantity_max = ?, unit = ?, food = ?
+		WHERE id = ? AND `+householdRecipe+`
+	`, ing.Name, ing.Quantity, ing.QuantityValue, ing.QuantityMax, ing.Unit, ing.Food, ing.ID, householdID)
+	if err != nil {
+		return ingredients.Ingredient{}, err
+	}
+	if err := checkAffected(res, ingredients.ErrNotFound); err != nil {
+		return ingredients.Ingredient{}, err
+	}
+
+	return getIngredient(ctx, s.db, householdID, ing.ID)
+}
+
+func (s *Store) DeleteIngredient(ctx context.Context, householdID, id int) error {
+	return deleteInHousehold(ctx, s.db, "recipe_ingredients", householdRecipe, householdID, id, ingredients.ErrNotFound)
+}
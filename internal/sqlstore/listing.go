@@ -0,0 +1,57 @@
+package sqlstore
+
+import (
+	"meal_prep/internal/listing"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filter collects the conditions of a list query and their arguments.
+type filter struct {
+	conds []string
+	args  []any
+}
+
+func (f *filter) add(cond string, args ...any) {
+	f.conds = append(f.conds, cond)
+	f.args = append(f.args, args...)
+}
+
+func (f *filter) String() string {
+	return strings.Join(f.conds, " AND ")
+}
+
+// page finishes a list query for q: it adds the keyset condition for the
+// sort, whose SQL is in exprs by sort name, and returns the ORDER BY and
+// LIMIT that follow the WHERE clause.
+func (f *filter) page(q listing.Query, exprs map[string]string) string {
+	cond, args, order := q.Keyset(exprs[q.Sort], "id")
+	f.add(cond, args...)
+
+	tail := `ORDER BY ` + order
+	if n := q.Fetch(); n > 0 {
+		tail += ` LIMIT ` + strconv.Itoa(n)
+	}
+
+	return tail
+}
+
+// dayAfter turns an inclusive YYYY-MM-DD upper bound on a timestamp into the
+// exclusive one the query compares with.
+func dayAfter(date string) string {
+	t, _ := time.Parse(time.DateOnly, date)
+	return t.AddDate(0, 0, 1).Format(time.DateOnly)
+}
+
+// containsPattern is the LIKE pattern, used with ESCAPE '\', matching text
+// that contains s.
+func containsPattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+// placeholders is the list of n ? placeholders for an IN (...).
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,301 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/db"
+	mealplan "meal_prep/internal/meal_plan"
+	"strings"
+	"time"
+)
+
+const (
+	mealPlanColumns       = `id, household_id, owner_id, name, start_date, end_date, restrictions, budget, created_at`
+	mealPlanRecipeColumns = `id, meal_plan_id, recipe_id, meal_type, planned_date, servings`
+)
+
+func scanMealPlan(row scanner) (mealplan.MealPlan, error) {
+	var (
+		mp           mealplan.MealPlan
+		restrictions string
+	)
+	err := row.Scan(&mp.ID, &mp.HouseholdID, &mp.OwnerID, &mp.Name, &mp.StartDate, &mp.EndDate, &restrictions, &mp.Budget, &mp.CreatedAt)
+	mp.StartDate, mp.EndDate = dateOnly(mp.StartDate), dateOnly(mp.EndDate)
+	mp.Restrictions = []string{}
+	if restrictions != "" {
+		mp.Restrictions = strings.Split(restrictions, ",")
+	}
+
+	return mp, err
+}
+
+func scanMealPlanRecipe(row scanner) (mealplan.MealPlanRecipe, error) {
+	var mpr mealplan.MealPlanRecipe
+	err := row.Scan(&mpr.ID, &mpr.MealPlanID, &mpr.RecipeID, &mpr.MealType, &mpr.PlannedDate, &mpr.Servings)
+	mpr.PlannedDate = dateOnlyPtr(mpr.PlannedDate)
+	return mpr, err
+}
+
+// dateOnly cuts a DATE column down to "YYYY-MM-DD", as drivers that parse
+// DATE columns hand them back as timestamps. Every date read from the
+// meal plan tables goes through it, so the handlers only ever see and
+// compare plain dates.
+func dateOnly(s string) string {
+	if len(s) > len(time.DateOnly) {
+		if _, err := time.Parse(time.DateOnly, s[:len(time.DateOnly)]); err == nil {
+			return s[:len(time.DateOnly)]
+		}
+	}
+	return s
+}
+
+func dateOnlyPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	date := dateOnly(*s)
+	return &date
+}
+
+func getMealPlan(ctx context.Context, q querier, householdID, id int) (mealplan.MealPlan, error) {
+	mp, err := scanMealPlan(q.QueryRowContext(ctx, `SELECT `+mealPlanColumns+` FROM meal_plans WHERE id = ? AND household_id = ?`, id, householdID))
+	if err == sql.ErrNoRows {
+		return mp, mealplan.ErrNotFound
+	}
+
+	return mp, err
+}
+
+func getMealPlanRecipe(ctx context.Context, q querier, householdID, id int) (mealplan.MealPlanRecipe, error) {
+	mpr, err := scanMealPlanRecipe(q.QueryRowContext(ctx, `
+		SELECT `+mealPlanRecipeColumns+`
+		FROM meal_plan_recipes
+		WHERE id = ? AND `+householdMealPlan+`
+	`, id, householdID))
+	if err == sql.ErrNoRows {
+		return mpr, mealplan.ErrEntryNotFound
+	}
+
+	return mpr, err
+}
+
+// mealPlanSorts is the SQL for each of mealplan.MealPlanSorts.
+var mealPlanSorts = map[string]string{
+	"start_date": `start_date`,
+	"name":       `name`,
+	"created":    `id`,
+}
+
+func (s *Store) ListMealPlans(ctx context.Context, householdID int, q mealplan.MealPlanQuery) ([]mealplan.MealPlan, error) {
+	var f filter
+	f.add(inHousehold, householdID)
+	if q.From != nil {
+		f.add(`end_date >= ?`, *q.From)
+	}
+	if q.To != nil {
+		f.add(`start_date <= ?`, *q.To)
+	}
+	tail := f.page(q.Query, mealPlanSorts)
+
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+mealPlanColumns+`
+		FROM meal_plans
+		WHERE `+f.String()+`
+		`+tail, f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []mealplan.MealPlan
+	for rows.Next() {
+		mp, err := scanMealPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, mp)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *Store) GetMealPlan(ctx context.Context, householdID, id int) (mealplan.MealPlan, error) {
+	return getMealPlan(ctx, s.db.Reader(), householdID, id)
+}
+
+func (s *Store) CreateMealPlan(ctx context.Context, mp mealplan.MealPlan) (mealplan.MealPlan, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO meal_plans (household_id, owner_id, name, start_date, end_date, restrictions, budget)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, mp.HouseholdID, mp.OwnerID, mp.Name, mp.StartDate, mp.EndDate, strings.Join(mp.Restrictions, ","), mp.Budget).Scan(&id)
+	if err != nil {
+		return mealplan.MealPlan{}, err
+	}
+
+	return getMealPlan(ctx, s.db, mp.HouseholdID, id)
+}
+
+func (s *Store) UpdateMealPlan(ctx context.Context, mp mealplan.MealPlan) (mealplan.MealPlan, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE meal_plans
+		SET name = ?, start_date = ?, end_date = ?, restrictions = ?, budget = ?
+		WHERE id = ? AND household_id = ?
+	`, mp.Name, mp.StartDate, mp.EndDate, strings.Join(mp.Restrictions, ","), mp.Budget, mp.ID, mp.HouseholdID)
+	if err != nil {
+		return mealplan.MealPlan{}, err
+	}
+	if err := checkAffected(res, mealplan.ErrNotFound); err != nil {
+		return mealplan.MealPlan{}, err
+	}
+
+	return getMealPlan(ctx, s.db, mp.HouseholdID, mp.ID)
+}
+
+// DeleteMealPlan relies on the cascade to remove the plan's entries.
+func (s *Store) DeleteMealPlan(ctx context.Context, householdID, id int) error {
+	return deleteInHousehold(ctx, s.db, "meal_plans", inHousehold, householdID, id, mealplan.ErrNotFound)
+}
+
+func (s *Store) ListMealPlanRecipes(ctx context.Context, householdID, mealPlanID int) ([]mealplan.MealPlanRecipe, error) {
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+mealPlanRecipeColumns+`
+		FROM meal_plan_recipes
+		WHERE meal_plan_id = ? AND `+householdMealPlan+`
+		ORDER BY planned_date ASC NULLS FIRST, id ASC
+	`, mealPlanID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []mealplan.MealPlanRecipe
+	for rows.Next() {
+		mpr, err := scanMealPlanRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, mpr)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *Store) GetMealPlanRecipe(ctx context.Context, householdID, id int) (mealplan.MealPlanRecipe, error) {
+	return getMealPlanRecipe(ctx, s.db.Reader(), householdID, id)
+}
+
+// checkRecipeRef reports an entry's missing recipe before the FK does, and
+// keeps plans from pointing at another household's recipes, which the FK
+// can't.
+func checkRecipeRef(ctx context.Context, q querier, householdID int, mpr mealplan.MealPlanRecipe) error {
+	if mpr.RecipeID == nil {
+		return nil
+	}
+
+	ok, err := belongs(ctx, q, "recipes", householdID, *mpr.RecipeID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return mealplan.ErrRecipeNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) CreateMealPlanRecipe(ctx context.Context, householdID int, mpr mealplan.MealPlanRecipe) (mealplan.MealPlanRecipe, error) {
+	var created mealplan.MealPlanRecipe
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		ok, err := belongs(ctx, tx, "meal_plans", householdID, mpr.MealPlanID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return mealplan.ErrNotFound
+		}
+		if err := checkRecipeRef(ctx, tx, householdID, mpr); err != nil {
+			return err
+		}
+
+		var id int
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO meal_plan_recipes (meal_plan_id, recipe_id, meal_type, planned_date, servings)
+			VALUES (?, ?, ?, ?, ?)
+			RETURNING id
+		`, mpr.MealPlanID, mpr.RecipeID, mpr.MealType, mpr.PlannedDate, mpr.Servings).Scan(&id); err != nil {
+			return err
+		}
+
+		created, err = getMealPlanRecipe(ctx, tx, householdID, id)
+		return err
+	})
+
+	return created, err
+}
+
+func (s *Store) UpdateMealPlanRecipe(ctx context.Context, householdID int, mpr mealplan.MealPlanRecipe) (mealplan.MealPlanRecipe, error) {
+	var updated mealplan.MealPlanRecipe
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		if _, err := getMealPlanRecipe(ctx, tx, householdID, mpr.ID); err != nil {
+			return err
+		}
+		if err := checkRecipeRef(ctx, tx, householdID, mpr); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			UPDATE meal_plan_recipes
+			SET recipe_id = ?, meal_type = ?, planned_date = ?, servings = ?
+			WHERE id = ?
+		`, mpr.RecipeID, mpr.MealType, mpr.PlannedDate, mpr.Servings, mpr.ID)
+		if err != nil {
+			return err
+		}
+
+		updated, err = getMealPlanRecipe(ctx, tx, householdID, mpr.ID)
+		return err
+	})
+
+	return updated, err
+}
+
+func (s *Store) DeleteMealPlanRecipe(ctx context.Context, householdID, id int) error {
+	return deleteInHousehold(ctx, s.db, "meal_plan_recipes", householdMealPlan, householdID, id, mealplan.ErrEntryNotFound)
+}
+
+func (s *Store) ListPlannedIngredients(ctx context.Context, householdID, mealPlanID int) ([]mealplan.PlannedIngredient, error) {
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT mpr.id, mpr.recipe_id, mpr.planned_date, mpr.servings, r.servings,
+		       ri.id, ri.recipe_id, ri.name, ri.quantity, ri.quantity_value, ri.quantity_max, ri.unit, ri.food
+		FROM meal_plan_recipes mpr
+		JOIN meal_plans mp ON mp.id = mpr.meal_plan_id
+		JOIN recipes r ON r.id = mpr.recipe_id
+		JOIN recipe_ingredients ri ON ri.recipe_id = mpr.recipe_id
+		WHERE mpr.meal_plan_id = ? AND mp.household_id = ?
+		ORDER BY mpr.id ASC, ri.id ASC
+	`, mealPlanID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []mealplan.PlannedIngredient
+	for rows.Next() {
+		var (
+			p   mealplan.PlannedIngredient
+			ing = &p.Ingredient
+		)
+		if err := rows.Scan(
+			&p.EntryID, &p.RecipeID, &p.PlannedDate, &p.EntryServings, &p.RecipeServings,
+			&ing.ID, &ing.RecipeID, &ing.Name, &ing.Quantity, &ing.QuantityValue, &ing.QuantityMax, &ing.Unit, &ing.Food,
+		); err != nil {
+			return nil, err
+		}
+		p.PlannedDate = dateOnlyPtr(p.PlannedDate)
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
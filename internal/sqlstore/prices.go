@@ -0,0 +1,86 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/prices"
+)
+
+const priceColumns = `id, household_id, item, store, price, quantity, unit, effective_from, effective_to, created_at`
+
+func scanPrice(row scanner) (prices.Price, error) {
+	var p prices.Price
+	err := row.Scan(&p.ID, &p.HouseholdID, &p.Item, &p.Store, &p.Amount, &p.Quantity, &p.Unit, &p.EffectiveFrom, &p.EffectiveTo, &p.CreatedAt)
+	return p, err
+}
+
+func getPrice(ctx context.Context, q querier, householdID, id int) (prices.Price, error) {
+	p, err := scanPrice(q.QueryRowContext(ctx, `SELECT `+priceColumns+` FROM prices WHERE id = ? AND household_id = ?`, id, householdID))
+	if err == sql.ErrNoRows {
+		return p, prices.ErrNotFound
+	}
+
+	return p, err
+}
+
+func (s *Store) ListPrices(ctx context.Context, householdID int) ([]prices.Price, error) {
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+priceColumns+`
+		FROM prices
+		WHERE household_id = ?
+		ORDER BY item ASC, store ASC, effective_from DESC, id DESC
+	`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []prices.Price
+	for rows.Next() {
+		p, err := scanPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *Store) GetPrice(ctx context.Context, householdID, id int) (prices.Price, error) {
+	return getPrice(ctx, s.db.Reader(), householdID, id)
+}
+
+func (s *Store) CreatePrice(ctx context.Context, p prices.Price) (prices.Price, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO prices (household_id, item, store, price, quantity, unit, effective_from, effective_to)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, p.HouseholdID, p.Item, p.Store, p.Amount, p.Quantity, p.Unit, p.EffectiveFrom, p.EffectiveTo).Scan(&id)
+	if err != nil {
+		return prices.Price{}, err
+	}
+
+	return getPrice(ctx, s.db, p.HouseholdID, id)
+}
+
+func (s *Store) UpdatePrice(ctx context.Context, p prices.Price) (prices.Price, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE prices
+		SET item = ?, store = ?, price = ?, quantity = ?, unit = ?, effective_from = ?, effective_to = ?
+		WHERE id = ? AND household_id = ?
+	`, p.Item, p.Store, p.Amount, p.Quantity, p.Unit, p.EffectiveFrom, p.EffectiveTo, p.ID, p.HouseholdID)
+	if err != nil {
+		return prices.Price{}, err
+	}
+	if err := checkAffected(res, prices.ErrNotFound); err != nil {
+		return prices.Price{}, err
+	}
+
+	return getPrice(ctx, s.db, p.HouseholdID, p.ID)
+}
+
+func (s *Store) DeletePrice(ctx context.Context, householdID, id int) error {
+	return deleteInHousehold(ctx, s.db, "prices", inHousehold, householdID, id, prices.ErrNotFound)
+}
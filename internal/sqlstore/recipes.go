@@ -0,0 +1,402 @@
+package sqlstore
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"meal_prep/internal/db"
+	"meal_prep/internal/diet"
+	"meal_prep/internal/recipes"
+	"slices"
+	"strings"
+)
+
+const recipeColumns = `id, household_id, owner_id, title, description, servings, prep_time, cook_time, is_public, created_at, updated_at`
+
+func scanRecipe(row scanner) (recipes.Recipe, error) {
+	var r recipes.Recipe
+	err := row.Scan(
+		&r.ID, &r.HouseholdID, &r.OwnerID, &r.Title, &r.Description, &r.Servings,
+		&r.PrepTime, &r.CookTime, &r.IsPublic, &r.CreatedAt, &r.UpdatedAt,
+	)
+
+	return r, err
+}
+
+// getRecipe loads one recipe matching cond, which is given the id first and
+// then args.
+func getRecipe(ctx context.Context, q querier, cond string, id int, args ...any) (recipes.Recipe, error) {
+	r, err := scanRecipe(q.QueryRowContext(ctx, `SELECT `+recipeColumns+` FROM recipes WHERE id = ? AND `+cond, append([]any{id}, args...)...))
+	if err == sql.ErrNoRows {
+		return r, recipes.ErrNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+
+	list := []recipes.Recipe{r}
+	err = classify(ctx, q, list)
+	return list[0], err
+}
+
+// classify fills in each recipe's diet profile from its ingredient names.
+func classify(ctx context.Context, q querier, list []recipes.Recipe) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]any, len(list))
+	for i, r := range list {
+		ids[i] = r.ID
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT recipe_id, name
+		FROM recipe_ingredients
+		WHERE recipe_id IN (`+placeholders(len(ids))+`)
+		ORDER BY id
+	`, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := map[int][]string{}
+	for rows.Next() {
+		var (
+			recipeID int
+			name     string
+		)
+		if err := rows.Scan(&recipeID, &name); err != nil {
+			return err
+		}
+		names[recipeID] = append(names[recipeID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range list {
+		list[i].Profile = diet.Classify(names[list[i].ID])
+	}
+
+	return nil
+}
+
+// publicRecipe leaves out recipes created before there were accounts, which
+// belong to no household until the first user registers.
+const publicRecipe = `is_public AND household_id IS NOT NULL`
+
+// listRecipes returns the newest recipes matching cond.
+func listRecipes(ctx context.Context, q querier, cond string, args ...any) ([]recipes.Recipe, error) {
+	return queryRecipes(ctx, q, `
+		SELECT `+recipeColumns+`
+		FROM recipes
+		WHERE `+cond+`
+		ORDER BY created_at DESC
+		LIMIT 100
+	`, args...)
+}
+
+// queryRecipes runs a query selecting recipeColumns and classifies the
+// recipes found.
+func queryRecipes(ctx context.Context, q querier, query string, args ...any) ([]recipes.Recipe, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []recipes.Recipe
+	for rows.Next() {
+		r, err := scanRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return list, classify(ctx, q, list)
+}
+
+// recipeSorts is the SQL for each of recipes.RecipeSorts.
+var recipeSorts = map[string]string{
+	"created":    `id`,
+	"title":      `title`,
+	"total_time": `(coalesce(prep_time, 0) + coalesce(cook_time, 0))`,
+	"servings":   `coalesce(servings, 0)`,
+}
+
+func (s *Store) ListRecipes(ctx context.Context, householdID int, q recipes.RecipeQuery) ([]recipes.Recipe, error) {
+	var f filter
+	f.add(inHousehold, householdID)
+	if q.MaxTotalTime != nil {
+		f.add(recipeSorts["total_time"]+` <= ?`, *q.MaxTotalTime)
+	}
+	if q.MinServings != nil {
+		f.add(`servings >= ?`, *q.MinServings)
+	}
+	if q.MaxServings != nil {
+		f.add(`servings <= ?`, *q.MaxServings)
+	}
+	if q.CreatedFrom != nil {
+		f.add(`created_at >= ?`, *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		f.add(`created_at < ?`, dayAfter(*q.CreatedTo))
+	}
+	if len(q.Tags) > 0 {
+		args := make([]any, 0, len(q.Tags)+1)
+		for _, name := range q.Tags {
+			args = append(args, name)
+		}
+		// A recipe carries a tag at most once, so counting the matches
+		// tells whether it has all of them
+		having := ``
+		if !q.AnyTag {
+			having = `HAVING COUNT(*) = ?`
+			args = append(args, len(q.Tags))
+		}
+		f.add(`id IN (
+			SELECT rt.recipe_id
+			FROM recipe_tags rt
+			JOIN tags t ON t.id = rt.tag_id
+			WHERE t.name IN (`+placeholders(len(q.Tags))+`)
+			GROUP BY rt.recipe_id
+			`+having+`
+		)`, args...)
+	}
+	tail := f.page(q.Query, recipeSorts)
+
+	return queryRecipes(ctx, s.db.Reader(), `
+		SELECT `+recipeColumns+`
+		FROM recipes
+		WHERE `+f.String()+`
+		`+tail, f.args...)
+}
+
+func (s *Store) GetRecipe(ctx context.Context, householdID, id int) (recipes.Recipe, error) {
+	return getRecipe(ctx, s.db.Reader(), inHousehold, id, householdID)
+}
+
+func (s *Store) ListPublicRecipes(ctx context.Context) ([]recipes.Recipe, error) {
+	return listRecipes(ctx, s.db.Reader(), publicRecipe)
+}
+
+func (s *Store) GetPublicRecipe(ctx context.Context, id int) (recipes.Recipe, error) {
+	return getRecipe(ctx, s.db.Reader(), publicRecipe, id)
+}
+
+func (s *Store) CreateRecipe(ctx context.Context, r recipes.Recipe) (recipes.Recipe, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO recipes (household_id, owner_id, title, description, servings, prep_time, cook_time, is_public)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, r.HouseholdID, r.OwnerID, r.Title, r.Description, r.Servings, r.PrepTime, r.CookTime, r.IsPublic).Scan(&id)
+	if err != nil {
+		return recipes.Recipe{}, err
+	}
+
+	return getRecipe(ctx, s.db, inHousehold, id, r.HouseholdID)
+}
+
+func (s *Store) UpdateRecipe(ctx context.Context, r recipes.Recipe) (recipes.Recipe, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE recipes
+		SET title = ?, description = ?, servings = ?, prep_time = ?, cook_time = ?, is_public = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND household_id = ?
+	`, r.Title, r.Description, r.Servings, r.PrepTime, r.CookTime, r.IsPublic, r.ID, r.HouseholdID)
+	if err != nil {
+		return recipes.Recipe{}, err
+	}
+	if err := checkAffected(res, recipes.ErrNotFound); err != nil {
+		return recipes.Recipe{}, err
+	}
+
+	return getRecipe(ctx, s.db, inHousehold, r.ID, r.HouseholdID)
+}
+
+// DeleteRecipe also removes the recipe's ingredients and steps; meal plan
+// entries that used it are kept with no recipe.
+func (s *Store) DeleteRecipe(ctx context.Context, householdID, id int) error {
+	return deleteInHousehold(ctx, s.db, "recipes", inHousehold, householdID, id, recipes.ErrNotFound)
+}
+
+// SearchRecipes uses the FTS5 index on SQLite builds that have one, text
+// search on Postgres and otherwise matches substrings and ranks in Go.
+func (s *Store) SearchRecipes(ctx context.Context, householdID int, terms []string) ([]recipes.Recipe, error) {
+	switch {
+	case s.db.Dialect == db.Postgres:
+		return s.searchTSVector(ctx, householdID, terms)
+	case s.db.FullText:
+		return s.searchFTS5(ctx, householdID, terms)
+	}
+
+	return s.searchSubstrings(ctx, householdID, terms)
+}
+
+// listSnippets runs a search query whose rows are a recipe followed by its
+// snippet.
+func listSnippets(ctx context.Context, q querier, query string, args ...any) ([]recipes.Recipe, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []recipes.Recipe
+	for rows.Next() {
+		var r recipes.Recipe
+		if err := rows.Scan(
+			&r.ID, &r.HouseholdID, &r.OwnerID, &r.Title, &r.Description, &r.Servings,
+			&r.PrepTime, &r.CookTime, &r.IsPublic, &r.CreatedAt, &r.UpdatedAt, &r.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return list, classify(ctx, q, list)
+}
+
+// searchFTS5 ranks with bm25, weighting the title over the description over
+// ingredients over steps. Every term is a prefix, so "tom" finds tomatoes.
+func (s *Store) searchFTS5(ctx context.Context, householdID int, terms []string) ([]recipes.Recipe, error) {
+	match := make([]string, len(terms))
+	for i, t := range terms {
+		match[i] = `"` + t + `"*`
+	}
+
+	return listSnippets(ctx, s.db.Reader(), `
+		SELECT `+qualified("r", recipeColumns)+`,
+		       snippet(recipe_search, -1, char(2), char(3), '…', 12)
+		FROM recipe_search
+		JOIN recipes r ON r.id = recipe_search.rowid
+		WHERE recipe_search MATCH ? AND r.household_id = ?
+		ORDER BY bm25(recipe_search, 10.0, 4.0, 2.0, 1.0), r.created_at DESC
+		LIMIT 100
+	`, strings.Join(match, " "), householdID)
+}
+
+// searchTSVector builds the document on the fly; households hold few enough
+// recipes that an index isn't worth the triggers to maintain it.
+func (s *Store) searchTSVector(ctx context.Context, householdID int, terms []string) ([]recipes.Recipe, error) {
+	query := make([]string, len(terms))
+	for i, t := range terms {
+		query[i] = t + `:*`
+	}
+
+	return listSnippets(ctx, s.db.Reader(), `
+		WITH docs AS (
+			SELECT r.*,
+			       setweight(to_tsvector('english', r.title), 'A') ||
+			       setweight(to_tsvector('english', coalesce(r.description, '')), 'B') ||
+			       setweight(to_tsvector('english', coalesce(i.names, '')), 'C') ||
+			       setweight(to_tsvector('english', coalesce(st.text, '')), 'D') AS doc,
+			       concat_ws(' ', r.title, r.description, i.names, st.text) AS body
+			FROM recipes r
+			LEFT JOIN LATERAL (
+				SELECT string_agg(name, ' ') AS names FROM recipe_ingredients WHERE recipe_id = r.id
+			) i ON true
+			LEFT JOIN LATERAL (
+				SELECT string_agg(instruction, ' ' ORDER BY step_no) AS text FROM recipe_steps WHERE recipe_id = r.id
+			) st ON true
+			WHERE r.household_id = ?
+		)
+		SELECT `+qualified("docs", recipeColumns)+`,
+		       ts_headline('english', body, q, 'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxWords=12, MinWords=6')
+		FROM docs, to_tsquery('english', ?) q
+		WHERE doc @@ q
+		ORDER BY ts_rank(doc, q) DESC, docs.created_at DESC
+		LIMIT 100
+	`, householdID, strings.Join(query, " & "))
+}
+
+// searchSubstrings narrows the household's recipes down in SQL and leaves
+// word matching, ranking and snippets to recipes.SearchDocument.
+func (s *Store) searchSubstrings(ctx context.Context, householdID int, terms []string) ([]recipes.Recipe, error) {
+	var (
+		conds []string
+		args  = []any{householdID}
+	)
+	for _, t := range terms {
+		conds = append(conds, `lower(r.title || ' ' || coalesce(r.description, '') || ' ' || coalesce(i.names, '') || ' ' || coalesce(st.text, '')) LIKE ? ESCAPE '\'`)
+		args = append(args, containsPattern(t))
+	}
+
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+qualified("r", recipeColumns)+`,
+		       coalesce(i.names, ''), coalesce(st.text, '')
+		FROM recipes r
+		LEFT JOIN (
+			SELECT recipe_id, group_concat(name, ' ') AS names FROM recipe_ingredients GROUP BY recipe_id
+		) i ON i.recipe_id = r.id
+		LEFT JOIN (
+			SELECT recipe_id, group_concat(instruction, ' ') AS text
+			FROM (SELECT recipe_id, instruction FROM recipe_steps ORDER BY recipe_id, step_no)
+			GROUP BY recipe_id
+		) st ON st.recipe_id = r.id
+		WHERE r.household_id = ? AND `+strings.Join(conds, " AND ")+`
+		ORDER BY r.created_at DESC, r.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		list   []recipes.Recipe
+		scores = map[int]float64{}
+	)
+	for rows.Next() {
+		var (
+			r                  recipes.Recipe
+			ingredients, steps string
+		)
+		if err := rows.Scan(
+			&r.ID, &r.HouseholdID, &r.OwnerID, &r.Title, &r.Description, &r.Servings,
+			&r.PrepTime, &r.CookTime, &r.IsPublic, &r.CreatedAt, &r.UpdatedAt, &ingredients, &steps,
+		); err != nil {
+			return nil, err
+		}
+
+		doc := recipes.SearchDocument{Title: r.Title, Ingredients: ingredients, Steps: steps}
+		if r.Description != nil {
+			doc.Description = *r.Description
+		}
+		score, snippet, ok := doc.Match(terms)
+		if !ok {
+			continue
+		}
+		r.Snippet = snippet
+		scores[r.ID] = score
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	slices.SortStableFunc(list, func(a, b recipes.Recipe) int { return cmp.Compare(scores[b.ID], scores[a.ID]) })
+	list = list[:min(len(list), 100)]
+	return list, classify(ctx, s.db.Reader(), list)
+}
+
+// qualified prefixes each of a column list's columns with a table alias.
+func qualified(alias, columns string) string {
+	cols := strings.Split(columns, ", ")
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+
+	return strings.Join(cols, ", ")
+}
@@ -0,0 +1,111 @@
+// Package sqlstore implements the recipe, ingredient, meal plan, user, API
+// token, household, tag and price stores on top of the database opened by
+// package db, SQLite or Postgres. Queries stick to SQL both understand and use
+// ? placeholders, which db rebinds.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/db"
+	"meal_prep/internal/households"
+	"meal_prep/internal/ingredients"
+	mealplan "meal_prep/internal/meal_plan"
+	"meal_prep/internal/prices"
+	"meal_prep/internal/recipes"
+	"meal_prep/internal/tags"
+)
+
+var (
+	_ recipes.RecipeStore         = (*Store)(nil)
+	_ ingredients.IngredientStore = (*Store)(nil)
+	_ mealplan.MealPlanStore      = (*Store)(nil)
+	_ auth.UserStore              = (*Store)(nil)
+	_ auth.TokenStore             = (*Store)(nil)
+	_ households.HouseholdStore   = (*Store)(nil)
+	_ tags.TagStore               = (*Store)(nil)
+	_ prices.PriceStore           = (*Store)(nil)
+)
+
+// Store sends standalone reads to the database's read pool. Writes, and the
+// reads that reload what a write just stored, stay on the writer.
+type Store struct {
+	db *db.DB
+}
+
+func New(conn *db.DB) *Store {
+	return &Store{db: conn}
+}
+
+// querier is satisfied by both *db.DB and *db.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// inTx runs fn in a transaction, committing only if it succeeds. A SQLite pool
+// has a single connection, so fn must not touch s.db directly.
+func (s *Store) inTx(ctx context.Context, fn func(tx *db.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Recipes and meal plans belong to a household, and the rows under them to
+// the household of their recipe or meal plan; these conditions restrict a
+// query to one household.
+const (
+	inHousehold       = `household_id = ?`
+	householdRecipe   = `recipe_id IN (SELECT id FROM recipes WHERE household_id = ?)`
+	householdMealPlan = `meal_plan_id IN (SELECT id FROM meal_plans WHERE household_id = ?)`
+)
+
+// belongs reports whether the row with the given id in table is in the
+// household.
+func belongs(ctx context.Context, q querier, table string, householdID, id int) (bool, error) {
+	var one int
+	err := q.QueryRowContext(ctx, `SELECT 1 FROM `+table+` WHERE id = ? AND household_id = ?`, id, householdID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// deleteInHousehold removes one row matching the household condition,
+// returning notFound if there was none.
+func deleteInHousehold(ctx context.Context, q querier, table, cond string, householdID, id int, notFound error) error {
+	res, err := q.ExecContext(ctx, `DELETE FROM `+table+` WHERE id = ? AND `+cond, id, householdID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, notFound)
+}
+
+func checkAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/db"
+	"meal_prep/internal/recipes"
+)
+
+const stepColumns = `id, recipe_id, step_no, instruction`
+
+func scanStep(row scanner) (recipes.Step, error) {
+	var st recipes.Step
+	err := row.Scan(&st.ID, &st.RecipeID, &st.StepNo, &st.Instruction)
+	return st, err
+}
+
+func listSteps(ctx context.Context, q querier, householdID, recipeID int) ([]recipes.Step, error) {
+	ok, err := belongs(ctx, q, "recipes", householdID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, recipes.ErrNotFound
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+stepColumns+`
+		FROM recipe_steps
+		WHERE recipe_id = ?
+		ORDER BY step_no ASC, id ASC
+	`, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var steps []recipes.Step
+	for rows.Next() {
+		st, err := scanStep(rows)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+	}
+
+	return steps, rows.Err()
+}
+
+func getStep(ctx context.Context, q querier, householdID, recipeID, stepID int) (recipes.Step, error) {
+	st, err := scanStep(q.QueryRowContext(ctx, `
+		SELECT `+stepColumns+`
+		FROM recipe_steps
+		WHERE id = ? AND recipe_id = ? AND `+householdRecipe+`
+	`, stepID, recipeID, householdID))
+	if err == sql.ErrNoRows {
+		return st, recipes.ErrStepNotFound
+	}
+
+	return st, err
+}
+
+func countSteps(ctx context.Context, q querier, recipeID int) (int, error) {
+	var n int
+	err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM recipe_steps WHERE recipe_id = ?`, recipeID).Scan(&n)
+	return n, err
+}
+
+func (s *Store) ListSteps(ctx context.Context, householdID, recipeID int) ([]recipes.Step, error) {
+	return listSteps(ctx, s.db.Reader(), householdID, recipeID)
+}
+
+func (s *Store) GetStep(ctx context.Context, householdID, recipeID, stepID int) (recipes.Step, error) {
+	return getStep(ctx, s.db.Reader(), householdID, recipeID, stepID)
+}
+
+func (s *Store) CreateStep(ctx context.Context, householdID int, st recipes.Step) (recipes.Step, error) {
+	var created recipes.Step
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		ok, err := belongs(ctx, tx, "recipes", householdID, st.RecipeID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return recipes.ErrNotFound
+		}
+
+		n, err := countSteps(ctx, tx, st.RecipeID)
+		if err != nil {
+			return err
+		}
+
+		// Append by default; positions past the end are clamped so step_no stays contiguous
+		stepNo := n + 1
+		if st.StepNo > 0 && st.StepNo < stepNo {
+			stepNo = st.StepNo
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE recipe_steps
+			SET step_no = step_no + 1
+			WHERE recipe_id = ? AND step_no >= ?
+		`, st.RecipeID, stepNo); err != nil {
+			return err
+		}
+
+		var id int
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO recipe_steps (recipe_id, step_no, instruction)
+			VALUES (?, ?, ?)
+			RETURNING id
+		`, st.RecipeID, stepNo, st.Instruction).Scan(&id); err != nil {
+			return err
+		}
+
+		created, err = getStep(ctx, tx, householdID, st.RecipeID, id)
+		return err
+	})
+
+	return created, err
+}
+
+func (s *Store) UpdateStep(ctx context.Context, householdID int, st recipes.Step) (recipes.Step, error) {
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		current, err := getStep(ctx, tx, householdID, st.RecipeID, st.ID)
+		if err != nil {
+			return err
+		}
+
+		if st.StepNo != current.StepNo {
+			n, err := countSteps(ctx, tx, st.RecipeID)
+			if err != nil {
+				return err
+			}
+
+			st.StepNo = min(st.StepNo, n)
+			if st.StepNo < current.StepNo {
+				_, err = tx.ExecContext(ctx, `
+					UPDATE recipe_steps
+					SET step_no = step_no + 1
+					WHERE recipe_id = ? AND step_no >= ? AND step_no < ?
+				`, st.RecipeID, st.StepNo, current.StepNo)
+			} else {
+				_, err = tx.ExecContext(ctx, `
+					UPDATE recipe_steps
+					SET step_no = step_no - 1
+					WHERE recipe_id = ? AND step_no > ? AND step_no <= ?
+				`, st.RecipeID, current.StepNo, st.StepNo)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE recipe_steps
+			SET step_no = ?, instruction = ?
+			WHERE id = ?
+		`, st.StepNo, st.Instruction, st.ID)
+		return err
+	})
+
+	return st, err
+}
+
+func (s *Store) ReorderSteps(ctx context.Context, householdID, recipeID int, stepIDs []int) ([]recipes.Step, error) {
+	var steps []recipes.Step
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		current, err := listSteps(ctx, tx, householdID, recipeID)
+		if err != nil {
+			return err
+		}
+
+		// The new order must name every existing step exactly once
+		pending := make(map[int]bool, len(current))
+		for _, st := range current {
+			pending[st.ID] = true
+		}
+		if len(stepIDs) != len(current) {
+			return recipes.ErrInvalidStepOrder
+		}
+		for _, id := range stepIDs {
+			if !pending[id] {
+				return recipes.ErrInvalidStepOrder
+			}
+			delete(pending, id)
+		}
+
+		for i, id := range stepIDs {
+			if _, err := tx.ExecContext(ctx, `UPDATE recipe_steps SET step_no = ? WHERE id = ?`, i+1, id); err != nil {
+				return err
+			}
+		}
+
+		steps, err = listSteps(ctx, tx, householdID, recipeID)
+		return err
+	})
+
+	return steps, err
+}
+
+func (s *Store) DeleteStep(ctx context.Context, householdID, recipeID, stepID int) error {
+	return s.inTx(ctx, func(tx *db.Tx) error {
+		current, err := getStep(ctx, tx, householdID, recipeID, stepID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_steps WHERE id = ?`, stepID); err != nil {
+			return err
+		}
+
+		// Close the gap left behind
+		_, err = tx.ExecContext(ctx, `
+			UPDATE recipe_steps
+			SET step_no = step_no - 1
+			WHERE recipe_id = ? AND step_no > ?
+		`, recipeID, current.StepNo)
+		return err
+	})
+}
@@ -0,0 +1,198 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/db"
+	"meal_prep/internal/tags"
+)
+
+const tagColumns = `t.id, t.household_id, t.name, t.category, t.created_at,
+	(SELECT COUNT(*) FROM recipe_tags rt WHERE rt.tag_id = t.id)`
+
+func scanTag(row scanner) (tags.Tag, error) {
+	var t tags.Tag
+	err := row.Scan(&t.ID, &t.HouseholdID, &t.Name, &t.Category, &t.CreatedAt, &t.RecipeCount)
+	return t, err
+}
+
+// getTag loads one of the household's tags matching cond.
+func getTag(ctx context.Context, q querier, householdID int, cond string, arg any) (tags.Tag, error) {
+	t, err := scanTag(q.QueryRowContext(ctx, `SELECT `+tagColumns+` FROM tags t WHERE t.household_id = ? AND `+cond, householdID, arg))
+	if err == sql.ErrNoRows {
+		return t, tags.ErrNotFound
+	}
+
+	return t, err
+}
+
+func listTags(ctx context.Context, q querier, query string, args ...any) ([]tags.Tag, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []tags.Tag
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+
+	return list, rows.Err()
+}
+
+// nameTaken reports whether the household has a tag other than id called
+// name. Checked up front so a duplicate isn't a driver-specific constraint
+// error.
+func nameTaken(ctx context.Context, q querier, householdID, id int, name string) error {
+	t, err := getTag(ctx, q, householdID, `t.name = ?`, name)
+	if err == tags.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if t.ID != id {
+		return tags.ErrExists
+	}
+
+	return nil
+}
+
+func (s *Store) ListTags(ctx context.Context, householdID int) ([]tags.Tag, error) {
+	return listTags(ctx, s.db.Reader(), `
+		SELECT `+tagColumns+`
+		FROM tags t
+		WHERE t.household_id = ?
+		ORDER BY t.name ASC
+	`, householdID)
+}
+
+func (s *Store) GetTag(ctx context.Context, householdID, id int) (tags.Tag, error) {
+	return getTag(ctx, s.db.Reader(), householdID, `t.id = ?`, id)
+}
+
+func (s *Store) CreateTag(ctx context.Context, t tags.Tag) (tags.Tag, error) {
+	var created tags.Tag
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		if err := nameTaken(ctx, tx, t.HouseholdID, 0, t.Name); err != nil {
+			return err
+		}
+
+		var id int
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO tags (household_id, name, category)
+			VALUES (?, ?, ?)
+			RETURNING id
+		`, t.HouseholdID, t.Name, t.Category).Scan(&id); err != nil {
+			return err
+		}
+
+		var err error
+		created, err = getTag(ctx, tx, t.HouseholdID, `t.id = ?`, id)
+		return err
+	})
+
+	return created, err
+}
+
+func (s *Store) UpdateTag(ctx context.Context, t tags.Tag) (tags.Tag, error) {
+	var updated tags.Tag
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		if err := nameTaken(ctx, tx, t.HouseholdID, t.ID, t.Name); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE tags SET name = ?, category = ?
+			WHERE id = ? AND household_id = ?
+		`, t.Name, t.Category, t.ID, t.HouseholdID)
+		if err != nil {
+			return err
+		}
+		if err := checkAffected(res, tags.ErrNotFound); err != nil {
+			return err
+		}
+
+		updated, err = getTag(ctx, tx, t.HouseholdID, `t.id = ?`, t.ID)
+		return err
+	})
+
+	return updated, err
+}
+
+func (s *Store) DeleteTag(ctx context.Context, householdID, id int) error {
+	return deleteInHousehold(ctx, s.db, "tags", inHousehold, householdID, id, tags.ErrNotFound)
+}
+
+func (s *Store) ListRecipeTags(ctx context.Context, householdID, recipeID int) ([]tags.Tag, error) {
+	q := s.db.Reader()
+	if ok, err := belongs(ctx, q, "recipes", householdID, recipeID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, tags.ErrRecipeNotFound
+	}
+
+	return listTags(ctx, q, `
+		SELECT `+tagColumns+`
+		FROM tags t
+		JOIN recipe_tags r ON r.tag_id = t.id
+		WHERE r.recipe_id = ?
+		ORDER BY t.name ASC
+	`, recipeID)
+}
+
+func (s *Store) TagRecipe(ctx context.Context, householdID, recipeID int, name string) (tags.Tag, error) {
+	var tagged tags.Tag
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		if ok, err := belongs(ctx, tx, "recipes", householdID, recipeID); err != nil {
+			return err
+		} else if !ok {
+			return tags.ErrRecipeNotFound
+		}
+
+		t, err := getTag(ctx, tx, householdID, `t.name = ?`, name)
+		if err == tags.ErrNotFound {
+			err = tx.QueryRowContext(ctx, `
+				INSERT INTO tags (household_id, name)
+				VALUES (?, ?)
+				RETURNING id
+			`, householdID, name).Scan(&t.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO recipe_tags (recipe_id, tag_id)
+			VALUES (?, ?)
+			ON CONFLICT DO NOTHING
+		`, recipeID, t.ID); err != nil {
+			return err
+		}
+
+		tagged, err = getTag(ctx, tx, householdID, `t.id = ?`, t.ID)
+		return err
+	})
+
+	return tagged, err
+}
+
+func (s *Store) UntagRecipe(ctx context.Context, householdID, recipeID, tagID int) error {
+	if ok, err := belongs(ctx, s.db, "recipes", householdID, recipeID); err != nil {
+		return err
+	} else if !ok {
+		return tags.ErrRecipeNotFound
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM recipe_tags WHERE recipe_id = ? AND tag_id = ?`, recipeID, tagID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, tags.ErrNotFound)
+}
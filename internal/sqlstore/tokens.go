@@ -0,0 +1,120 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/auth"
+	"time"
+)
+
+const apiTokenColumns = `id, user_id, name, scope, token_hash, created_at, last_used_at`
+
+func scanAPIToken(row scanner) (auth.APIToken, error) {
+	var t auth.APIToken
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.TokenHash, &t.CreatedAt, &t.LastUsedAt)
+	return t, err
+}
+
+func getAPIToken(ctx context.Context, q querier, userID, id int) (auth.APIToken, error) {
+	t, err := scanAPIToken(q.QueryRowContext(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE id = ? AND user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return t, auth.ErrTokenNotFound
+	}
+
+	return t, err
+}
+
+func (s *Store) CreateAPIToken(ctx context.Context, t auth.APIToken) (auth.APIToken, error) {
+	var id int
+	if err := s.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (user_id, name, scope, token_hash)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`, t.UserID, t.Name, t.Scope, t.TokenHash).Scan(&id); err != nil {
+		return auth.APIToken{}, err
+	}
+
+	return getAPIToken(ctx, s.db, t.UserID, id)
+}
+
+func (s *Store) ListAPITokens(ctx context.Context, userID int) ([]auth.APIToken, error) {
+	rows, err := s.db.Reader().QueryContext(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []auth.APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *Store) RenameAPIToken(ctx context.Context, userID, id int, name string) (auth.APIToken, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE api_tokens SET name = ? WHERE id = ? AND user_id = ?`, name, id, userID)
+	if err != nil {
+		return auth.APIToken{}, err
+	}
+	if err := checkAffected(res, auth.ErrTokenNotFound); err != nil {
+		return auth.APIToken{}, err
+	}
+
+	return getAPIToken(ctx, s.db, userID, id)
+}
+
+func (s *Store) DeleteAPIToken(ctx context.Context, userID, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, auth.ErrTokenNotFound)
+}
+
+// UseAPIToken reads from the read pool and only takes the writer when
+// last_used_at is due for a refresh.
+func (s *Store) UseAPIToken(ctx context.Context, tokenHash string) (auth.User, auth.APIToken, error) {
+	var (
+		u auth.User
+		t auth.APIToken
+	)
+	err := s.db.Reader().QueryRowContext(ctx, `
+		SELECT u.id, u.email, u.password_hash, u.created_at,
+		       t.id, t.user_id, t.name, t.scope, t.token_hash, t.created_at, t.last_used_at
+		FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = ?
+	`, tokenHash).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt,
+		&t.ID, &t.UserID, &t.Name, &t.Scope, &t.TokenHash, &t.CreatedAt, &t.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return auth.User{}, auth.APIToken{}, auth.ErrTokenNotFound
+	}
+	if err != nil {
+		return auth.User{}, auth.APIToken{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= auth.LastUsedGranularity {
+		if _, err := s.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, now, t.ID); err != nil {
+			return auth.User{}, auth.APIToken{}, err
+		}
+		t.LastUsedAt = &now
+	}
+
+	return u, t, nil
+}
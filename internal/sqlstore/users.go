@@ -0,0 +1,120 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"meal_prep/internal/auth"
+	"meal_prep/internal/db"
+	"time"
+)
+
+const userColumns = `id, email, password_hash, created_at`
+
+func scanUser(row scanner) (auth.User, error) {
+	var u auth.User
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt)
+	return u, err
+}
+
+func getUser(ctx context.Context, q querier, cond string, arg any) (auth.User, error) {
+	u, err := scanUser(q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+cond, arg))
+	if err == sql.ErrNoRows {
+		return u, auth.ErrUserNotFound
+	}
+
+	return u, err
+}
+
+// CreateUser also gives the user a household of their own, named after them.
+func (s *Store) CreateUser(ctx context.Context, u auth.User) (auth.User, error) {
+	var created auth.User
+	err := s.inTx(ctx, func(tx *db.Tx) error {
+		// Left to the UNIQUE constraint, since two sign-ups can race past any
+		// check made first
+		var id int
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (email, password_hash)
+			VALUES (?, ?)
+			RETURNING id
+		`, u.Email, u.PasswordHash).Scan(&id)
+		if db.IsUniqueViolation(err) {
+			return auth.ErrEmailTaken
+		}
+		if err != nil {
+			return err
+		}
+
+		householdID, err := createHousehold(ctx, tx, id, u.Email)
+		if err != nil {
+			return err
+		}
+
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+			return err
+		}
+		if n == 1 {
+			for _, table := range []string{"recipes", "meal_plans"} {
+				if _, err := tx.ExecContext(ctx, `
+					UPDATE `+table+`
+					SET owner_id = ?, household_id = ?
+					WHERE household_id IS NULL
+				`, id, householdID); err != nil {
+					return err
+				}
+			}
+		}
+
+		created, err = getUser(ctx, tx, `id = ?`, id)
+		return err
+	})
+
+	return created, err
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (auth.User, error) {
+	return getUser(ctx, s.db.Reader(), `email = ?`, email)
+}
+
+func (s *Store) CreateSession(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	return s.inTx(ctx, func(tx *db.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM sessions
+			WHERE user_id = ? AND expires_at < ?
+		`, userID, time.Now().UTC()); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO sessions (user_id, token_hash, expires_at)
+			VALUES (?, ?, ?)
+		`, userID, tokenHash, expiresAt.UTC())
+		return err
+	})
+}
+
+func (s *Store) GetSessionUser(ctx context.Context, tokenHash string) (auth.User, error) {
+	var (
+		u       auth.User
+		expires time.Time
+	)
+	err := s.db.Reader().QueryRowContext(ctx, `
+		SELECT u.id, u.email, u.password_hash, u.created_at, s.expires_at
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ?
+	`, tokenHash).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt, &expires)
+	if err == sql.ErrNoRows || (err == nil && !time.Now().Before(expires)) {
+		return auth.User{}, auth.ErrSessionNotFound
+	}
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	return u, nil
+}
+
+func (s *Store) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	return err
+}
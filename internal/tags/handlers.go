@@ -0,0 +1,314 @@
+package tags
+
+import (
+	"errors"
+	"meal_prep/internal/households"
+	"meal_prep/internal/patch"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTagRequest struct {
+	Name     string  `json:"name" binding:"required"`
+	Category *string `json:"category"`
+}
+
+type UpdateTagRequest struct {
+	Name     patch.Field[string] `json:"name"`
+	Category patch.Field[string] `json:"category"` // null clears
+}
+
+type TagRecipeRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// cleanTag normalizes the name and category of t, reporting a message for
+// the client if they can't be used.
+func cleanTag(t *Tag) (string, bool) {
+	t.Name = Normalize(t.Name)
+	if t.Name == "" || len(t.Name) > MaxNameLength {
+		return "name must be 1 to " + strconv.Itoa(MaxNameLength) + " characters", false
+	}
+
+	if t.Category != nil {
+		category := Normalize(*t.Category)
+		t.Category = &category
+		if category == "" {
+			t.Category = nil
+		}
+	}
+
+	return "", true
+}
+
+func parseTagID(c *gin.Context, param string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+func parseRecipeID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipe id"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+// ListTagsHandler returns the household's tags with their recipe counts,
+// optionally only those in ?category=.
+func ListTagsHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	list, err := store.ListTags(c.Request.Context(), households.ID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query tags"})
+		return
+	}
+
+	if category, ok := c.GetQuery("category"); ok {
+		category = Normalize(category)
+		kept := []Tag{}
+		for _, t := range list {
+			if t.Category != nil && *t.Category == category {
+				kept = append(kept, t)
+			}
+		}
+		list = kept
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func GetTagHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	id, ok := parseTagID(c, "id")
+	if !ok {
+		return
+	}
+
+	t, err := store.GetTag(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+func CreateTagHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	var req CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	t := Tag{HouseholdID: households.ID(c), Name: req.Name, Category: req.Category}
+	if msg, ok := cleanTag(&t); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	t, err := store.CreateTag(c.Request.Context(), t)
+	if errors.Is(err, ErrExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, t)
+}
+
+func UpdateTagHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	id, ok := parseTagID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateTagRequest
+	if err := patch.Bind(c, &req); err != nil {
+		if err == patch.ErrUnsupportedMediaType {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported media type"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	// Load existing
+	t, err := store.GetTag(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	// Apply patch
+	if err := req.Name.Apply(&t.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be null"})
+		return
+	}
+	req.Category.ApplyPtr(&t.Category)
+	if msg, ok := cleanTag(&t); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	t, err = store.UpdateTag(c.Request.Context(), t)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	case errors.Is(err, ErrExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+func DeleteTagHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	id, ok := parseTagID(c, "id")
+	if !ok {
+		return
+	}
+
+	err := store.DeleteTag(c.Request.Context(), households.ID(c), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func ListRecipeTagsHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Viewer) {
+		return
+	}
+
+	recipeID, ok := parseRecipeID(c)
+	if !ok {
+		return
+	}
+
+	list, err := store.ListRecipeTags(c.Request.Context(), households.ID(c), recipeID)
+	if errors.Is(err, ErrRecipeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// TagRecipeHandler attaches a tag by name, creating it on first use.
+func TagRecipeHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	recipeID, ok := parseRecipeID(c)
+	if !ok {
+		return
+	}
+
+	var req TagRecipeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	t := Tag{Name: req.Name}
+	if msg, ok := cleanTag(&t); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	t, err := store.TagRecipe(c.Request.Context(), households.ID(c), recipeID, t.Name)
+	if errors.Is(err, ErrRecipeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to tag recipe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+func UntagRecipeHandler(c *gin.Context, store TagStore) {
+	if !households.Allow(c, households.Editor) {
+		return
+	}
+
+	recipeID, ok := parseRecipeID(c)
+	if !ok {
+		return
+	}
+	tagID, ok := parseTagID(c, "tagId")
+	if !ok {
+		return
+	}
+
+	err := store.UntagRecipe(c.Request.Context(), households.ID(c), recipeID, tagID)
+	switch {
+	case errors.Is(err, ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
+		return
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "the recipe doesn't have this tag"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to untag recipe"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,117 @@
+package tags_test
+
+import (
+	"meal_prep/internal/apitest"
+	"meal_prep/internal/recipes"
+	"meal_prep/internal/tags"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Weeknight", "weeknight"},
+		{"  Freezer   Friendly ", "freezer friendly"},
+		{"\t", ""},
+	}
+	for _, tt := range tests {
+		if got := tags.Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		var vegetarian tags.Tag
+		cl.Create("/v1/tags", map[string]any{"name": " Vegetarian ", "category": "Diet"}, &vegetarian)
+		if vegetarian.Name != "vegetarian" || vegetarian.Category == nil || *vegetarian.Category != "diet" {
+			t.Errorf("create = %+v, want names normalized", vegetarian)
+		}
+		cl.Create("/v1/tags", map[string]any{"name": "Weeknight"}, nil)
+
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			body   any
+			want   int
+		}{
+			{"same name", "POST", "/v1/tags", map[string]any{"name": "VEGETARIAN"}, http.StatusConflict},
+			{"blank name", "POST", "/v1/tags", map[string]any{"name": "   "}, http.StatusBadRequest},
+			{"renaming onto another", "PATCH", "/v1/tags/" + strconv.Itoa(vegetarian.ID), map[string]any{"name": "weeknight"}, http.StatusConflict},
+			{"null name", "PATCH", "/v1/tags/" + strconv.Itoa(vegetarian.ID), map[string]any{"name": nil}, http.StatusBadRequest},
+			{"missing tag", "GET", "/v1/tags/999", nil, http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			if code := cl.Do(tt.method, tt.path, tt.body, nil); code != tt.want {
+				t.Errorf("%s: status %d, want %d", tt.name, code, tt.want)
+			}
+		}
+
+		var diets []tags.Tag
+		cl.Do("GET", "/v1/tags?category=DIET", nil, &diets)
+		if len(diets) != 1 || diets[0].ID != vegetarian.ID {
+			t.Errorf("?category=DIET = %+v", diets)
+		}
+
+		var patched tags.Tag
+		if code := cl.Do("PATCH", "/v1/tags/"+strconv.Itoa(vegetarian.ID), map[string]any{"category": nil}, &patched); code != http.StatusOK {
+			t.Fatalf("patch: status %d", code)
+		}
+		if patched.Category != nil || patched.Name != "vegetarian" {
+			t.Errorf("patch = %+v", patched)
+		}
+	})
+}
+
+func TestTagRecipes(t *testing.T) {
+	apitest.ForEachStore(t, func(t *testing.T, s apitest.Store) {
+		cl := apitest.NewClient(t, s, "cook@example.com")
+		var pie recipes.Recipe
+		cl.Create("/v1/recipes", map[string]any{"title": "Apple pie"}, &pie)
+		path := "/v1/recipes/" + strconv.Itoa(pie.ID) + "/tags"
+
+		// Tagging by a new name creates the tag, and tagging twice is a no-op
+		var dessert, again tags.Tag
+		if code := cl.Do("POST", path, map[string]any{"name": "Dessert"}, &dessert); code != http.StatusOK || dessert.Name != "dessert" {
+			t.Fatalf("tag: status %d, %+v", code, dessert)
+		}
+		if code := cl.Do("POST", path, map[string]any{"name": "dessert"}, &again); code != http.StatusOK || again.ID != dessert.ID {
+			t.Errorf("tag again: status %d, %+v", code, again)
+		}
+
+		var list []tags.Tag
+		cl.Do("GET", "/v1/tags", nil, &list)
+		if len(list) != 1 || list[0].RecipeCount != 1 {
+			t.Errorf("tags = %+v, want dessert on one recipe", list)
+		}
+
+		if code := cl.Do("POST", "/v1/recipes/999/tags", map[string]any{"name": "dessert"}, nil); code != http.StatusNotFound {
+			t.Errorf("missing recipe: status %d, want 404", code)
+		}
+		if code := cl.Do("DELETE", path+"/"+strconv.Itoa(dessert.ID), nil, nil); code != http.StatusNoContent {
+			t.Fatalf("untag: status %d", code)
+		}
+		if code := cl.Do("DELETE", path+"/"+strconv.Itoa(dessert.ID), nil, nil); code != http.StatusNotFound {
+			t.Errorf("untag again: status %d, want 404", code)
+		}
+
+		// Deleting a tag takes it off its recipes
+		cl.Do("POST", path, map[string]any{"name": "dessert"}, nil)
+		if code := cl.Do("DELETE", "/v1/tags/"+strconv.Itoa(dessert.ID), nil, nil); code != http.StatusNoContent {
+			t.Fatalf("delete: status %d", code)
+		}
+		cl.Do("GET", path, nil, &list)
+		if len(list) != 0 {
+			t.Errorf("recipe tags after delete = %+v", list)
+		}
+
+		other := apitest.NewClient(t, s, "baker@example.com")
+		if code := other.Do("POST", path, map[string]any{"name": "mine"}, nil); code != http.StatusNotFound {
+			t.Errorf("another household's recipe: status %d, want 404", code)
+		}
+	})
+}
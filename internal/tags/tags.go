@@ -0,0 +1,58 @@
+// Package tags labels recipes with words like "vegetarian", "weeknight" or
+// "freezer-friendly" for browsing. Tags belong to a household, are compared
+// by their normalized name and can be grouped under an optional category.
+package tags
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxNameLength bounds tag names, counted in bytes after normalizing.
+const MaxNameLength = 50
+
+var (
+	ErrNotFound       = errors.New("tag not found")
+	ErrExists         = errors.New("a tag with this name already exists")
+	ErrRecipeNotFound = errors.New("recipe not found")
+)
+
+type Tag struct {
+	ID          int        `json:"id"`
+	HouseholdID int        `json:"household_id"`
+	Name        string     `json:"name"`
+	Category    *string    `json:"category,omitempty"` // e.g. "diet", "occasion"
+	RecipeCount int        `json:"recipe_count"`       // recipes carrying the tag
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// TagStore is the persistence behind tags. Every call is scoped to a
+// household; another household's tag or recipe reads as missing.
+type TagStore interface {
+	// ListTags returns the household's tags by name, with their recipe
+	// counts.
+	ListTags(ctx context.Context, householdID int) ([]Tag, error)
+	GetTag(ctx context.Context, householdID, id int) (Tag, error)
+	// CreateTag and UpdateTag fail with ErrExists if the household has
+	// another tag with the name.
+	CreateTag(ctx context.Context, t Tag) (Tag, error)
+	UpdateTag(ctx context.Context, t Tag) (Tag, error)
+	// DeleteTag also takes the tag off every recipe.
+	DeleteTag(ctx context.Context, householdID, id int) error
+
+	// ListRecipeTags returns the recipe's tags by name, or ErrRecipeNotFound.
+	ListRecipeTags(ctx context.Context, householdID, recipeID int) ([]Tag, error)
+	// TagRecipe puts the tag with the name on the recipe, creating the tag if
+	// the household doesn't have it yet. Tagging twice is a no-op.
+	TagRecipe(ctx context.Context, householdID, recipeID int, name string) (Tag, error)
+	// UntagRecipe fails with ErrNotFound if the recipe doesn't carry the tag.
+	UntagRecipe(ctx context.Context, householdID, recipeID, tagID int) error
+}
+
+// Normalize is the form names are stored and looked up in, so "Weeknight"
+// and " weeknight " are the same tag.
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
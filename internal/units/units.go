@@ -0,0 +1,172 @@
+// Package units is the registry of ingredient units. It maps the free-text
+// spellings people type ("Tablespoon", "tbsp", "T") to a canonical unit and
+// converts amounts between units of the same dimension. There is no density
+// data here, so volume and mass never convert into each other.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Dimension string
+
+const (
+	Volume Dimension = "volume" // base unit: ml
+	Mass   Dimension = "mass"   // base unit: g
+	Count  Dimension = "count"  // base unit: each
+)
+
+type System string
+
+const (
+	Metric System = "metric"
+	US     System = "us"
+)
+
+type Unit struct {
+	Name      string // canonical spelling
+	Dimension Dimension
+	System    System  // empty for count units
+	Factor    float64 // size of one unit in the dimension's base unit
+}
+
+var (
+	ErrUnknownUnit  = errors.New("unknown unit")
+	ErrIncompatible = errors.New("incompatible units")
+)
+
+var registry = map[string]Unit{}
+
+// aliases maps a normalized spelling to a canonical name
+var aliases = map[string]string{}
+
+// caseSensitive spellings are checked before normalization, where the case
+// carries the meaning: a capital T is a tablespoon, a lower-case t a teaspoon.
+var caseSensitive = map[string]string{
+	"T":  "tbsp",
+	"Tb": "tbsp",
+	"t":  "tsp",
+}
+
+func register(u Unit, spellings ...string) {
+	registry[u.Name] = u
+	aliases[u.Name] = u.Name
+	for _, s := range spellings {
+		aliases[s] = u.Name
+	}
+}
+
+func init() {
+	// Volume
+	register(Unit{"ml", Volume, Metric, 1}, "milliliter", "milliliters", "millilitre", "millilitres", "mls")
+	register(Unit{"cl", Volume, Metric, 10}, "centiliter", "centiliters", "centilitre", "centilitres")
+	register(Unit{"dl", Volume, Metric, 100}, "deciliter", "deciliters", "decilitre", "decilitres")
+	register(Unit{"l", Volume, Metric, 1000}, "liter", "liters", "litre", "litres", "ltr")
+	register(Unit{"tsp", Volume, US, 4.92892159375}, "teaspoon", "teaspoons", "tsps", "tspn")
+	register(Unit{"tbsp", Volume, US, 14.78676478125}, "tablespoon", "tablespoons", "tbsps", "tbs", "tbl", "tbls")
+	register(Unit{"fl oz", Volume, US, 29.5735295625}, "fluid ounce", "fluid ounces", "floz", "fl. oz", "fl.oz")
+	register(Unit{"cup", Volume, US, 236.5882365}, "cups", "c")
+	register(Unit{"pint", Volume, US, 473.176473}, "pints", "pt", "pts")
+	register(Unit{"quart", Volume, US, 946.352946}, "quarts", "qt", "qts")
+	register(Unit{"gallon", Volume, US, 3785.411784}, "gallons", "gal", "gals")
+
+	// Mass
+	register(Unit{"mg", Mass, Metric, 0.001}, "milligram", "milligrams", "milligramme", "milligrammes")
+	register(Unit{"g", Mass, Metric, 1}, "gram", "grams", "gramme", "grammes", "gr", "grs")
+	register(Unit{"kg", Mass, Metric, 1000}, "kilogram", "kilograms", "kilogramme", "kilogrammes", "kilo", "kilos", "kgs")
+	register(Unit{"oz", Mass, US, 28.349523125}, "ounce", "ounces", "ozs")
+	register(Unit{"lb", Mass, US, 453.59237}, "pound", "pounds", "lbs")
+
+	// Count; no unit at all means "this many of the thing"
+	register(Unit{"each", Count, "", 1}, "", "ea", "piece", "pieces", "pc", "pcs", "whole", "item", "items")
+	register(Unit{"dozen", Count, "", 12}, "doz", "dozens")
+}
+
+func normalize(s string) string {
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Lookup resolves any known spelling of a unit.
+func Lookup(s string) (Unit, bool) {
+	if name, ok := caseSensitive[strings.TrimSpace(s)]; ok {
+		return registry[name], true
+	}
+
+	name, ok := aliases[normalize(s)]
+	if !ok {
+		return Unit{}, false
+	}
+
+	return registry[name], true
+}
+
+// Compatible reports whether amounts in a and b can be converted into each
+// other.
+func Compatible(a, b string) bool {
+	ua, okA := Lookup(a)
+	ub, okB := Lookup(b)
+
+	return okA && okB && ua.Dimension == ub.Dimension
+}
+
+func Convert(v float64, from, to string) (float64, error) {
+	uf, ok := Lookup(from)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	ut, ok := Lookup(to)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if uf.Dimension != ut.Dimension {
+		return 0, fmt.Errorf("%w: cannot convert %s (%s) to %s (%s)", ErrIncompatible, uf.Name, uf.Dimension, ut.Name, ut.Dimension)
+	}
+
+	return v * uf.Factor / ut.Factor, nil
+}
+
+// ladders are the units a cook would actually reach for, smallest first,
+// with the smallest amount of each that still reads naturally ("1/4 cup" is
+// fine, "1/4 kg" is better said in grams).
+var ladders = map[Dimension]map[System][]struct {
+	name string
+	min  float64
+}{
+	Volume: {
+		US:     {{"tsp", 0}, {"tbsp", 1}, {"cup", 0.25}},
+		Metric: {{"ml", 0}, {"l", 1}},
+	},
+	Mass: {
+		US:     {{"oz", 0}, {"lb", 1}},
+		Metric: {{"g", 0}, {"kg", 1}},
+	},
+}
+
+// Promote re-expresses v in the most readable unit of the same measuring
+// system, so 48 tsp becomes 1 cup and 0.5 tbsp becomes 1.5 tsp. Count units
+// and units the registry doesn't know are returned unchanged.
+func Promote(v float64, unit string) (float64, string) {
+	u, ok := Lookup(unit)
+	if !ok {
+		return v, unit
+	}
+
+	ladder := ladders[u.Dimension][u.System]
+	if len(ladder) == 0 {
+		return v, unit
+	}
+
+	// The epsilon keeps 3 tsp from landing a hair under 1 tbsp
+	base := v * u.Factor
+	for i := len(ladder) - 1; i > 0; i-- {
+		step := registry[ladder[i].name]
+		if base/step.Factor >= ladder[i].min-1e-9 {
+			return base / step.Factor, step.Name
+		}
+	}
+
+	return base / registry[ladder[0].name].Factor, ladder[0].name
+}
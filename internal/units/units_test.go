@@ -0,0 +1,121 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"g", "g"},
+		{"Grams", "g"},
+		{" kilos ", "kg"},
+		{"Tablespoon", "tbsp"},
+		{"T", "tbsp"},
+		{"t", "tsp"},
+		{"tsp.", "tsp"},
+		{"fl. oz", "fl oz"},
+		{"Fluid  Ounces", "fl oz"},
+		{"", "each"},
+		{"pcs", "each"},
+	}
+
+	for _, tt := range tests {
+		u, ok := Lookup(tt.in)
+		if !ok || u.Name != tt.want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", tt.in, u.Name, ok, tt.want)
+		}
+	}
+
+	if u, ok := Lookup("furlong"); ok {
+		t.Errorf("Lookup(furlong) = %q, want unknown", u.Name)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		v        float64
+		from, to string
+		want     float64
+	}{
+		{1.5, "kg", "g", 1500},
+		{250, "ml", "l", 0.25},
+		{3, "tsp", "tbsp", 1},
+		{1, "cup", "tbsp", 16},
+		{1, "lb", "oz", 16},
+		{1, "oz", "g", 28.349523125},
+		{2, "dozen", "each", 24},
+		{4, "", "pieces", 4},
+	}
+
+	for _, tt := range tests {
+		got, err := Convert(tt.v, tt.from, tt.to)
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v, %q, %q) = %v, %v; want %v", tt.v, tt.from, tt.to, got, err, tt.want)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     error
+	}{
+		{"g", "ml", ErrIncompatible},
+		{"cup", "oz", ErrIncompatible},
+		{"each", "g", ErrIncompatible},
+		{"furlong", "g", ErrUnknownUnit},
+		{"g", "furlong", ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		if _, err := Convert(1, tt.from, tt.to); !errors.Is(err, tt.want) {
+			t.Errorf("Convert(1, %q, %q) = %v, want %v", tt.from, tt.to, err, tt.want)
+		}
+		if Compatible(tt.from, tt.to) {
+			t.Errorf("Compatible(%q, %q) = true", tt.from, tt.to)
+		}
+	}
+}
+
+func TestPromote(t *testing.T) {
+	tests := []struct {
+		v        float64
+		unit     string
+		want     float64
+		wantUnit string
+	}{
+		// Each rung starts at its threshold, epsilon included
+		{2, "tsp", 2, "tsp"},
+		{3, "tsp", 1, "tbsp"},
+		{3, "teaspoons", 1, "tbsp"},
+		{3.999, "tbsp", 3.999, "tbsp"},
+		{4, "tbsp", 0.25, "cup"},
+		{48, "tsp", 1, "cup"},
+		{0.5, "tbsp", 1.5, "tsp"},
+		{999, "g", 999, "g"},
+		{1000, "g", 1, "kg"},
+		{0.5, "kg", 500, "g"},
+		{2000, "ml", 2, "l"},
+		{8, "oz", 8, "oz"},
+		{16, "oz", 1, "lb"},
+		// Metric stays metric and US stays US, whatever the amount
+		{1000, "ml", 1, "l"},
+		{100, "cup", 100, "cup"},
+		// Nothing to climb
+		{24, "each", 24, "each"},
+		{3, "dozen", 3, "dozen"},
+		{2, "sprigs", 2, "sprigs"},
+	}
+
+	for _, tt := range tests {
+		got, unit := Promote(tt.v, tt.unit)
+		if math.Abs(got-tt.want) > 1e-9 || unit != tt.wantUnit {
+			t.Errorf("Promote(%v, %q) = %v %s, want %v %s", tt.v, tt.unit, got, unit, tt.want, tt.wantUnit)
+		}
+	}
+}
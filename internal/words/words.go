@@ -0,0 +1,54 @@
+// Package words splits ingredient names into the words that name things, for
+// matching them against lists of foods: lower-cased and singular, so "Eggs"
+// finds egg and "tomatoes" tomato.
+package words
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Split breaks a name into lower-cased, singular words. Hyphens stay inside
+// words, as in "gluten-free".
+func Split(name string) []string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	for i, w := range words {
+		words[i] = Singular(w)
+	}
+
+	return words
+}
+
+// Key is Split joined by single spaces, the form lists of foods are indexed
+// by.
+func Key(name string) string {
+	return strings.Join(Split(name), " ")
+}
+
+// Singular undoes the common English plurals. It only has to be consistent,
+// as both sides of a match go through it.
+func Singular(w string) string {
+	switch {
+	case len(w) <= 3:
+		return w
+	case strings.HasSuffix(w, "ies"):
+		return strings.TrimSuffix(w, "ies") + "y"
+	case strings.HasSuffix(w, "oes"), strings.HasSuffix(w, "ches"), strings.HasSuffix(w, "shes"), strings.HasSuffix(w, "xes"):
+		return strings.TrimSuffix(w, "es")
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+		return strings.TrimSuffix(w, "s")
+	}
+
+	return w
+}
+
+// Plain reports whether s is free of control characters other than tabs and
+// line breaks. Text that ends up in search snippets has to be, as snippets
+// mark their matches with control characters.
+func Plain(s string) bool {
+	return !strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r'
+	})
+}